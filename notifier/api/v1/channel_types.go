@@ -0,0 +1,109 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChannelType is the kind of destination notifications are delivered to
+// +kubebuilder:validation:Enum=email;webhook;slack;teams;pagerduty;opsgenie
+type ChannelType string
+
+const (
+	// EmailChannel sends an email through the SMTP server
+	EmailChannel ChannelType = "email"
+	// WebhookChannel posts a generic JSON document
+	WebhookChannel ChannelType = "webhook"
+	// SlackChannel posts to a Slack incoming webhook
+	SlackChannel ChannelType = "slack"
+	// TeamsChannel posts a message card to a Microsoft Teams connector
+	TeamsChannel ChannelType = "teams"
+	// PagerDutyChannel triggers, acknowledges and resolves alerts through the PagerDuty Events API v2
+	PagerDutyChannel ChannelType = "pagerduty"
+	// OpsgenieChannel creates, acknowledges and closes Opsgenie alerts
+	OpsgenieChannel ChannelType = "opsgenie"
+)
+
+const (
+	// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	// DefaultOpsgenieURL is the Opsgenie Alert API endpoint, https://api.eu.opsgenie.com/v2/alerts for the EU instance
+	DefaultOpsgenieURL = "https://api.opsgenie.com/v2/alerts"
+)
+
+// Channel describes a single destination for notifications
+type Channel struct {
+	// Type selects how the notifications are delivered
+	Type ChannelType `json:"type"`
+
+	// Email is the recipient address of an email channel
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// URL the webhook, slack and teams channels post to.
+	// Overrides the default endpoint of the pagerduty and opsgenie channels.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// URLSecretRef selects a Secret key holding the URL, for webhooks embedding a token.
+	// Takes precedence over URL.
+	// +optional
+	URLSecretRef *corev1.SecretKeySelector `json:"urlSecretRef,omitempty"`
+
+	// RoutingKeySecretRef selects a Secret key holding the routing key of a pagerduty channel,
+	// or the API key of an opsgenie channel
+	// +optional
+	RoutingKeySecretRef *corev1.SecretKeySelector `json:"routingKeySecretRef,omitempty"`
+
+	// Webhook tunes the requests of the channels posting to a URL
+	// +optional
+	Webhook *WebhookOptions `json:"webhook,omitempty"`
+}
+
+// WebhookOptions authenticate the requests to the endpoint, and the endpoint to the controller.
+// The referenced Secrets are in the namespace of the Notifier.
+type WebhookOptions struct {
+	// HeadersSecretRef selects a Secret whose keys and values are sent as request headers, like Authorization
+	// +optional
+	HeadersSecretRef *corev1.LocalObjectReference `json:"headersSecretRef,omitempty"`
+
+	// SigningSecretRef selects a Secret key holding the key signing the request body with HMAC-SHA256.
+	// The signature is sent in the X-Notifier-Signature header as sha256=<hex digest>.
+	// +optional
+	SigningSecretRef *corev1.SecretKeySelector `json:"signingSecretRef,omitempty"`
+
+	// TLSSecretRef selects a Secret with the CA bundle verifying the endpoint in ca.crt,
+	// and the client certificate in tls.crt and tls.key. Either may be left out.
+	// +optional
+	TLSSecretRef *corev1.LocalObjectReference `json:"tlsSecretRef,omitempty"`
+
+	// Timeout bounds a single request, 30s by default
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultURL is the endpoint of the channel type when the channel has no URL, empty if it needs one
+func (t ChannelType) DefaultURL() string {
+	switch t {
+	case PagerDutyChannel:
+		return DefaultPagerDutyURL
+	case OpsgenieChannel:
+		return DefaultOpsgenieURL
+	}
+	return ""
+}
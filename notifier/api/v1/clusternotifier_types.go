@@ -0,0 +1,110 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ClusterNotifierKind is the kind of ClusterNotifiers, set on the Notifiers built by AsNotifier
+const ClusterNotifierKind = "ClusterNotifier"
+
+// ClusterNotifierSpec defines the desired state of ClusterNotifier
+type ClusterNotifierSpec struct {
+	NotifierSpec `json:",inline"`
+
+	// NamespaceSelector selects the namespaces the ClusterNotifier watches by their labels.
+	// An empty selector watches every namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=clusternotifiers,scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type==\"DeliveryDegraded\")].status"
+// +kubebuilder:printcolumn:name="Delivered",type="integer",JSONPath=".status.deliveredCount"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedCount"
+// +kubebuilder:printcolumn:name="Suppressed",type="integer",JSONPath=".status.suppressedCount",priority=1
+// +kubebuilder:printcolumn:name="Dead Lettered",type="integer",JSONPath=".status.deadLetteredCount",priority=1
+// +kubebuilder:printcolumn:name="Last Notification",type="date",JSONPath=".status.lastNotificationTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterNotifier is a Notifier for Events in every selected namespace
+type ClusterNotifier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterNotifierSpec `json:"spec,omitempty"`
+	Status NotifierStatus      `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterNotifierList contains a list of ClusterNotifier
+type ClusterNotifierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterNotifier `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterNotifier{}, &ClusterNotifierList{})
+}
+
+// AsNotifier is the ClusterNotifier acting as a Notifier in the namespace holding its Secrets and NotificationRecords.
+// Changes to the status of the returned Notifier are not reflected in the ClusterNotifier.
+func (r *ClusterNotifier) AsNotifier(namespace string) *Notifier {
+	return &Notifier{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersion.String(),
+			Kind:       ClusterNotifierKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       r.GetName(),
+			Namespace:  namespace,
+			UID:        r.GetUID(),
+			Generation: r.GetGeneration(),
+			Labels:     r.GetLabels(),
+		},
+		Spec:   *r.Spec.NotifierSpec.DeepCopy(),
+		Status: *r.Status.DeepCopy(),
+	}
+}
+
+// SelectsNamespace reports whether Events in the namespace with the labels are watched
+func (r *ClusterNotifier) SelectsNamespace(namespaceLabels map[string]string) (bool, error) {
+	if r.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(r.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespaceLabels)), nil
+}
+
+// UsesNamespaceLabels reports whether any ClusterNotifier selects namespaces by their labels
+func (r ClusterNotifierList) UsesNamespaceLabels() bool {
+	for _, notifier := range r.Items {
+		if notifier.Spec.NamespaceSelector != nil {
+			return true
+		}
+	}
+	return false
+}
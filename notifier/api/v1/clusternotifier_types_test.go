@@ -0,0 +1,86 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ClusterNotifier", func() {
+	var clusterNotifier *ClusterNotifier
+
+	BeforeEach(func() {
+		clusterNotifier = &ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform", UID: "b1e1c5c4", Generation: 2},
+			Spec: ClusterNotifierSpec{
+				NotifierSpec: NotifierSpec{
+					Email:   "platform@example.com",
+					Filters: []string{"BackOff"},
+				},
+			},
+		}
+	})
+
+	It("should act as a Notifier in the given namespace", func() {
+		clusterNotifier.Status.DeliveredCount = 3
+
+		notifier := clusterNotifier.AsNotifier("notifier-system")
+		Expect(notifier.IsClusterNotifier()).To(BeTrue())
+		Expect(notifier.GetNamespace()).To(Equal("notifier-system"))
+		Expect(notifier.GetUID()).To(Equal(clusterNotifier.GetUID()))
+		Expect(notifier.GetGeneration()).To(BeEquivalentTo(2))
+		Expect(notifier.Spec).To(Equal(clusterNotifier.Spec.NotifierSpec))
+		Expect(notifier.Status.DeliveredCount).To(BeEquivalentTo(3))
+
+		notifier.Spec.Filters[0] = "Changed"
+		Expect(clusterNotifier.Spec.Filters).To(Equal([]string{"BackOff"}))
+		Expect(Notifier{}.IsClusterNotifier()).To(BeFalse())
+	})
+
+	It("should select namespaces by their labels", func() {
+		Expect(clusterNotifier.SelectsNamespace(nil)).To(BeTrue())
+		Expect(ClusterNotifierList{Items: []ClusterNotifier{*clusterNotifier}}.UsesNamespaceLabels()).To(BeFalse())
+
+		clusterNotifier.Spec.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "env",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{"prod", "staging"},
+			}},
+		}
+		Expect(ClusterNotifierList{Items: []ClusterNotifier{*clusterNotifier}}.UsesNamespaceLabels()).To(BeTrue())
+		Expect(clusterNotifier.SelectsNamespace(map[string]string{"env": "prod"})).To(BeTrue())
+		Expect(clusterNotifier.SelectsNamespace(map[string]string{"env": "dev"})).To(BeFalse())
+		Expect(clusterNotifier.SelectsNamespace(nil)).To(BeFalse())
+	})
+
+	It("should reject invalid selectors and filters on admission", func() {
+		Expect(clusterNotifier.ValidateCreate()).To(Succeed())
+
+		clusterNotifier.Spec.Filters = []string{"(unclosed"}
+		clusterNotifier.Spec.NamespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "env", Operator: "Near"}},
+		}
+		err := clusterNotifier.ValidateUpdate(clusterNotifier.DeepCopy())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.filters[0]"))
+		Expect(err.Error()).To(ContainSubstring("spec.namespaceSelector"))
+	})
+})
@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var clusternotifierlog = logf.Log.WithName("clusternotifier-resource")
+
+// +kubebuilder:webhook:path=/mutate-email-notify-io-v1-clusternotifier,mutating=true,failurePolicy=fail,groups=email.notify.io,resources=clusternotifiers,verbs=create;update,versions=v1,name=mclusternotifier.kb.io
+
+var _ webhook.Defaulter = &ClusterNotifier{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ClusterNotifier) Default() {
+	clusternotifierlog.Info("default", "name", r.Name)
+	r.Spec.NotifierSpec.Default()
+}
+
+// +kubebuilder:webhook:path=/validate-email-notify-io-v1-clusternotifier,mutating=false,failurePolicy=fail,groups=email.notify.io,resources=clusternotifiers,verbs=create;update,versions=v1,name=vclusternotifier.kb.io
+
+var _ webhook.Validator = &ClusterNotifier{}
+
+// ValidateCreate rejects ClusterNotifiers the controller can't work with
+func (r *ClusterNotifier) ValidateCreate() error {
+	clusternotifierlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate rejects updates the controller can't work with
+func (r *ClusterNotifier) ValidateUpdate(old runtime.Object) error {
+	clusternotifierlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+func (r *ClusterNotifier) validate() error {
+	allErrs := r.AsNotifier("").validateSpec()
+	if r.Spec.NamespaceSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.NamespaceSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "namespaceSelector"), "", err.Error()))
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind(ClusterNotifierKind).GroupKind(), r.Name, allErrs)
+}
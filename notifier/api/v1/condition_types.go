@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the name of a condition
+type ConditionType string
+
+const (
+	// ConditionReady is True when the Notifier is configured correctly and can deliver notifications
+	ConditionReady ConditionType = "Ready"
+	// ConditionDeliveryDegraded is True when the last delivery attempt failed
+	ConditionDeliveryDegraded ConditionType = "DeliveryDegraded"
+	// ConditionInvalidFilter is True when a filter can't be compiled
+	ConditionInvalidFilter ConditionType = "InvalidFilter"
+	// ConditionInvalidTemplate is True when the message template can't be parsed or rendered
+	ConditionInvalidTemplate ConditionType = "InvalidTemplate"
+)
+
+// Condition describes one aspect of the observed state
+type Condition struct {
+	Type   ConditionType          `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime is when the status last changed
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is a CamelCase identifier of the cause
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the cause
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// FindCondition returns the condition of the given type, or nil
+func FindCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition is present and True
+func IsConditionTrue(conditions []Condition, conditionType ConditionType) bool {
+	condition := FindCondition(conditions, conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or updates the condition, the transition time only changes along with the status
+func SetCondition(conditions *[]Condition, conditionType ConditionType, status corev1.ConditionStatus, reason, message string) {
+	condition := FindCondition(*conditions, conditionType)
+	if condition == nil {
+		*conditions = append(*conditions, Condition{Type: conditionType})
+		condition = &(*conditions)[len(*conditions)-1]
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+}
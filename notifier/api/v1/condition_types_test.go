@@ -0,0 +1,49 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Conditions", func() {
+	It("should only move the transition time when the status changes", func() {
+		conditions := []Condition{}
+
+		SetCondition(&conditions, ConditionReady, corev1.ConditionFalse, "InvalidFilter", "bad regexp")
+		Expect(conditions).To(HaveLen(1))
+		Expect(IsConditionTrue(conditions, ConditionReady)).To(BeFalse())
+
+		transition := metav1.NewTime(conditions[0].LastTransitionTime.Add(-60e9))
+		conditions[0].LastTransitionTime = transition
+
+		SetCondition(&conditions, ConditionReady, corev1.ConditionFalse, "InvalidConfiguration", "no Secret")
+		Expect(conditions[0].LastTransitionTime).To(Equal(transition))
+		Expect(conditions[0].Reason).To(Equal("InvalidConfiguration"))
+
+		SetCondition(&conditions, ConditionReady, corev1.ConditionTrue, "Configured", "")
+		Expect(conditions[0].LastTransitionTime).NotTo(Equal(transition))
+		Expect(IsConditionTrue(conditions, ConditionReady)).To(BeTrue())
+
+		SetCondition(&conditions, ConditionDeliveryDegraded, corev1.ConditionFalse, "NoFailures", "")
+		Expect(conditions).To(HaveLen(2))
+		Expect(FindCondition(conditions, ConditionInvalidFilter)).To(BeNil())
+	})
+})
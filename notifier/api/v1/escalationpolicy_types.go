@@ -0,0 +1,121 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AcknowledgedByAnnotation acknowledges a NotificationRecord, stopping its escalation.
+// The value tells who acknowledged it.
+const AcknowledgedByAnnotation = "email.notify.io/acknowledged-by"
+
+// EscalationPolicySpec defines the desired state of EscalationPolicy
+type EscalationPolicySpec struct {
+	// Tiers are notified in turn, until the notification is acknowledged
+	// +kubebuilder:validation:MinItems=1
+	Tiers []EscalationTier `json:"tiers"`
+}
+
+// EscalationTier is a group of recipients notified once the notification was left unacknowledged for a while
+type EscalationTier struct {
+	// Name describes the tier in notifications, like on-call or manager
+	Name string `json:"name"`
+
+	// After is how long after the first notification the tier is notified, tiers are ordered by it
+	After metav1.Duration `json:"after"`
+
+	// Channels are the recipients of the tier, Secrets are read from the Notifier namespace
+	// +kubebuilder:validation:MinItems=1
+	Channels []Channel `json:"channels"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// EscalationPolicy notifies further recipients about notifications nobody acknowledged in time
+type EscalationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EscalationPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EscalationPolicyList contains a list of EscalationPolicy
+type EscalationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EscalationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EscalationPolicy{}, &EscalationPolicyList{})
+}
+
+// EscalationStatus tracks the escalation of a sent NotificationRecord
+type EscalationStatus struct {
+	// Policy is the name of the EscalationPolicy
+	Policy string `json:"policy"`
+
+	// StartTime is when the record was first sent, the tier delays count from it
+	StartTime metav1.Time `json:"startTime"`
+
+	// Level is the number of tiers notified so far
+	// +optional
+	Level int32 `json:"level,omitempty"`
+
+	// NextTime is when the next tier is notified, unset once every tier was notified or it was acknowledged
+	// +optional
+	NextTime *metav1.Time `json:"nextTime,omitempty"`
+
+	// FailedAttempts is the number of attempts to notify the next tier failed in a row.
+	// The tier is skipped once it failed on every attempt allowed by the Notifier retry policy.
+	// +optional
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+
+	// DeliveredTo lists the channels of the next tier which received the notification already, they are skipped on retries
+	// +optional
+	DeliveredTo []string `json:"deliveredTo,omitempty"`
+
+	// LastError describes why the last attempt to notify a tier failed
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// AcknowledgedTime is when the escalation was stopped
+	// +optional
+	AcknowledgedTime *metav1.Time `json:"acknowledgedTime,omitempty"`
+
+	// AcknowledgedBy is who acknowledged the notification
+	// +optional
+	AcknowledgedBy string `json:"acknowledgedBy,omitempty"`
+}
+
+// IsPending reports whether further tiers are waiting to be notified
+func (s *EscalationStatus) IsPending() bool {
+	return s != nil && s.AcknowledgedTime == nil && s.NextTime != nil
+}
+
+// NextTier is the time the tier at the level is due, false when there are no more tiers
+func (p *EscalationPolicy) NextTier(start time.Time, level int32) (time.Time, bool) {
+	if int(level) >= len(p.Spec.Tiers) {
+		return time.Time{}, false
+	}
+	return start.Add(p.Spec.Tiers[level].After.Duration), true
+}
@@ -0,0 +1,73 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("EscalationPolicy", func() {
+	var policy *EscalationPolicy
+
+	BeforeEach(func() {
+		policy = &EscalationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: "apps"},
+			Spec: EscalationPolicySpec{Tiers: []EscalationTier{
+				{Name: "team", Channels: []Channel{{Type: " Email", Email: " team@example.com "}}},
+				{Name: "on-call", After: metav1.Duration{Duration: 15 * time.Minute}, Channels: []Channel{{Type: WebhookChannel, URL: "https://hooks.example.com/oncall"}}},
+				{Name: "manager", After: metav1.Duration{Duration: time.Hour}, Channels: []Channel{{Type: EmailChannel, Email: "manager@example.com"}}},
+			}},
+		}
+	})
+
+	It("should schedule each tier after the first notification", func() {
+		start := time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+		next, ok := policy.NextTier(start, 0)
+		Expect(ok).To(BeTrue())
+		Expect(next).To(Equal(start))
+		next, _ = policy.NextTier(start, 2)
+		Expect(next).To(Equal(start.Add(time.Hour)))
+		_, ok = policy.NextTier(start, 3)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should normalize the channels of every tier", func() {
+		policy.Default()
+		Expect(policy.Spec.Tiers[0].Channels[0]).To(Equal(Channel{Type: EmailChannel, Email: "team@example.com"}))
+		Expect(policy.ValidateCreate()).To(Succeed())
+	})
+
+	It("should reject unordered tiers and invalid channels", func() {
+		policy.Default()
+		policy.Spec.Tiers[1].After.Duration = 2 * time.Hour
+		policy.Spec.Tiers[2].Name = ""
+		policy.Spec.Tiers[2].Channels = []Channel{{Type: SlackChannel}}
+		err := policy.ValidateUpdate(policy.DeepCopy())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.tiers[2].after"))
+		Expect(err.Error()).To(ContainSubstring("spec.tiers[2].name: Required value"))
+		Expect(err.Error()).To(ContainSubstring("spec.tiers[2].channels[0]"))
+
+		policy.Spec.Tiers = nil
+		Expect(policy.ValidateCreate()).To(MatchError(ContainSubstring("at least one tier is required")))
+	})
+})
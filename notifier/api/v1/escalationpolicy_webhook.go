@@ -0,0 +1,94 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var escalationpolicylog = logf.Log.WithName("escalationpolicy-resource")
+
+// +kubebuilder:webhook:path=/mutate-email-notify-io-v1-escalationpolicy,mutating=true,failurePolicy=fail,groups=email.notify.io,resources=escalationpolicies,verbs=create;update,versions=v1,name=mescalationpolicy.kb.io
+
+var _ webhook.Defaulter = &EscalationPolicy{}
+
+// Default normalizes the tier channels like those of Notifiers
+func (r *EscalationPolicy) Default() {
+	escalationpolicylog.Info("default", "name", r.Name)
+	for i := range r.Spec.Tiers {
+		defaultChannels(r.Spec.Tiers[i].Channels)
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-email-notify-io-v1-escalationpolicy,mutating=false,failurePolicy=fail,groups=email.notify.io,resources=escalationpolicies,verbs=create;update,versions=v1,name=vescalationpolicy.kb.io
+
+var _ webhook.Validator = &EscalationPolicy{}
+
+// SetupWebhookWithManager registers the webhooks, EscalationPolicies have no controller doing it
+func (r *EscalationPolicy) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/mutate-email-notify-io-v1-escalationpolicy", admission.DefaultingWebhookFor(r))
+	mgr.GetWebhookServer().Register("/validate-email-notify-io-v1-escalationpolicy", admission.ValidatingWebhookFor(r))
+	return nil
+}
+
+// ValidateCreate rejects policies with unordered tiers or invalid channels
+func (r *EscalationPolicy) ValidateCreate() error {
+	escalationpolicylog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate rejects policies with unordered tiers or invalid channels
+func (r *EscalationPolicy) ValidateUpdate(old runtime.Object) error {
+	escalationpolicylog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+func (r *EscalationPolicy) validate() error {
+	var allErrs field.ErrorList
+	tiers := field.NewPath("spec", "tiers")
+
+	if len(r.Spec.Tiers) == 0 {
+		allErrs = append(allErrs, field.Required(tiers, "at least one tier is required"))
+	}
+	for i, tier := range r.Spec.Tiers {
+		path := tiers.Index(i)
+		if tier.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+		}
+		if tier.After.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("after"), tier.After.Duration.String(), "must not be negative"))
+		} else if i > 0 && tier.After.Duration < r.Spec.Tiers[i-1].After.Duration {
+			allErrs = append(allErrs, field.Invalid(path.Child("after"), tier.After.Duration.String(), "tiers must be ordered by after"))
+		}
+		if len(tier.Channels) == 0 {
+			allErrs = append(allErrs, field.Required(path.Child("channels"), "at least one channel is required"))
+		}
+		for j, channel := range tier.Channels {
+			allErrs = append(allErrs, validateChannel(channel, path.Child("channels").Index(j))...)
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("EscalationPolicy").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,325 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// StringMatch compares a single value. Exactly one of the operators has to be set.
+type StringMatch struct {
+	// Regex matches anywhere in the value, anchor it with ^ and $ to match the whole value
+	// +optional
+	Regex string `json:"regex,omitempty"`
+
+	// Exact matches the whole value
+	// +optional
+	Exact string `json:"exact,omitempty"`
+
+	// Glob matches the whole value, * matches any sequence of characters and ? a single one
+	// +optional
+	Glob string `json:"glob,omitempty"`
+}
+
+// EventMatcher matches an Event when all of its fields match
+type EventMatcher struct {
+	// Reason of the Event
+	// +optional
+	Reason *StringMatch `json:"reason,omitempty"`
+
+	// Message of the Event
+	// +optional
+	Message *StringMatch `json:"message,omitempty"`
+
+	// Type of the Event, Normal or Warning
+	// +optional
+	Type *StringMatch `json:"type,omitempty"`
+
+	// Kind of the involved object
+	// +optional
+	Kind *StringMatch `json:"kind,omitempty"`
+
+	// Name of the involved object
+	// +optional
+	Name *StringMatch `json:"name,omitempty"`
+
+	// Namespace of the involved object
+	// +optional
+	Namespace *StringMatch `json:"namespace,omitempty"`
+
+	// Component which reported the Event
+	// +optional
+	Component *StringMatch `json:"component,omitempty"`
+
+	// MinCount is the number of occurrences the Event needs to match
+	// +optional
+	MinCount int32 `json:"minCount,omitempty"`
+
+	// Labels of the involved object, every listed label has to be present and match
+	// +optional
+	Labels map[string]StringMatch `json:"labels,omitempty"`
+}
+
+// EventFilter composes matchers: every matcher in all, at least one in any, and none in none has to match.
+// Empty lists are ignored.
+type EventFilter struct {
+	// +optional
+	All []EventMatcher `json:"all,omitempty"`
+
+	// +optional
+	Any []EventMatcher `json:"any,omitempty"`
+
+	// +optional
+	None []EventMatcher `json:"none,omitempty"`
+}
+
+// FilterInput is what an EventFilter is evaluated against
+type FilterInput struct {
+	Event *corev1.Event
+	// Labels of the involved object, nil when they were not fetched or the object is gone
+	Labels map[string]string
+}
+
+// Matcher reports whether a value matches
+// +kubebuilder:object:generate=false
+type Matcher func(value string) bool
+
+// Validate reports the first operator which is missing, ambiguous or not a valid expression
+func (m StringMatch) Validate() error {
+	_, err := m.Compile()
+	return err
+}
+
+// Match compares the value, invalid expressions never match
+func (m StringMatch) Match(value string) bool {
+	match, err := m.Compile()
+	return err == nil && match(value)
+}
+
+// Compile builds the Matcher, compiling the expression once
+func (m StringMatch) Compile() (Matcher, error) {
+	set := 0
+	for _, operator := range []string{m.Regex, m.Exact, m.Glob} {
+		if operator != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one of regex, exact or glob has to be set")
+	}
+
+	switch {
+	case m.Regex != "":
+		expr, err := regexp.Compile(m.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %v", m.Regex, err)
+		}
+		return expr.MatchString, nil
+	case m.Glob != "":
+		return globRegexp(m.Glob).MatchString, nil
+	default:
+		exact := m.Exact
+		return func(value string) bool { return value == exact }, nil
+	}
+}
+
+// globRegexp translates the glob into an anchored regular expression
+func globRegexp(glob string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(glob)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	expr = strings.Replace(expr, `\?`, ".", -1)
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// eventField is a field of the matcher and the Event value it is compared to
+type eventField struct {
+	name  string
+	match func(m *EventMatcher) *StringMatch
+	value func(event *corev1.Event) string
+}
+
+var eventFields = []eventField{
+	{"reason", func(m *EventMatcher) *StringMatch { return m.Reason }, func(e *corev1.Event) string { return e.Reason }},
+	{"message", func(m *EventMatcher) *StringMatch { return m.Message }, func(e *corev1.Event) string { return e.Message }},
+	{"type", func(m *EventMatcher) *StringMatch { return m.Type }, func(e *corev1.Event) string { return e.Type }},
+	{"kind", func(m *EventMatcher) *StringMatch { return m.Kind }, func(e *corev1.Event) string { return e.InvolvedObject.Kind }},
+	{"name", func(m *EventMatcher) *StringMatch { return m.Name }, func(e *corev1.Event) string { return e.InvolvedObject.Name }},
+	{"namespace", func(m *EventMatcher) *StringMatch { return m.Namespace }, func(e *corev1.Event) string { return e.InvolvedObject.Namespace }},
+	{"component", func(m *EventMatcher) *StringMatch { return m.Component }, func(e *corev1.Event) string { return e.Source.Component }},
+}
+
+// compiledField is a compiled field of the matcher
+type compiledField struct {
+	match Matcher
+	value func(event *corev1.Event) string
+}
+
+// CompiledMatcher is an EventMatcher with its expressions compiled
+// +kubebuilder:object:generate=false
+type CompiledMatcher struct {
+	fields   []compiledField
+	minCount int32
+	labels   map[string]Matcher
+}
+
+// Validate reports the first invalid field
+func (m EventMatcher) Validate() error {
+	_, err := m.Compile()
+	return err
+}
+
+// Match reports whether every field set on the matcher matches, invalid matchers never match
+func (m EventMatcher) Match(input FilterInput) bool {
+	compiled, err := m.Compile()
+	return err == nil && compiled.Match(input)
+}
+
+// Compile compiles every field set on the matcher
+func (m EventMatcher) Compile() (*CompiledMatcher, error) {
+	compiled := &CompiledMatcher{minCount: m.MinCount, labels: map[string]Matcher{}}
+	for _, field := range eventFields {
+		match := field.match(&m)
+		if match == nil {
+			continue
+		}
+		matcher, err := match.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", field.name, err)
+		}
+		compiled.fields = append(compiled.fields, compiledField{match: matcher, value: field.value})
+	}
+
+	labels := []string{}
+	for label := range m.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		matcher, err := m.Labels[label].Compile()
+		if err != nil {
+			return nil, fmt.Errorf("labels[%s]: %v", label, err)
+		}
+		compiled.labels[label] = matcher
+	}
+
+	if m.MinCount < 0 {
+		return nil, fmt.Errorf("minCount must not be negative")
+	}
+	return compiled, nil
+}
+
+// Match reports whether every field set on the matcher matches
+func (m *CompiledMatcher) Match(input FilterInput) bool {
+	for _, field := range m.fields {
+		if !field.match(field.value(input.Event)) {
+			return false
+		}
+	}
+	if input.Event.Count < m.minCount {
+		return false
+	}
+	for label, match := range m.labels {
+		value, found := input.Labels[label]
+		if !found || !match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// CompiledFilter is an EventFilter with its expressions compiled
+// +kubebuilder:object:generate=false
+type CompiledFilter struct {
+	all, any, none []*CompiledMatcher
+	usesLabels     bool
+}
+
+// Validate reports the first invalid matcher
+func (f EventFilter) Validate() error {
+	_, err := f.Compile()
+	return err
+}
+
+// Match evaluates the composition of all matchers, invalid filters never match
+func (f EventFilter) Match(input FilterInput) bool {
+	compiled, err := f.Compile()
+	return err == nil && compiled.Match(input)
+}
+
+// Compile compiles every matcher of the filter
+func (f EventFilter) Compile() (*CompiledFilter, error) {
+	compiled := &CompiledFilter{usesLabels: f.UsesLabels()}
+	lists := []struct {
+		name     string
+		matchers []EventMatcher
+		compiled *[]*CompiledMatcher
+	}{{"all", f.All, &compiled.all}, {"any", f.Any, &compiled.any}, {"none", f.None, &compiled.none}}
+	for _, list := range lists {
+		for i, matcher := range list.matchers {
+			compiledMatcher, err := matcher.Compile()
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d].%v", list.name, i, err)
+			}
+			*list.compiled = append(*list.compiled, compiledMatcher)
+		}
+	}
+	return compiled, nil
+}
+
+// Match evaluates the composition of all matchers
+func (f *CompiledFilter) Match(input FilterInput) bool {
+	for _, matcher := range f.all {
+		if !matcher.Match(input) {
+			return false
+		}
+	}
+	for _, matcher := range f.none {
+		if matcher.Match(input) {
+			return false
+		}
+	}
+	if len(f.any) == 0 {
+		return true
+	}
+	for _, matcher := range f.any {
+		if matcher.Match(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesLabels reports whether the involved object labels are needed to evaluate the filter
+func (f *CompiledFilter) UsesLabels() bool {
+	return f.usesLabels
+}
+
+// UsesLabels reports whether the involved object labels are needed to evaluate the filter
+func (f EventFilter) UsesLabels() bool {
+	for _, list := range [][]EventMatcher{f.All, f.Any, f.None} {
+		for _, matcher := range list {
+			if len(matcher.Labels) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
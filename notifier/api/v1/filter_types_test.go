@@ -0,0 +1,119 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("EventFilter", func() {
+	var input FilterInput
+
+	BeforeEach(func() {
+		input = FilterInput{
+			Event: &corev1.Event{
+				Type:    corev1.EventTypeWarning,
+				Reason:  "BackOff",
+				Message: "Back-off restarting failed container",
+				Count:   5,
+				Source:  corev1.EventSource{Component: "kubelet"},
+				InvolvedObject: corev1.ObjectReference{
+					Kind:      "Pod",
+					Namespace: "payments",
+					Name:      "api-7d9f8b6c4-x2x8z",
+				},
+			},
+			Labels: map[string]string{"app": "api", "tier": "backend"},
+		}
+	})
+
+	It("should match strings with every operator", func() {
+		Expect(StringMatch{Regex: "Back"}.Match("BackOff")).To(BeTrue())
+		Expect(StringMatch{Regex: "^Off"}.Match("BackOff")).To(BeFalse())
+		Expect(StringMatch{Exact: "BackOff"}.Match("BackOff")).To(BeTrue())
+		Expect(StringMatch{Exact: "Back"}.Match("BackOff")).To(BeFalse())
+		Expect(StringMatch{Glob: "api-*"}.Match("api-7d9f8b6c4-x2x8z")).To(BeTrue())
+		Expect(StringMatch{Glob: "api-?"}.Match("api-7d9f8b6c4-x2x8z")).To(BeFalse())
+		Expect(StringMatch{Glob: "a.i-*"}.Match("api-1")).To(BeFalse())
+	})
+
+	It("should require every field of a matcher", func() {
+		matcher := EventMatcher{
+			Kind:      &StringMatch{Exact: "Pod"},
+			Name:      &StringMatch{Glob: "api-*"},
+			Component: &StringMatch{Exact: "kubelet"},
+			MinCount:  5,
+			Labels:    map[string]StringMatch{"tier": {Exact: "backend"}},
+		}
+		Expect(matcher.Match(input)).To(BeTrue())
+
+		matcher.MinCount = 6
+		Expect(matcher.Match(input)).To(BeFalse())
+
+		matcher.MinCount = 0
+		matcher.Labels["team"] = StringMatch{Regex: ".*"}
+		Expect(matcher.Match(input)).To(BeFalse())
+	})
+
+	It("should compose matchers with all, any and none", func() {
+		filter := EventFilter{
+			All:  []EventMatcher{{Type: &StringMatch{Exact: "Warning"}}},
+			Any:  []EventMatcher{{Reason: &StringMatch{Exact: "OOMKilling"}}, {Message: &StringMatch{Regex: "restarting"}}},
+			None: []EventMatcher{{Namespace: &StringMatch{Glob: "kube-*"}}},
+		}
+		Expect(filter.Match(input)).To(BeTrue())
+
+		input.Event.InvolvedObject.Namespace = "kube-system"
+		Expect(filter.Match(input)).To(BeFalse())
+
+		input.Event.InvolvedObject.Namespace = "payments"
+		input.Event.Message = "Liveness probe failed"
+		Expect(filter.Match(input)).To(BeFalse())
+
+		Expect(EventFilter{}.Match(input)).To(BeTrue())
+	})
+
+	It("should report invalid matchers", func() {
+		Expect(EventFilter{Any: []EventMatcher{{Reason: &StringMatch{Regex: "Back(Off"}}}}.Validate()).
+			To(MatchError(ContainSubstring(`any[0].reason: invalid regex "Back(Off"`)))
+		Expect(EventFilter{All: []EventMatcher{{Kind: &StringMatch{Exact: "Pod", Glob: "P*"}}}}.Validate()).
+			To(MatchError("all[0].kind: exactly one of regex, exact or glob has to be set"))
+		Expect(EventFilter{None: []EventMatcher{{Labels: map[string]StringMatch{"app": {}}}}}.Validate()).
+			To(MatchError("none[0].labels[app]: exactly one of regex, exact or glob has to be set"))
+		Expect(EventFilter{All: []EventMatcher{{MinCount: 3, Labels: map[string]StringMatch{"app": {Exact: "api"}}}}}.Validate()).
+			To(Succeed())
+	})
+
+	It("should keep the reason filters working next to match", func() {
+		notifier := Notifier{Spec: NotifierSpec{
+			Filters: []string{"Back"},
+			Match:   &EventFilter{All: []EventMatcher{{Labels: map[string]StringMatch{"app": {Exact: "api"}}}}},
+		}}
+		Expect(notifier.UsesLabels()).To(BeTrue())
+		Expect(notifier.Match(input)).To(BeTrue())
+
+		notifier.Spec.Filters = []string{"OOM"}
+		Expect(notifier.Match(input)).To(BeFalse())
+
+		notifier.Spec.Filters = nil
+		notifier.Spec.Match = nil
+		Expect(notifier.UsesLabels()).To(BeFalse())
+		Expect(notifier.Match(input)).To(BeTrue())
+	})
+})
@@ -0,0 +1,197 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaxIncidentObjects caps the involved objects listed in the incident status, the others are only counted
+const MaxIncidentObjects = 20
+
+// IncidentState tells whether related Events still join the incident
+// +kubebuilder:validation:Enum=Open;Closed
+type IncidentState string
+
+const (
+	// IncidentOpen incidents take the related Events arriving within the window
+	IncidentOpen IncidentState = "Open"
+	// IncidentClosed incidents had no related Event within the window, the next one reopens them
+	IncidentClosed IncidentState = "Closed"
+)
+
+// IncidentSpec identifies what the grouped Events have in common
+type IncidentSpec struct {
+	// Notifier is the name of the Notifier in the same namespace, or the name of the ClusterNotifier
+	Notifier string `json:"notifier"`
+
+	// NotifierKind is ClusterNotifier for incidents of a ClusterNotifier, Notifier otherwise
+	// +kubebuilder:validation:Enum=Notifier;ClusterNotifier
+	// +optional
+	NotifierKind string `json:"notifierKind,omitempty"`
+
+	// Namespace of the workload, which differs from the incident namespace for ClusterNotifiers
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Workload is the top most controller of the involved objects, like a Deployment
+	// +optional
+	Workload *WorkloadReference `json:"workload,omitempty"`
+
+	// NodeName is the node of the involved objects, for Events about nodes or objects without a workload
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Window is how long the incident stays open after its last Event
+	Window metav1.Duration `json:"window"`
+}
+
+// IncidentStatus defines the observed state of Incident
+type IncidentStatus struct {
+	// State is Open while related Events keep arriving within the window
+	// +optional
+	State IncidentState `json:"state,omitempty"`
+
+	// OpenedTime is when the first Event of the incident arrived
+	// +optional
+	OpenedTime *metav1.Time `json:"openedTime,omitempty"`
+
+	// LastEventTime is when the last Event joined the incident
+	// +optional
+	LastEventTime *metav1.Time `json:"lastEventTime,omitempty"`
+
+	// ClosedTime is when the window passed without a related Event
+	// +optional
+	ClosedTime *metav1.Time `json:"closedTime,omitempty"`
+
+	// Record is the NotificationRecord notifying about the incident, the records of the other Events are suppressed
+	// +optional
+	Record string `json:"record,omitempty"`
+
+	// EventCount is the number of Events grouped into the incident
+	// +optional
+	EventCount int32 `json:"eventCount,omitempty"`
+
+	// ObjectCount is the number of distinct involved objects, objects past the listed ones may be counted twice
+	// +optional
+	ObjectCount int32 `json:"objectCount,omitempty"`
+
+	// Objects lists the first involved objects
+	// +optional
+	Objects []corev1.ObjectReference `json:"objects,omitempty"`
+
+	// Reasons of the grouped Events
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Notifier",type="string",JSONPath=".spec.notifier"
+// +kubebuilder:printcolumn:name="Workload",type="string",JSONPath=".spec.workload.name"
+// +kubebuilder:printcolumn:name="Node",type="string",JSONPath=".spec.nodeName"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Events",type="integer",JSONPath=".status.eventCount"
+// +kubebuilder:printcolumn:name="Objects",type="integer",JSONPath=".status.objectCount"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Incident groups the Events about the same workload or node, so they are notified once
+type Incident struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IncidentSpec   `json:"spec,omitempty"`
+	Status IncidentStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// IncidentList contains a list of Incident
+type IncidentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Incident `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Incident{}, &IncidentList{})
+}
+
+// IsOpen reports whether an Event at the time joins the incident
+func (i *Incident) IsOpen(now time.Time) bool {
+	return i.Status.State == IncidentOpen && i.Status.LastEventTime != nil &&
+		now.Sub(i.Status.LastEventTime.Time) < i.Spec.Window.Duration
+}
+
+// ClosesIn is how long the incident stays open without a related Event
+func (i *Incident) ClosesIn(now time.Time) time.Duration {
+	if i.Status.State != IncidentOpen || i.Status.LastEventTime == nil {
+		return 0
+	}
+	if wait := i.Spec.Window.Duration - now.Sub(i.Status.LastEventTime.Time); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Group is what the Events have in common, like Deployment apps/web or Node node-1
+func (i *Incident) Group() string {
+	if i.Spec.Workload != nil {
+		return i.Spec.Workload.Kind + " " + i.Spec.Namespace + "/" + i.Spec.Workload.Name
+	}
+	return "Node " + i.Spec.NodeName
+}
+
+// AddEvent counts the Event, reopening a closed incident
+func (i *Incident) AddEvent(reason string, object corev1.ObjectReference, now time.Time) {
+	status := &i.Status
+	at := metav1.NewTime(now)
+	if !i.IsOpen(now) {
+		*status = IncidentStatus{State: IncidentOpen, OpenedTime: &at}
+	}
+	status.LastEventTime = &at
+	status.EventCount++
+
+	if !containsReason(status.Reasons, reason) {
+		status.Reasons = append(status.Reasons, reason)
+	}
+	for _, known := range status.Objects {
+		if known.Kind == object.Kind && known.Namespace == object.Namespace && known.Name == object.Name {
+			return
+		}
+	}
+	status.ObjectCount++
+	if len(status.Objects) < MaxIncidentObjects {
+		status.Objects = append(status.Objects, corev1.ObjectReference{
+			APIVersion: object.APIVersion,
+			Kind:       object.Kind,
+			Namespace:  object.Namespace,
+			Name:       object.Name,
+		})
+	}
+}
+
+func containsReason(reasons []string, reason string) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Incident", func() {
+	var (
+		incident *Incident
+		start    time.Time
+	)
+
+	pod := func(name string) corev1.ObjectReference {
+		return corev1.ObjectReference{Kind: "Pod", Namespace: "apps", Name: name, UID: types.UID("uid-" + name)}
+	}
+
+	BeforeEach(func() {
+		start = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+		incident = &Incident{Spec: IncidentSpec{
+			Notifier:  "team",
+			Namespace: "apps",
+			Workload:  &WorkloadReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			Window:    metav1.Duration{Duration: 5 * time.Minute},
+		}}
+	})
+
+	It("should count the Events of each replica once the incident is open", func() {
+		Expect(incident.IsOpen(start)).To(BeFalse())
+		incident.AddEvent("BackOff", pod("web-1"), start)
+		incident.AddEvent("BackOff", pod("web-2"), start.Add(time.Minute))
+		incident.AddEvent("Unhealthy", pod("web-1"), start.Add(2*time.Minute))
+
+		Expect(incident.Status.State).To(Equal(IncidentOpen))
+		Expect(incident.Status.OpenedTime.Time).To(Equal(start))
+		Expect(incident.Status.LastEventTime.Time).To(Equal(start.Add(2 * time.Minute)))
+		Expect(incident.Status.EventCount).To(BeEquivalentTo(3))
+		Expect(incident.Status.ObjectCount).To(BeEquivalentTo(2))
+		Expect(incident.Status.Objects).To(Equal([]corev1.ObjectReference{
+			{Kind: "Pod", Namespace: "apps", Name: "web-1"},
+			{Kind: "Pod", Namespace: "apps", Name: "web-2"},
+		}))
+		Expect(incident.Status.Reasons).To(Equal([]string{"BackOff", "Unhealthy"}))
+		Expect(incident.Group()).To(Equal("Deployment apps/web"))
+	})
+
+	It("should stay open for the window after the last Event", func() {
+		incident.AddEvent("BackOff", pod("web-1"), start)
+		incident.AddEvent("BackOff", pod("web-1"), start.Add(4*time.Minute))
+		Expect(incident.ClosesIn(start.Add(5 * time.Minute))).To(Equal(4 * time.Minute))
+		Expect(incident.IsOpen(start.Add(8 * time.Minute))).To(BeTrue())
+		Expect(incident.IsOpen(start.Add(9 * time.Minute))).To(BeFalse())
+		Expect(incident.ClosesIn(start.Add(9 * time.Minute))).To(BeZero())
+	})
+
+	It("should start over when an Event reopens the incident", func() {
+		incident.AddEvent("BackOff", pod("web-1"), start)
+		incident.Status.State = IncidentClosed
+		incident.Status.Record = "team-3f2a9c1b7e"
+
+		reopened := start.Add(time.Hour)
+		incident.AddEvent("FailedMount", pod("web-3"), reopened)
+		Expect(incident.Status.State).To(Equal(IncidentOpen))
+		Expect(incident.Status.OpenedTime.Time).To(Equal(reopened))
+		Expect(incident.Status.ClosedTime).To(BeNil())
+		Expect(incident.Status.Record).To(BeEmpty())
+		Expect(incident.Status.EventCount).To(BeEquivalentTo(1))
+		Expect(incident.Status.Reasons).To(Equal([]string{"FailedMount"}))
+	})
+
+	It("should only list the first objects", func() {
+		for i := 0; i < MaxIncidentObjects+5; i++ {
+			incident.AddEvent("BackOff", pod(fmt.Sprintf("web-%d", i)), start)
+		}
+		Expect(incident.Status.Objects).To(HaveLen(MaxIncidentObjects))
+		Expect(incident.Status.ObjectCount).To(BeEquivalentTo(MaxIncidentObjects + 5))
+
+		node := &Incident{Spec: IncidentSpec{NodeName: "node-1"}}
+		Expect(node.Group()).To(Equal("Node node-1"))
+	})
+})
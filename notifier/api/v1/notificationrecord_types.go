@@ -0,0 +1,288 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecordState is the delivery state of a NotificationRecord
+// +kubebuilder:validation:Enum=Pending;Sent;Failed;Suppressed;DeadLettered
+type RecordState string
+
+const (
+	// RecordPending waits for the Notifier to deliver it
+	RecordPending RecordState = "Pending"
+	// RecordSent was delivered to every channel
+	RecordSent RecordState = "Sent"
+	// RecordFailed could not be delivered on the last attempt, it is retried
+	RecordFailed RecordState = "Failed"
+	// RecordSuppressed was dropped without delivery
+	RecordSuppressed RecordState = "Suppressed"
+	// RecordDeadLettered failed on every attempt allowed by the retry policy, it is not retried anymore
+	RecordDeadLettered RecordState = "DeadLettered"
+)
+
+// EventSnapshot is a copy of the Event fields used in notifications.
+// Events are short lived, so the record keeps what it needs to deliver them later.
+type EventSnapshot struct {
+	// Type of the Event, Normal or Warning
+	Type string `json:"type"`
+
+	// Reason is the short, machine readable cause of the Event
+	Reason string `json:"reason"`
+
+	// Message is the human readable description of the Event
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// InvolvedObject is the object the Event is about
+	InvolvedObject corev1.ObjectReference `json:"involvedObject"`
+
+	// Source is the component which reported the Event
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Count is the number of times the Event occurred
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// FirstTimestamp is when the Event was first recorded
+	// +optional
+	FirstTimestamp metav1.Time `json:"firstTimestamp,omitempty"`
+
+	// LastTimestamp is the time of the most recent occurrence
+	// +optional
+	LastTimestamp metav1.Time `json:"lastTimestamp,omitempty"`
+}
+
+// ObjectContext describes the workload behind the involved object, resolved when the record was created
+type ObjectContext struct {
+	// Owners is the controller chain of the involved object, from its direct owner up to the workload
+	// +optional
+	Owners []WorkloadReference `json:"owners,omitempty"`
+
+	// NodeName is the node the Pod is scheduled on
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Phase of the Pod
+	// +optional
+	Phase corev1.PodPhase `json:"phase,omitempty"`
+
+	// Containers are the statuses of the Pod containers, init containers first
+	// +optional
+	Containers []ContainerContext `json:"containers,omitempty"`
+}
+
+// WorkloadReference identifies a controller of the involved object
+type WorkloadReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// ContainerContext is the status of a container of the Pod
+type ContainerContext struct {
+	Name string `json:"name"`
+
+	// Init is set for init containers
+	// +optional
+	Init bool `json:"init,omitempty"`
+
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+
+	// +optional
+	RestartCount int32 `json:"restartCount,omitempty"`
+
+	// State is Waiting, Running or Terminated
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// Reason explains the state, like CrashLoopBackOff or Completed
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// ExitCode of the current termination, or of the last one when the container was restarted
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// LastTerminationReason is why the container last terminated, like OOMKilled or Error
+	// +optional
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+}
+
+// Workload is the top most controller of the involved object, like a Deployment or a CronJob
+func (c *ObjectContext) Workload() *WorkloadReference {
+	if c == nil || len(c.Owners) == 0 {
+		return nil
+	}
+	return &c.Owners[len(c.Owners)-1]
+}
+
+// String is Kind/name, like Deployment/web
+func (w WorkloadReference) String() string {
+	return w.Kind + "/" + w.Name
+}
+
+// NotificationRecordSpec defines the Event a Notifier has to deliver
+type NotificationRecordSpec struct {
+	// Notifier is the name of the Notifier in the same namespace delivering the record,
+	// or the name of the ClusterNotifier
+	Notifier string `json:"notifier"`
+
+	// NotifierKind is ClusterNotifier for records of a ClusterNotifier, Notifier otherwise
+	// +kubebuilder:validation:Enum=Notifier;ClusterNotifier
+	// +optional
+	NotifierKind string `json:"notifierKind,omitempty"`
+
+	// EventRef points to the Event, which may be gone by now
+	EventRef corev1.ObjectReference `json:"eventRef"`
+
+	// Event is the snapshot of the Event taken when the record was created
+	Event EventSnapshot `json:"event"`
+
+	// Silenced is why the Event is not notified, like Silence/<name> or QuietHours.
+	// Silenced records are suppressed by the Notifier.
+	// +optional
+	Silenced string `json:"silenced,omitempty"`
+
+	// Context is the owner workload, node and containers of the involved object, when they could be resolved
+	// +optional
+	Context *ObjectContext `json:"context,omitempty"`
+
+	// Incident is the name of the Incident the Event was grouped into.
+	// Only the first record of an Incident is notified, the others are silenced by it.
+	// +optional
+	Incident string `json:"incident,omitempty"`
+}
+
+// NotificationRecordStatus defines the observed state of NotificationRecord
+type NotificationRecordStatus struct {
+	// State is Pending until the record is delivered
+	// +optional
+	State RecordState `json:"state,omitempty"`
+
+	// Attempts is the number of delivery attempts
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// FailedAttempts is the number of attempts failed in a row, reset once the record is sent
+	// +optional
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+
+	// LastAttemptTime is when the delivery was last attempted
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// NextAttemptTime is when a failed delivery is retried
+	// +optional
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+
+	// DeliveredTo lists the channels which received the notification already, they are skipped on retries
+	// +optional
+	DeliveredTo []string `json:"deliveredTo,omitempty"`
+
+	// SentTime is when the record was last delivered to every channel
+	// +optional
+	SentTime *metav1.Time `json:"sentTime,omitempty"`
+
+	// SuppressedTime is when the record was dropped
+	// +optional
+	SuppressedTime *metav1.Time `json:"suppressedTime,omitempty"`
+
+	// SuppressedBy is why the record was dropped, like Duplicate or RateLimited
+	// +optional
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+
+	// NotifiedCount is the Event count at the last delivery
+	// +optional
+	NotifiedCount int32 `json:"notifiedCount,omitempty"`
+
+	// LastError describes why the last delivery attempt failed
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Escalation tracks the tiers of the Notifier escalation policy, once the record was sent
+	// +optional
+	Escalation *EscalationStatus `json:"escalation,omitempty"`
+
+	// ResolvedTime is when the incident about the involved object was resolved,
+	// the record opens no further incident unless the Event recurs later
+	// +optional
+	ResolvedTime *metav1.Time `json:"resolvedTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Notifier",type="string",JSONPath=".spec.notifier"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".spec.event.reason"
+// +kubebuilder:printcolumn:name="Object",type="string",JSONPath=".spec.event.involvedObject.name"
+// +kubebuilder:printcolumn:name="Count",type="integer",JSONPath=".spec.event.count"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Attempts",type="integer",JSONPath=".status.attempts"
+// +kubebuilder:printcolumn:name="Escalation",type="integer",JSONPath=".status.escalation.level",priority=1
+// +kubebuilder:printcolumn:name="Acknowledged By",type="string",JSONPath=".status.escalation.acknowledgedBy",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NotificationRecord tracks the delivery of a single Event by a single Notifier
+type NotificationRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotificationRecordSpec   `json:"spec,omitempty"`
+	Status NotificationRecordStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NotificationRecordList contains a list of NotificationRecord
+type NotificationRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationRecord{}, &NotificationRecordList{})
+}
+
+// IsPending reports whether the record still has to be delivered
+func (r NotificationRecord) IsPending() bool {
+	return r.Status.State != RecordSent && r.Status.State != RecordSuppressed && r.Status.State != RecordDeadLettered
+}
+
+// CompletionTime is when the record was sent, suppressed or dead-lettered, nil while it is pending
+func (r NotificationRecord) CompletionTime() *metav1.Time {
+	switch r.Status.State {
+	case RecordSent:
+		if r.Status.Escalation.IsPending() {
+			// Kept until it is acknowledged or every tier is notified
+			return nil
+		}
+		return r.Status.SentTime
+	case RecordSuppressed:
+		return r.Status.SuppressedTime
+	case RecordDeadLettered:
+		return r.Status.LastAttemptTime
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("NotificationRecord", func() {
+	Context("Create API", func() {
+		It("should create an object successfully", func() {
+			key := types.NamespacedName{
+				Name:      "foo-0123456789",
+				Namespace: "default",
+			}
+			created := &NotificationRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: NotificationRecordSpec{
+					Notifier: "foo",
+					EventRef: corev1.ObjectReference{Kind: "Event", Namespace: "default", Name: "test-pod.backoff"},
+					Event: EventSnapshot{
+						Type:           corev1.EventTypeWarning,
+						Reason:         "BackOff",
+						InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "test-pod"},
+					},
+				},
+			}
+
+			By("creating an API obj")
+			Expect(k8sClient.Create(context.TODO(), created)).To(Succeed())
+
+			fetched := &NotificationRecord{}
+			Expect(k8sClient.Get(context.TODO(), key, fetched)).To(Succeed())
+			Expect(fetched.Spec).To(Equal(created.Spec))
+			Expect(fetched.IsPending()).To(BeTrue())
+
+			By("deleting the created object")
+			Expect(k8sClient.Delete(context.TODO(), created)).To(Succeed())
+			Expect(k8sClient.Get(context.TODO(), key, created)).ToNot(Succeed())
+		})
+	})
+})
@@ -18,29 +18,586 @@ package v1
 import (
 	"fmt"
 	"regexp"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
-// NotifyPrefix Label prefix for current Notify resource
-const NotifyPrefix = "%s-notify"
+const (
+	// DefaultKind is watched by Notifiers which don't list their kinds
+	DefaultKind = "Pod"
+	// AnyKind watches Events about objects of every kind
+	AnyKind = "*"
+)
 
 // NotifierSpec defines the desired state of Notifier
 type NotifierSpec struct {
-	Email   string   `json:"email"`
-	Filters []string `json:"filters"`
+	// Email is a shorthand for a single email channel
+	// +optional
+	Email string `json:"email,omitempty"`
+
+	// Filters are regular expressions, which all have to match the Event reason.
+	// Kept for compatibility, match covers more fields.
+	// +optional
+	Filters []string `json:"filters,omitempty"`
+
+	// Kinds of the involved objects the Notifier watches Events for, like Node or Job.
+	// Defaults to Pod, * watches every kind.
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+
+	// Match selects Events by their fields and the labels of the involved object.
+	// When both are set, filters and match have to match.
+	// +optional
+	Match *EventFilter `json:"match,omitempty"`
+
+	// Channels lists additional destinations for the notifications
+	// +optional
+	Channels []Channel `json:"channels,omitempty"`
+
+	// Renotify sends the notification again while the Event keeps occurring.
+	// Without it, every Event is notified once.
+	// +optional
+	Renotify *RenotifyPolicy `json:"renotify,omitempty"`
+
+	// Digest aggregates the notifications into a single message per window
+	// +optional
+	Digest *DigestPolicy `json:"digest,omitempty"`
+
+	// Dedup suppresses notifications about the same failure within a window
+	// +optional
+	Dedup *DedupPolicy `json:"dedup,omitempty"`
+
+	// RateLimit caps the number of notifications sent by the Notifier
+	// +optional
+	RateLimit *RateLimitPolicy `json:"rateLimit,omitempty"`
+
+	// QuietHours suppresses the notifications during recurring windows, like nights or weekends
+	// +optional
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+
+	// Retry decides how failed deliveries are retried before they are dead-lettered.
+	// Defaults to 5 attempts with an exponential backoff from 10s up to 10m.
+	// +optional
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// EscalationPolicyRef points to an EscalationPolicy in the Notifier namespace,
+	// notifying further recipients about notifications nobody acknowledged in time
+	// +optional
+	EscalationPolicyRef *corev1.LocalObjectReference `json:"escalationPolicyRef,omitempty"`
+
+	// Logs attaches the previous logs of the failing container to notifications about crashing Pods
+	// +optional
+	Logs *LogsPolicy `json:"logs,omitempty"`
+
+	// Resolve tracks the objects the Notifier notified about, and sends a resolved notification once they recover
+	// +optional
+	Resolve *ResolvePolicy `json:"resolve,omitempty"`
+
+	// Grouping groups the Events about the same workload or node into Incidents, notified once
+	// +optional
+	Grouping *GroupingPolicy `json:"grouping,omitempty"`
+
+	// Template customizes the subject and body of the notifications
+	// +optional
+	Template *MessageTemplate `json:"template,omitempty"`
+
+	// SMTPSecretRef points to a Secret in the Notifier namespace with the mail relay settings.
+	// Recognized keys are host, port, username, password and from, plus optional tls and auth.
+	// When unset, the cluster-wide default configured on the manager is used.
+	// +optional
+	SMTPSecretRef *corev1.LocalObjectReference `json:"smtpSecretRef,omitempty"`
+}
+
+// RenotifyPolicy decides when repeated occurrences of an Event are notified again.
+// Kubernetes counts repeated Events on the existing object instead of creating a new one.
+type RenotifyPolicy struct {
+	// AfterCount notifies again once the Event occurred this many more times
+	// +optional
+	AfterCount int32 `json:"afterCount,omitempty"`
+
+	// After notifies again about new occurrences once this much time passed since the last notification
+	// +optional
+	After *metav1.Duration `json:"after,omitempty"`
+}
+
+// DigestPolicy batches the Events of a Notifier, grouped by reason and involved object
+type DigestPolicy struct {
+	// Window is how long Events are collected after the first one arrived
+	Window metav1.Duration `json:"window"`
+
+	// MaxItems sends the digest early once this many Events are collected, and limits the size of a single digest
+	// +optional
+	MaxItems int32 `json:"maxItems,omitempty"`
+}
+
+// DedupPolicy suppresses Events with the same reason, involved object and message.
+// Numbers in the message are ignored, so changing counters or durations don't defeat it.
+type DedupPolicy struct {
+	// Window is how long a sent notification suppresses its duplicates
+	Window metav1.Duration `json:"window"`
+}
+
+// RateLimitPolicy is a token bucket, holding up to burst tokens and refilled with one token every period.
+// Every notification, or digest, takes a token. Notifications without a token are dropped.
+type RateLimitPolicy struct {
+	// Burst is the capacity of the bucket
+	// +kubebuilder:validation:Minimum=1
+	Burst int32 `json:"burst"`
+
+	// Every is the time to refill a single token. Defaults to 1m.
+	// +optional
+	Every metav1.Duration `json:"every,omitempty"`
+}
+
+// DefaultRateLimitEvery is the time to refill a token without one in the rate limit policy
+const DefaultRateLimitEvery = time.Minute
+
+// GetEvery returns the refill period, with the default applied
+func (p *RateLimitPolicy) GetEvery() time.Duration {
+	if p.Every.Duration <= 0 {
+		return DefaultRateLimitEvery
+	}
+	return p.Every.Duration
+}
+
+const (
+	// DefaultMaxAttempts is the number of delivery attempts of a Notifier without a retry policy
+	DefaultMaxAttempts = 5
+	// DefaultRetryBackoff is the delay before the first retry
+	DefaultRetryBackoff = 10 * time.Second
+	// DefaultMaxRetryBackoff caps the delay between retries
+	DefaultMaxRetryBackoff = 10 * time.Minute
+)
+
+// RetryPolicy retries failed deliveries with an exponential backoff and jitter.
+// Notifications still failing after maxAttempts are dead-lettered.
+type RetryPolicy struct {
+	// MaxAttempts is the number of delivery attempts before a notification is dead-lettered
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// Backoff is the delay before the first retry, doubled with every further attempt
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts
+	// +optional
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+}
+
+// GetMaxAttempts returns the max attempts, with the default applied
+func (r *RetryPolicy) GetMaxAttempts() int32 {
+	if r == nil || r.MaxAttempts == 0 {
+		return DefaultMaxAttempts
+	}
+	return r.MaxAttempts
+}
+
+// GetBackoff returns the delay before the first retry, with the default applied
+func (r *RetryPolicy) GetBackoff() time.Duration {
+	if r == nil || r.Backoff == nil {
+		return DefaultRetryBackoff
+	}
+	return r.Backoff.Duration
+}
+
+// GetMaxBackoff returns the longest delay between attempts, with the default applied
+func (r *RetryPolicy) GetMaxBackoff() time.Duration {
+	if r == nil || r.MaxBackoff == nil {
+		return DefaultMaxRetryBackoff
+	}
+	return r.MaxBackoff.Duration
+}
+
+const (
+	// DefaultLogTailLines is the number of log lines attached without a limit in the logs policy
+	DefaultLogTailLines = 20
+	// DefaultLogLimitBytes caps the attached logs without a limit in the logs policy
+	DefaultLogLimitBytes = 4 * 1024
+	// MaxLogLimitBytes is the largest allowed limitBytes, logs end up in emails and chat messages
+	MaxLogLimitBytes = 64 * 1024
+)
+
+// DefaultLogReasons are the reasons of Events about crashing containers
+var DefaultLogReasons = []string{"BackOff", "CrashLoopBackOff"}
+
+// LogsPolicy decides which notifications carry container logs, and how much of them
+type LogsPolicy struct {
+	// Reasons are the Event reasons logs are attached for. Defaults to BackOff and CrashLoopBackOff.
+	// +optional
+	Reasons []string `json:"reasons,omitempty"`
+
+	// TailLines is the number of lines from the end of the log
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TailLines int64 `json:"tailLines,omitempty"`
+
+	// LimitBytes caps the size of the attached logs, the oldest lines are cut first
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65536
+	// +optional
+	LimitBytes int64 `json:"limitBytes,omitempty"`
+}
+
+// GetTailLines returns the number of lines, with the default applied
+func (l *LogsPolicy) GetTailLines() int64 {
+	if l == nil || l.TailLines == 0 {
+		return DefaultLogTailLines
+	}
+	return l.TailLines
+}
+
+// GetLimitBytes returns the size limit, with the default applied
+func (l *LogsPolicy) GetLimitBytes() int64 {
+	if l == nil || l.LimitBytes == 0 {
+		return DefaultLogLimitBytes
+	}
+	return l.LimitBytes
+}
+
+// AppliesTo tells whether logs are attached to notifications about Events with the reason
+func (l *LogsPolicy) AppliesTo(reason string) bool {
+	if l == nil {
+		return false
+	}
+	reasons := l.Reasons
+	if len(reasons) == 0 {
+		reasons = DefaultLogReasons
+	}
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// DefaultResolveWindow is how long the warnings about an object have to stop for its incident to be resolved
+	DefaultResolveWindow = 10 * time.Minute
+	// DefaultResolveReadyFor is how long a Pod has to stay Ready for its incident to be resolved
+	DefaultResolveReadyFor = time.Minute
+)
+
+// Resolutions of an incident
+const (
+	// ResolvedReady incidents are about a Pod which became Ready again
+	ResolvedReady = "Ready"
+	// ResolvedDeleted incidents are about a Pod which is gone
+	ResolvedDeleted = "Deleted"
+	// ResolvedQuiet incidents had no matching warning within the window
+	ResolvedQuiet = "Quiet"
+)
+
+// ResolvePolicy decides when an incident is over: when the Pod is Ready again, or no warning recurred within the window
+type ResolvePolicy struct {
+	// Window is how long the matching warnings have to stop. Defaults to 10m.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// ReadyFor is how long a Pod has to stay Ready after the last warning, so a crash loop doesn't flap.
+	// Defaults to 1m.
+	// +optional
+	ReadyFor *metav1.Duration `json:"readyFor,omitempty"`
+}
+
+// GetWindow returns the quiet window, with the default applied
+func (p *ResolvePolicy) GetWindow() time.Duration {
+	if p == nil || p.Window == nil {
+		return DefaultResolveWindow
+	}
+	return p.Window.Duration
+}
+
+// GetReadyFor returns how long a Pod has to stay Ready, with the default applied
+func (p *ResolvePolicy) GetReadyFor() time.Duration {
+	if p == nil || p.ReadyFor == nil {
+		return DefaultResolveReadyFor
+	}
+	return p.ReadyFor.Duration
+}
+
+// DefaultGroupingWindow is how long an Incident waits for related Events without a window in the grouping policy
+const DefaultGroupingWindow = 5 * time.Minute
+
+// GroupingPolicy groups Events by the workload owning the involved object, or else by its node.
+// The first Event of an Incident is notified, the others within the window are suppressed.
+type GroupingPolicy struct {
+	// Window is how long an Incident stays open after its last Event. Defaults to 5m.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// GetWindow returns the grouping window, with the default applied
+func (p *GroupingPolicy) GetWindow() time.Duration {
+	if p == nil || p.Window == nil {
+		return DefaultGroupingWindow
+	}
+	return p.Window.Duration
+}
+
+// Weekday is the abbreviated name of a day of the week
+// +kubebuilder:validation:Enum=Mon;Tue;Wed;Thu;Fri;Sat;Sun
+type Weekday string
+
+// weekdays are indexed by time.Weekday
+var weekdays = []Weekday{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// QuietHours is a weekly schedule of windows without notifications
+type QuietHours struct {
+	// TimeZone is the IANA name of the time zone of the windows, like Europe/Prague. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are the quiet periods
+	Windows []QuietWindow `json:"windows"`
+}
+
+// QuietWindow is a daily period, a window ending before it starts lasts over midnight
+type QuietWindow struct {
+	// Days the window starts on, defaults to every day
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// Start is the local time the window starts, like 22:00
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End is the local time the window ends, like 06:30. Equal to start, the window lasts the whole day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+}
+
+// Validate reports an unknown time zone, or the first malformed window
+func (r *QuietHours) Validate() error {
+	if _, err := time.LoadLocation(r.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone %q: %v", r.TimeZone, err)
+	}
+	for i, window := range r.Windows {
+		if err := window.validate(); err != nil {
+			return fmt.Errorf("windows[%d].%v", i, err)
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether the time falls into one of the windows
+func (r *QuietHours) IsActive(now time.Time) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+	location, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return false, fmt.Errorf("invalid time zone %q: %v", r.TimeZone, err)
+	}
+	now = now.In(location)
+	for _, window := range r.Windows {
+		active, err := window.isActive(now)
+		if err != nil || active {
+			return active, err
+		}
+	}
+	return false, nil
+}
+
+func (w QuietWindow) validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %v", err)
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %v", err)
+	}
+	for _, day := range w.Days {
+		if weekdayIndex(day) < 0 {
+			return fmt.Errorf("days: unknown day %q", day)
+		}
+	}
+	return nil
+}
+
+// isActive checks the local time against the window starting today, and the one started yesterday
+func (w QuietWindow) isActive(now time.Time) (bool, error) {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+	minute := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+	if start < end {
+		return w.startsOn(today) && start <= minute && minute < end, nil
+	}
+	return (w.startsOn(today) && minute >= start) || (w.startsOn(yesterday) && minute < end), nil
+}
+
+func (w QuietWindow) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdayIndex(d) == int(day) {
+			return true
+		}
+	}
+	return false
+}
+
+func weekdayIndex(day Weekday) int {
+	for i, d := range weekdays {
+		if d == day {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseClock returns the minutes since midnight of a HH:MM time
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil || len(clock) != len("15:04") {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// DedupEntry remembers when a notification with the key was last sent
+type DedupEntry struct {
+	Key      string      `json:"key"`
+	LastSent metav1.Time `json:"lastSent"`
+}
+
+// TokenBucket is the state of the rate limit
+type TokenBucket struct {
+	// Tokens left in the bucket
+	Tokens int32 `json:"tokens"`
+	// LastRefill is when the last token was added
+	LastRefill metav1.Time `json:"lastRefill"`
+}
+
+// OpenIncident is an object the Notifier notified about, which didn't recover yet
+type OpenIncident struct {
+	// InvolvedObject is the object the warnings are about
+	InvolvedObject corev1.ObjectReference `json:"involvedObject"`
+
+	// Record is the NotificationRecord of the first notification
+	Record string `json:"record"`
+
+	// Reason of the first notified Event
+	Reason string `json:"reason"`
+
+	// OpenedTime is when the first notification was sent
+	OpenedTime metav1.Time `json:"openedTime"`
+
+	// LastSeenTime is the last occurrence of a matching warning
+	LastSeenTime metav1.Time `json:"lastSeenTime"`
+
+	// Resolution is Ready, Deleted or Quiet once the incident is resolved, until the resolved notification is delivered
+	// +optional
+	Resolution string `json:"resolution,omitempty"`
+
+	// ResolvedTime is when the incident was resolved
+	// +optional
+	ResolvedTime *metav1.Time `json:"resolvedTime,omitempty"`
+
+	// NextAttemptTime is when a failed resolved notification is retried
+	// +optional
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+
+	// FailedAttempts is the number of attempts to send the resolved notification failed in a row.
+	// The incident is dropped once it failed on every attempt allowed by the retry policy.
+	// +optional
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+
+	// DeliveredTo lists the channels which received the resolved notification already, they are skipped on retries
+	// +optional
+	DeliveredTo []string `json:"deliveredTo,omitempty"`
+
+	// Alerts are the other notified records of the incident, whose alerts are resolved along with it
+	// +optional
+	Alerts []ResolvedAlert `json:"alerts,omitempty"`
+}
+
+// ResolvedAlert is a record whose alerts are resolved with the incident
+type ResolvedAlert struct {
+	// Record is the NotificationRecord the alerts were triggered for
+	Record string `json:"record"`
+
+	// DeliveredTo lists the channels which resolved the alert already
+	// +optional
+	DeliveredTo []string `json:"deliveredTo,omitempty"`
 }
 
 // NotifierStatus defines the observed state of Notifier
 type NotifierStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the generation the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions are Ready, DeliveryDegraded, InvalidFilter and InvalidTemplate
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// LastNotificationTime is when a notification was last delivered
+	// +optional
+	LastNotificationTime *metav1.Time `json:"lastNotificationTime,omitempty"`
+
+	// LastError describes why the last notification attempt failed
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// DeliveredCount is the number of notifications delivered to a channel
+	// +optional
+	DeliveredCount int64 `json:"deliveredCount,omitempty"`
+
+	// FailedCount is the number of failed delivery attempts
+	// +optional
+	FailedCount int64 `json:"failedCount,omitempty"`
+
+	// SuppressedCount is the number of notifications dropped as duplicates or over the rate limit
+	// +optional
+	SuppressedCount int64 `json:"suppressedCount,omitempty"`
+
+	// GroupedCount is the number of notifications folded into the Incident of an earlier one
+	// +optional
+	GroupedCount int64 `json:"groupedCount,omitempty"`
+
+	// DeadLetteredCount is the number of notifications given up after the last retry
+	// +optional
+	DeadLetteredCount int64 `json:"deadLetteredCount,omitempty"`
+
+	// Dedup lists the notifications sent within the dedup window
+	// +optional
+	Dedup []DedupEntry `json:"dedup,omitempty"`
+
+	// RateLimit is the token bucket of the rate limit
+	// +optional
+	RateLimit *TokenBucket `json:"rateLimit,omitempty"`
+
+	// OpenIncidents lists the objects notified about, until the resolved notification is delivered
+	// +optional
+	OpenIncidents []OpenIncident `json:"openIncidents,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type==\"DeliveryDegraded\")].status"
+// +kubebuilder:printcolumn:name="Delivered",type="integer",JSONPath=".status.deliveredCount"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failedCount"
+// +kubebuilder:printcolumn:name="Suppressed",type="integer",JSONPath=".status.suppressedCount",priority=1
+// +kubebuilder:printcolumn:name="Dead Lettered",type="integer",JSONPath=".status.deadLetteredCount",priority=1
+// +kubebuilder:printcolumn:name="Last Notification",type="date",JSONPath=".status.lastNotificationTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Notifier is the Schema for the notifiers API
 type Notifier struct {
@@ -64,16 +621,122 @@ func init() {
 	SchemeBuilder.Register(&Notifier{}, &NotifierList{})
 }
 
+// IsClusterNotifier reports whether the Notifier stands for a ClusterNotifier, see ClusterNotifier.AsNotifier
+func (r Notifier) IsClusterNotifier() bool {
+	return r.Kind == ClusterNotifierKind
+}
+
 func (r Notifier) GetEmail() string {
 	return r.Spec.Email
 }
 
+// GetChannels returns all channels, including the one defined by the email shorthand
+func (r Notifier) GetChannels() []Channel {
+	channels := []Channel{}
+	if r.Spec.Email != "" {
+		channels = append(channels, Channel{Type: EmailChannel, Email: r.Spec.Email})
+	}
+	return append(channels, r.Spec.Channels...)
+}
+
 func (r Notifier) GetFilters() []string {
 	return r.Spec.Filters
 }
 
-func (r Notifier) GetNotifyLabel() string {
-	return fmt.Sprintf(NotifyPrefix, r.GetName())
+// GetKinds returns the watched kinds, with the default applied
+func (r Notifier) GetKinds() []string {
+	if len(r.Spec.Kinds) == 0 {
+		return []string{DefaultKind}
+	}
+	return r.Spec.Kinds
+}
+
+// WatchesKind reports whether Events about objects of the kind are considered
+func (r Notifier) WatchesKind(kind string) bool {
+	for _, watched := range r.GetKinds() {
+		if watched == kind || watched == AnyKind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Notifier) GetSMTPSecretName() string {
+	if r.Spec.SMTPSecretRef == nil {
+		return ""
+	}
+	return r.Spec.SMTPSecretRef.Name
+}
+
+// GetEscalationPolicyName is the name of the referenced EscalationPolicy, empty when the Notifier doesn't escalate
+func (r Notifier) GetEscalationPolicyName() string {
+	if r.Spec.EscalationPolicyRef == nil {
+		return ""
+	}
+	return r.Spec.EscalationPolicyRef.Name
+}
+
+// ValidateFilters reports the first filter which is not a valid regular expression, or an invalid match
+func (r Notifier) ValidateFilters() error {
+	_, err := r.Compile()
+	return err
+}
+
+// CompiledNotifier holds the watched kinds, filters and match block of a Notifier, compiled once
+// +kubebuilder:object:generate=false
+type CompiledNotifier struct {
+	kinds      map[string]bool
+	anyKind    bool
+	filters    []*regexp.Regexp
+	match      *CompiledFilter
+	usesLabels bool
+}
+
+// Compile compiles the filters and the match block, reporting the first invalid one
+func (r Notifier) Compile() (*CompiledNotifier, error) {
+	compiled := &CompiledNotifier{kinds: map[string]bool{}, usesLabels: r.UsesLabels()}
+	for _, kind := range r.GetKinds() {
+		compiled.kinds[kind] = true
+		compiled.anyKind = compiled.anyKind || kind == AnyKind
+	}
+	for _, filter := range r.GetFilters() {
+		expr, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %v", filter, err)
+		}
+		compiled.filters = append(compiled.filters, expr)
+	}
+	if r.Spec.Match != nil {
+		match, err := r.Spec.Match.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid match: %v", err)
+		}
+		compiled.match = match
+	}
+	return compiled, nil
+}
+
+// WatchesKind reports whether Events about objects of the kind are considered
+func (c *CompiledNotifier) WatchesKind(kind string) bool {
+	return c.anyKind || c.kinds[kind]
+}
+
+// Match evaluates the watched kinds, the filters and the match block
+func (c *CompiledNotifier) Match(input FilterInput) bool {
+	if !c.WatchesKind(input.Event.InvolvedObject.Kind) {
+		return false
+	}
+	for _, filter := range c.filters {
+		if !filter.MatchString(input.Event.Reason) {
+			return false
+		}
+	}
+	return c.match == nil || c.match.Match(input)
+}
+
+// UsesLabels reports whether the involved object labels are needed to match the Notifier
+func (c *CompiledNotifier) UsesLabels() bool {
+	return c.usesLabels
 }
 
 func (r Notifier) FilterMatch(input string) (bool, error) {
@@ -90,16 +753,39 @@ func (r Notifier) FilterMatch(input string) (bool, error) {
 	return true, nil
 }
 
-func (r NotifierList) Matching(input string) ([]Notifier, error) {
+// Match evaluates the watched kinds, the filters and the match block
+func (r Notifier) Match(input FilterInput) (bool, error) {
+	compiled, err := r.Compile()
+	if err != nil {
+		return false, err
+	}
+	return compiled.Match(input), nil
+}
+
+// UsesLabels reports whether the involved object labels are needed to match the Notifier
+func (r Notifier) UsesLabels() bool {
+	return r.Spec.Match != nil && r.Spec.Match.UsesLabels()
+}
+
+// Matching returns the Notifiers matching the input. Notifiers with invalid filters never match,
+// they are reported in their status and must not hold back the others.
+func (r NotifierList) Matching(input FilterInput) []Notifier {
 	matchedNotifiers := []Notifier{}
 	for _, notifier := range r.Items {
-		match, err := notifier.FilterMatch(input)
-		if err != nil {
-			return nil, err
-		}
-		if match {
+		match, err := notifier.Match(input)
+		if err == nil && match {
 			matchedNotifiers = append(matchedNotifiers, notifier)
 		}
 	}
-	return matchedNotifiers, nil
+	return matchedNotifiers
+}
+
+// UsesLabels reports whether any Notifier needs the involved object labels
+func (r NotifierList) UsesLabels() bool {
+	for _, notifier := range r.Items {
+		if notifier.UsesLabels() {
+			return true
+		}
+	}
+	return false
 }
@@ -20,6 +20,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -79,4 +80,24 @@ var _ = Describe("Notifier", func() {
 
 	})
 
+	Context("kinds", func() {
+		It("should watch Pods by default", func() {
+			notifier := Notifier{}
+			Expect(notifier.GetKinds()).To(Equal([]string{DefaultKind}))
+			Expect(notifier.WatchesKind("Pod")).To(BeTrue())
+			Expect(notifier.WatchesKind("Node")).To(BeFalse())
+		})
+
+		It("should only match Events about the listed kinds", func() {
+			notifier := Notifier{Spec: NotifierSpec{Kinds: []string{"Node", "Job"}}}
+			input := FilterInput{Event: &corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "Job"}}}
+			Expect(notifier.Match(input)).To(BeTrue())
+
+			input.Event.InvolvedObject.Kind = "Pod"
+			Expect(notifier.Match(input)).To(BeFalse())
+
+			notifier.Spec.Kinds = []string{AnyKind}
+			Expect(notifier.Match(input)).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,346 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var notifierlog = logf.Log.WithName("notifier-resource")
+
+// +kubebuilder:webhook:path=/mutate-email-notify-io-v1-notifier,mutating=true,failurePolicy=fail,groups=email.notify.io,resources=notifiers,verbs=create;update,versions=v1,name=mnotifier.kb.io
+
+var _ webhook.Defaulter = &Notifier{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Notifier) Default() {
+	notifierlog.Info("default", "name", r.Name)
+	r.Spec.Default()
+}
+
+// Default spells out the watched kinds and normalizes the addresses and channel types
+func (r *NotifierSpec) Default() {
+	if len(r.Kinds) == 0 {
+		r.Kinds = []string{DefaultKind}
+	}
+	r.Email = strings.TrimSpace(r.Email)
+	defaultChannels(r.Channels)
+}
+
+// defaultChannels normalizes the channel types and addresses
+func defaultChannels(channels []Channel) {
+	for i := range channels {
+		channel := &channels[i]
+		channel.Type = ChannelType(strings.ToLower(strings.TrimSpace(string(channel.Type))))
+		channel.Email = strings.TrimSpace(channel.Email)
+		channel.URL = strings.TrimSpace(channel.URL)
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-email-notify-io-v1-notifier,mutating=false,failurePolicy=fail,groups=email.notify.io,resources=notifiers,verbs=create;update,versions=v1,name=vnotifier.kb.io
+
+var _ webhook.Validator = &Notifier{}
+
+// ValidateCreate rejects Notifiers the controller can't work with
+func (r *Notifier) ValidateCreate() error {
+	notifierlog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate rejects updates the controller can't work with
+func (r *Notifier) ValidateUpdate(old runtime.Object) error {
+	notifierlog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+func (r *Notifier) validate() error {
+	allErrs := r.validateSpec()
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Notifier").GroupKind(), r.Name, allErrs)
+}
+
+func (r *Notifier) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	spec := field.NewPath("spec")
+
+	for i, filter := range r.Spec.Filters {
+		if _, err := regexp.Compile(filter); err != nil {
+			allErrs = append(allErrs, field.Invalid(spec.Child("filters").Index(i), filter, err.Error()))
+		}
+	}
+	if r.Spec.Match != nil {
+		if err := r.Spec.Match.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(spec.Child("match"), "", err.Error()))
+		}
+	}
+	if r.Spec.Email != "" {
+		if err := validateAddress(r.Spec.Email); err != nil {
+			allErrs = append(allErrs, field.Invalid(spec.Child("email"), r.Spec.Email, err.Error()))
+		}
+	}
+	for i, channel := range r.Spec.Channels {
+		allErrs = append(allErrs, validateChannel(channel, spec.Child("channels").Index(i))...)
+	}
+	if r.Spec.QuietHours != nil {
+		if err := r.Spec.QuietHours.Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(spec.Child("quietHours"), "", err.Error()))
+		}
+	}
+	allErrs = append(allErrs, validateDigest(r.Spec.Digest, spec.Child("digest"))...)
+	allErrs = append(allErrs, validateDedup(r.Spec.Dedup, spec.Child("dedup"))...)
+	allErrs = append(allErrs, validateRateLimit(r.Spec.RateLimit, spec.Child("rateLimit"))...)
+	allErrs = append(allErrs, validateRetry(r.Spec.Retry, spec.Child("retry"))...)
+	allErrs = append(allErrs, validateLogs(r.Spec.Logs, spec.Child("logs"))...)
+	allErrs = append(allErrs, validateResolve(r.Spec.Resolve, spec.Child("resolve"))...)
+	if r.Spec.Grouping.GetWindow() <= 0 {
+		allErrs = append(allErrs, field.Invalid(spec.Child("grouping", "window"), r.Spec.Grouping.GetWindow().String(), "must be positive"))
+	}
+	allErrs = append(allErrs, validateTemplate(r.Spec.Template, spec.Child("template"))...)
+	return allErrs
+}
+
+// channelTypes are the supported ChannelTypes
+var channelTypes = []string{
+	string(EmailChannel),
+	string(WebhookChannel),
+	string(SlackChannel),
+	string(TeamsChannel),
+	string(PagerDutyChannel),
+	string(OpsgenieChannel),
+}
+
+func validateChannel(channel Channel, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch channel.Type {
+	case EmailChannel:
+		if channel.Email == "" {
+			allErrs = append(allErrs, field.Required(path.Child("email"), "email channels need a recipient"))
+		} else if err := validateAddress(channel.Email); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("email"), channel.Email, err.Error()))
+		}
+		if channel.Webhook != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("webhook"), "email channels don't post requests"))
+		}
+	case WebhookChannel, SlackChannel, TeamsChannel, PagerDutyChannel, OpsgenieChannel:
+		switch {
+		case channel.URLSecretRef != nil:
+			if channel.URLSecretRef.Name == "" {
+				allErrs = append(allErrs, field.Required(path.Child("urlSecretRef", "name"), ""))
+			}
+			if channel.URLSecretRef.Key == "" {
+				allErrs = append(allErrs, field.Required(path.Child("urlSecretRef", "key"), ""))
+			}
+		case channel.URL == "" && channel.Type.DefaultURL() != "":
+			// Posts to the default endpoint
+		case channel.URL == "":
+			allErrs = append(allErrs, field.Required(path.Child("url"), "url or urlSecretRef is required"))
+		default:
+			if err := validateURL(channel.URL); err != nil {
+				// Don't echo the URL, it may carry a token
+				allErrs = append(allErrs, field.Invalid(path.Child("url"), "", err.Error()))
+			}
+		}
+		allErrs = append(allErrs, validateWebhookOptions(channel.Webhook, path.Child("webhook"))...)
+		if channel.Type == PagerDutyChannel || channel.Type == OpsgenieChannel {
+			allErrs = append(allErrs, validateRoutingKey(channel.RoutingKeySecretRef, path.Child("routingKeySecretRef"))...)
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), channel.Type, channelTypes))
+	}
+	return allErrs
+}
+
+func validateRoutingKey(ref *corev1.SecretKeySelector, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref == nil {
+		return append(allErrs, field.Required(path, "the key is read from a Secret"))
+	}
+	if ref.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+	}
+	if ref.Key == "" {
+		allErrs = append(allErrs, field.Required(path.Child("key"), ""))
+	}
+	return allErrs
+}
+
+func validateWebhookOptions(options *WebhookOptions, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if options == nil {
+		return allErrs
+	}
+	if options.HeadersSecretRef != nil && options.HeadersSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("headersSecretRef", "name"), ""))
+	}
+	if options.SigningSecretRef != nil {
+		if options.SigningSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("signingSecretRef", "name"), ""))
+		}
+		if options.SigningSecretRef.Key == "" {
+			allErrs = append(allErrs, field.Required(path.Child("signingSecretRef", "key"), ""))
+		}
+	}
+	if options.TLSSecretRef != nil && options.TLSSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("tlsSecretRef", "name"), ""))
+	}
+	if options.Timeout != nil && options.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeout"), options.Timeout.Duration.String(), "must be positive"))
+	}
+	return allErrs
+}
+
+// validateAddress accepts a bare email address, as passed to the SMTP server
+func validateAddress(address string) error {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	if parsed.Name != "" || parsed.Address != address {
+		return errors.New("expected a bare address like team@example.com")
+	}
+	return nil
+}
+
+func validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("malformed url")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return errors.New("url must use http or https")
+	}
+	if parsed.Host == "" {
+		return errors.New("url has no host")
+	}
+	return nil
+}
+
+func validateRetry(retry *RetryPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if retry == nil {
+		return allErrs
+	}
+	if retry.MaxAttempts < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxAttempts"), retry.MaxAttempts, "must be positive"))
+	}
+	if retry.Backoff != nil && retry.Backoff.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("backoff"), retry.Backoff.Duration.String(), "must be positive"))
+	}
+	if retry.GetMaxBackoff() < retry.GetBackoff() {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxBackoff"), retry.GetMaxBackoff().String(), "must not be shorter than the backoff"))
+	}
+	return allErrs
+}
+
+func validateLogs(logs *LogsPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if logs == nil {
+		return allErrs
+	}
+	if logs.TailLines < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("tailLines"), logs.TailLines, "must be positive"))
+	}
+	if logs.LimitBytes < 0 || logs.LimitBytes > MaxLogLimitBytes {
+		allErrs = append(allErrs, field.Invalid(path.Child("limitBytes"), logs.LimitBytes,
+			fmt.Sprintf("must be between 1 and %d", MaxLogLimitBytes)))
+	}
+	return allErrs
+}
+
+func validateResolve(resolve *ResolvePolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if resolve == nil {
+		return allErrs
+	}
+	if resolve.GetWindow() <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("window"), resolve.GetWindow().String(), "must be positive"))
+	}
+	if resolve.GetReadyFor() < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("readyFor"), resolve.GetReadyFor().String(), "must not be negative"))
+	}
+	return allErrs
+}
+
+func validateTemplate(t *MessageTemplate, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if t == nil {
+		return allErrs
+	}
+	if t.ConfigMapRef != nil && t.ConfigMapRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("configMapRef", "name"), ""))
+	}
+	if _, err := ParseTemplate(TemplateSubjectKey, t.Subject, false); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("subject"), t.Subject, err.Error()))
+	}
+	if _, err := ParseTemplate(TemplateBodyKey, t.Body, t.HTML); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("body"), t.Body, err.Error()))
+	}
+	return allErrs
+}
+
+func validateDigest(digest *DigestPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if digest == nil {
+		return allErrs
+	}
+	if digest.Window.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("window"), digest.Window.Duration.String(), "must be positive"))
+	}
+	if digest.MaxItems < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxItems"), digest.MaxItems, "must be positive"))
+	}
+	return allErrs
+}
+
+func validateDedup(dedup *DedupPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if dedup == nil {
+		return allErrs
+	}
+	if dedup.Window.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("window"), dedup.Window.Duration.String(), "must be positive"))
+	}
+	return allErrs
+}
+
+func validateRateLimit(rateLimit *RateLimitPolicy, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if rateLimit == nil {
+		return allErrs
+	}
+	if rateLimit.Burst < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("burst"), rateLimit.Burst, "must be at least 1"))
+	}
+	if rateLimit.Every.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("every"), rateLimit.Every.Duration.String(), "must be positive"))
+	}
+	return allErrs
+}
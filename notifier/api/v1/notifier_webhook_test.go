@@ -0,0 +1,292 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Notifier webhook", func() {
+	var notifier *Notifier
+
+	BeforeEach(func() {
+		notifier = &Notifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"},
+			Spec: NotifierSpec{
+				Email:   "team@example.com",
+				Filters: []string{"BackOff"},
+			},
+		}
+	})
+
+	causes := func(err error) []string {
+		status, ok := err.(apierrors.APIStatus)
+		Expect(ok).To(BeTrue())
+		fields := []string{}
+		for _, cause := range status.Status().Details.Causes {
+			fields = append(fields, cause.Field)
+		}
+		return fields
+	}
+
+	It("should accept a valid Notifier", func() {
+		notifier.Spec.Template = &MessageTemplate{
+			Subject: "[{{ .ClusterName }}] {{ .Event.Reason }}",
+			Body:    "<p>{{ .Event.Message }}</p>",
+			HTML:    true,
+		}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.ValidateUpdate(notifier.DeepCopy())).To(Succeed())
+	})
+
+	It("should reject invalid filters", func() {
+		notifier.Spec.Filters = []string{"BackOff", "(unclosed"}
+		notifier.Spec.Match = &EventFilter{All: []EventMatcher{{Reason: &StringMatch{}}}}
+
+		err := notifier.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(causes(err)).To(Equal([]string{"spec.filters[1]", "spec.match"}))
+	})
+
+	It("should reject retry policies which never back off", func() {
+		notifier.Spec.Retry = &RetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: time.Minute}}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+
+		notifier.Spec.Retry = &RetryPolicy{
+			MaxAttempts: -1,
+			Backoff:     &metav1.Duration{},
+		}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.retry.maxAttempts", "spec.retry.backoff"}))
+
+		notifier.Spec.Retry = &RetryPolicy{Backoff: &metav1.Duration{Duration: time.Hour}}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.retry.maxBackoff"}))
+	})
+
+	It("should reject throttling windows which never open", func() {
+		notifier.Spec.Digest = &DigestPolicy{Window: metav1.Duration{Duration: time.Minute}, MaxItems: 10}
+		notifier.Spec.Dedup = &DedupPolicy{Window: metav1.Duration{Duration: time.Hour}}
+		notifier.Spec.RateLimit = &RateLimitPolicy{Burst: 5}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.Spec.RateLimit.GetEvery()).To(Equal(DefaultRateLimitEvery))
+
+		notifier.Spec.Digest = &DigestPolicy{MaxItems: -1}
+		notifier.Spec.Dedup = &DedupPolicy{Window: metav1.Duration{Duration: -time.Minute}}
+		notifier.Spec.RateLimit = &RateLimitPolicy{Every: metav1.Duration{Duration: -time.Minute}}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{
+			"spec.digest.window", "spec.digest.maxItems", "spec.dedup.window", "spec.rateLimit.burst", "spec.rateLimit.every",
+		}))
+	})
+
+	It("should keep attached logs within the size limit", func() {
+		notifier.Spec.Logs = &LogsPolicy{}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.Spec.Logs.GetTailLines()).To(BeEquivalentTo(DefaultLogTailLines))
+		Expect(notifier.Spec.Logs.AppliesTo("BackOff")).To(BeTrue())
+		Expect(notifier.Spec.Logs.AppliesTo("FailedMount")).To(BeFalse())
+
+		notifier.Spec.Logs = &LogsPolicy{TailLines: -1, LimitBytes: MaxLogLimitBytes + 1}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.logs.tailLines", "spec.logs.limitBytes"}))
+	})
+
+	It("should reject resolve windows which never end", func() {
+		notifier.Spec.Resolve = &ResolvePolicy{}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.Spec.Resolve.GetWindow()).To(Equal(DefaultResolveWindow))
+
+		notifier.Spec.Resolve = &ResolvePolicy{ReadyFor: &metav1.Duration{}}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.Spec.Resolve.GetReadyFor()).To(BeZero())
+
+		notifier.Spec.Resolve = &ResolvePolicy{Window: &metav1.Duration{}, ReadyFor: &metav1.Duration{Duration: -time.Second}}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.resolve.window", "spec.resolve.readyFor"}))
+	})
+
+	It("should reject grouping windows which never open", func() {
+		notifier.Spec.Grouping = &GroupingPolicy{}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+		Expect(notifier.Spec.Grouping.GetWindow()).To(Equal(DefaultGroupingWindow))
+
+		notifier.Spec.Grouping.Window = &metav1.Duration{Duration: -time.Minute}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.grouping.window"}))
+	})
+
+	It("should reject malformed quiet hours", func() {
+		notifier.Spec.QuietHours = &QuietHours{TimeZone: "Europe/Prague", Windows: []QuietWindow{{Start: "22:00", End: "6:00"}}}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{"spec.quietHours"}))
+
+		notifier.Spec.QuietHours.Windows[0].End = "06:00"
+		Expect(notifier.ValidateCreate()).To(Succeed())
+	})
+
+	It("should reject templates which don't parse", func() {
+		notifier.Spec.Template = &MessageTemplate{
+			Subject:      "{{ .Event.Reason ",
+			Body:         "{{ if .Event }}no end",
+			ConfigMapRef: &corev1.LocalObjectReference{},
+		}
+
+		err := notifier.ValidateUpdate(notifier.DeepCopy())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(causes(err)).To(Equal([]string{
+			"spec.template.configMapRef.name",
+			"spec.template.subject",
+			"spec.template.body",
+		}))
+	})
+
+	It("should default the kinds and normalize channels", func() {
+		notifier.Spec.Email = " team@example.com "
+		notifier.Spec.Channels = []Channel{{Type: " Slack", URL: "https://hooks.slack.com/services/T/B/X "}}
+		notifier.Default()
+
+		Expect(notifier.Spec.Kinds).To(Equal([]string{DefaultKind}))
+		Expect(notifier.Spec.Email).To(Equal("team@example.com"))
+		Expect(notifier.Spec.Channels[0].Type).To(Equal(SlackChannel))
+		Expect(notifier.Spec.Channels[0].URL).To(Equal("https://hooks.slack.com/services/T/B/X"))
+		Expect(notifier.ValidateCreate()).To(Succeed())
+
+		notifier.Spec.Kinds = []string{"Node"}
+		notifier.Default()
+		Expect(notifier.Spec.Kinds).To(Equal([]string{"Node"}))
+	})
+
+	It("should reject malformed email addresses", func() {
+		notifier.Spec.Email = "team at example.com"
+		notifier.Spec.Channels = []Channel{
+			{Type: EmailChannel, Email: "Team <team@example.com>"},
+			{Type: EmailChannel},
+			{Type: EmailChannel, Email: "ops@example.com"},
+		}
+
+		err := notifier.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(causes(err)).To(Equal([]string{"spec.email", "spec.channels[0].email", "spec.channels[1].email"}))
+	})
+
+	It("should reject unknown and incomplete channels", func() {
+		notifier.Spec.Channels = []Channel{
+			{Type: "pager", URL: "https://pager.example.com"},
+			{Type: WebhookChannel},
+			{Type: TeamsChannel, URL: "ftp://example.com/hook?token=secret"},
+			{Type: SlackChannel, URLSecretRef: &corev1.SecretKeySelector{}},
+			{Type: WebhookChannel, URL: "https://hooks.example.com/notify"},
+		}
+
+		err := notifier.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(causes(err)).To(Equal([]string{
+			"spec.channels[0].type",
+			"spec.channels[1].url",
+			"spec.channels[2].url",
+			"spec.channels[3].urlSecretRef.name",
+			"spec.channels[3].urlSecretRef.key",
+		}))
+		Expect(err.Error()).NotTo(ContainSubstring("token=secret"))
+	})
+
+	It("should require the routing key of alerting channels", func() {
+		routingKey := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "pagerduty"}, Key: "routingKey"}
+		notifier.Spec.Channels = []Channel{
+			{Type: PagerDutyChannel, RoutingKeySecretRef: routingKey},
+			{Type: OpsgenieChannel, URL: "https://api.eu.opsgenie.com/v2/alerts", RoutingKeySecretRef: routingKey},
+		}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+
+		notifier.Spec.Channels = []Channel{
+			{Type: PagerDutyChannel},
+			{Type: OpsgenieChannel, URL: "api.opsgenie.com", RoutingKeySecretRef: &corev1.SecretKeySelector{}},
+		}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{
+			"spec.channels[0].routingKeySecretRef",
+			"spec.channels[1].url",
+			"spec.channels[1].routingKeySecretRef.name",
+			"spec.channels[1].routingKeySecretRef.key",
+		}))
+	})
+
+	It("should reject incomplete webhook options", func() {
+		notifier.Spec.Channels = []Channel{
+			{Type: WebhookChannel, URL: "https://tickets.example.com/api", Webhook: &WebhookOptions{
+				HeadersSecretRef: &corev1.LocalObjectReference{Name: "ticketing-headers"},
+				SigningSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ticketing-signing"}, Key: "key"},
+				TLSSecretRef:     &corev1.LocalObjectReference{Name: "ticketing-tls"},
+				Timeout:          &metav1.Duration{Duration: 5 * time.Second},
+			}},
+		}
+		Expect(notifier.ValidateCreate()).To(Succeed())
+
+		notifier.Spec.Channels = []Channel{
+			{Type: WebhookChannel, URL: "https://tickets.example.com/api", Webhook: &WebhookOptions{
+				SigningSecretRef: &corev1.SecretKeySelector{Key: "key"},
+				TLSSecretRef:     &corev1.LocalObjectReference{},
+				Timeout:          &metav1.Duration{},
+			}},
+			{Type: EmailChannel, Email: "team@example.com", Webhook: &WebhookOptions{}},
+		}
+		Expect(causes(notifier.ValidateCreate())).To(Equal([]string{
+			"spec.channels[0].webhook.signingSecretRef.name",
+			"spec.channels[0].webhook.tlsSecretRef.name",
+			"spec.channels[0].webhook.timeout",
+			"spec.channels[1].webhook",
+		}))
+	})
+
+	It("should reject unknown functions", func() {
+		notifier.Spec.Template = &MessageTemplate{Body: "{{ .Event.Reason | shout }}"}
+		Expect(notifier.ValidateCreate()).To(MatchError(ContainSubstring(`function "shout" not defined`)))
+	})
+})
+
+var _ = Describe("Template", func() {
+	render := func(text string, html bool, data interface{}) string {
+		tmpl, err := ParseTemplate("test", text, html)
+		Expect(err).NotTo(HaveOccurred())
+		out := &bytes.Buffer{}
+		Expect(tmpl.Execute(out, data)).To(Succeed())
+		return out.String()
+	}
+
+	It("should escape values in HTML templates", func() {
+		data := map[string]string{"Message": `<script>alert("x")</script>`}
+		Expect(render("<b>{{ .Message }}</b>", true, data)).
+			To(Equal("<b>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</b>"))
+		Expect(render("<b>{{ .Message }}</b>", false, data)).
+			To(Equal(`<b><script>alert("x")</script></b>`))
+	})
+
+	It("should provide the helper functions", func() {
+		at := metav1.NewTime(time.Date(2019, 7, 1, 12, 30, 0, 0, time.UTC))
+		data := map[string]interface{}{"Reason": "BackOff", "Empty": "", "At": at}
+
+		Expect(render(`{{ upper .Reason }} {{ truncate 4 .Reason }} {{ default "none" .Empty }}`, false, data)).
+			To(Equal("BACKOFF Back none"))
+		Expect(render(`{{ formatTime "2006-01-02 15:04" .At }}`, false, data)).To(Equal("2019-07-01 12:30"))
+	})
+
+	It("should fail on missing keys", func() {
+		tmpl, err := ParseTemplate("test", "{{ .Missing }}", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpl.Execute(&bytes.Buffer{}, map[string]string{})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,97 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SilenceSpec defines the desired state of Silence
+type SilenceSpec struct {
+	// Match selects the silenced Events by their fields and the labels of the involved object, like on Notifiers.
+	// Silences only apply to Events in their own namespace, except in the cluster resource namespace,
+	// where they apply to every namespace and can be narrowed with a namespace matcher.
+	Match EventFilter `json:"match"`
+
+	// StartsAt is when the Silence becomes active, defaults to its creation
+	// +optional
+	StartsAt *metav1.Time `json:"startsAt,omitempty"`
+
+	// EndsAt is when the Silence expires
+	EndsAt metav1.Time `json:"endsAt"`
+
+	// CreatedBy is who created the Silence
+	// +optional
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	// Comment explains why the Events are silenced, like the maintenance ticket
+	// +optional
+	Comment string `json:"comment,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Starts",type="date",JSONPath=".spec.startsAt"
+// +kubebuilder:printcolumn:name="Ends",type="date",JSONPath=".spec.endsAt"
+// +kubebuilder:printcolumn:name="Created By",type="string",JSONPath=".spec.createdBy"
+// +kubebuilder:printcolumn:name="Comment",type="string",JSONPath=".spec.comment",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Silence suppresses the notifications about matching Events for a period, like a planned maintenance
+type Silence struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SilenceSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SilenceList contains a list of Silence
+type SilenceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Silence `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Silence{}, &SilenceList{})
+}
+
+// GetStartsAt is when the Silence becomes active, with the default applied
+func (r Silence) GetStartsAt() time.Time {
+	if r.Spec.StartsAt == nil {
+		return r.CreationTimestamp.Time
+	}
+	return r.Spec.StartsAt.Time
+}
+
+// IsActive reports whether the Silence applies at the time
+func (r Silence) IsActive(now time.Time) bool {
+	return !now.Before(r.GetStartsAt()) && now.Before(r.Spec.EndsAt.Time)
+}
+
+// Active returns the Silences which apply at the time
+func (r SilenceList) Active(now time.Time) []Silence {
+	active := []Silence{}
+	for _, silence := range r.Items {
+		if silence.IsActive(now) {
+			active = append(active, silence)
+		}
+	}
+	return active
+}
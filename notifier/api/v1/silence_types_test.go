@@ -0,0 +1,136 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Silence", func() {
+	var (
+		silence *Silence
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2019, 7, 1, 22, 30, 0, 0, time.UTC)
+		silence = &Silence{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "db-upgrade",
+				Namespace:         "apps",
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+			},
+			Spec: SilenceSpec{
+				Match:   EventFilter{All: []EventMatcher{{Reason: &StringMatch{Exact: "BackOff"}}}},
+				EndsAt:  metav1.NewTime(now.Add(time.Hour)),
+				Comment: "Database upgrade",
+			},
+		}
+	})
+
+	It("should be active from its start until it ends", func() {
+		Expect(silence.IsActive(now)).To(BeTrue())
+		Expect(silence.IsActive(now.Add(-2 * time.Hour))).To(BeFalse())
+		Expect(silence.IsActive(now.Add(time.Hour))).To(BeFalse())
+
+		start := metav1.NewTime(now.Add(30 * time.Minute))
+		silence.Spec.StartsAt = &start
+		Expect(silence.IsActive(now)).To(BeFalse())
+		Expect(silence.IsActive(now.Add(45 * time.Minute))).To(BeTrue())
+
+		expired := silence.DeepCopy()
+		expired.Spec.EndsAt = metav1.NewTime(now.Add(-time.Minute))
+		list := SilenceList{Items: []Silence{*silence, *expired}}
+		Expect(list.Active(now.Add(45 * time.Minute))).To(Equal([]Silence{*silence}))
+	})
+
+	It("should reject Silences which can't match or never end", func() {
+		Expect(silence.ValidateCreate()).To(Succeed())
+
+		silence.Spec.Match.All[0].Reason = &StringMatch{Regex: "Back(Off"}
+		silence.Spec.EndsAt = metav1.Time{}
+		err := silence.ValidateUpdate(silence.DeepCopy())
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.match"))
+		Expect(err.Error()).To(ContainSubstring("spec.endsAt: Required value"))
+
+		silence.Spec.Match.All[0].Reason = &StringMatch{Exact: "BackOff"}
+		start := metav1.NewTime(now)
+		silence.Spec.StartsAt = &start
+		silence.Spec.EndsAt = start
+		Expect(silence.ValidateCreate()).To(MatchError(ContainSubstring("must be after startsAt")))
+	})
+})
+
+var _ = Describe("QuietHours", func() {
+	// 2019-07-01 is a Monday
+	at := func(day int, clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		Expect(err).NotTo(HaveOccurred())
+		return time.Date(2019, 7, day, parsed.Hour(), parsed.Minute(), 0, 0, time.UTC)
+	}
+
+	It("should be quiet within the window", func() {
+		quiet := &QuietHours{Windows: []QuietWindow{{Start: "09:00", End: "17:00"}}}
+		Expect(quiet.IsActive(at(1, "09:00"))).To(BeTrue())
+		Expect(quiet.IsActive(at(1, "16:59"))).To(BeTrue())
+		Expect(quiet.IsActive(at(1, "17:00"))).To(BeFalse())
+		Expect(quiet.IsActive(at(1, "08:59"))).To(BeFalse())
+
+		var none *QuietHours
+		Expect(none.IsActive(at(1, "12:00"))).To(BeFalse())
+	})
+
+	It("should keep windows over midnight on the day they started", func() {
+		quiet := &QuietHours{Windows: []QuietWindow{{Days: []Weekday{"Fri"}, Start: "22:00", End: "06:00"}}}
+		Expect(quiet.IsActive(at(5, "23:00"))).To(BeTrue(), "Friday night")
+		Expect(quiet.IsActive(at(6, "05:59"))).To(BeTrue(), "Saturday morning")
+		Expect(quiet.IsActive(at(6, "23:00"))).To(BeFalse(), "Saturday night")
+		Expect(quiet.IsActive(at(5, "05:00"))).To(BeFalse(), "Friday morning")
+	})
+
+	It("should cover whole days", func() {
+		quiet := &QuietHours{Windows: []QuietWindow{{Days: []Weekday{"Sat", "Sun"}, Start: "00:00", End: "00:00"}}}
+		Expect(quiet.IsActive(at(6, "00:00"))).To(BeTrue())
+		Expect(quiet.IsActive(at(7, "23:59"))).To(BeTrue())
+		Expect(quiet.IsActive(at(8, "00:00"))).To(BeFalse())
+	})
+
+	It("should use the time zone", func() {
+		quiet := &QuietHours{TimeZone: "Europe/Prague", Windows: []QuietWindow{{Start: "00:00", End: "06:00"}}}
+		Expect(quiet.Validate()).To(Succeed())
+		// Prague is two hours ahead in summer
+		Expect(quiet.IsActive(at(1, "23:00"))).To(BeTrue())
+		Expect(quiet.IsActive(at(1, "05:00"))).To(BeFalse())
+	})
+
+	It("should report invalid schedules", func() {
+		Expect((&QuietHours{TimeZone: "Mars/Olympus"}).Validate()).To(MatchError(ContainSubstring(`invalid time zone "Mars/Olympus"`)))
+		Expect((&QuietHours{Windows: []QuietWindow{{Start: "25:00", End: "06:00"}}}).Validate()).
+			To(MatchError(`windows[0].start: invalid time "25:00", expected HH:MM`))
+		Expect((&QuietHours{Windows: []QuietWindow{{Days: []Weekday{"Monday"}, Start: "22:00", End: "06:00"}}}).Validate()).
+			To(MatchError(`windows[0].days: unknown day "Monday"`))
+
+		_, err := (&QuietHours{TimeZone: "Mars/Olympus"}).IsActive(at(1, "12:00"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,69 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var silencelog = logf.Log.WithName("silence-resource")
+
+// +kubebuilder:webhook:path=/validate-email-notify-io-v1-silence,mutating=false,failurePolicy=fail,groups=email.notify.io,resources=silences,verbs=create;update,versions=v1,name=vsilence.kb.io
+
+var _ webhook.Validator = &Silence{}
+
+// SetupWebhookWithManager registers the validating webhook, Silences have no controller doing it
+func (r *Silence) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register("/validate-email-notify-io-v1-silence", admission.ValidatingWebhookFor(r))
+	return nil
+}
+
+// ValidateCreate rejects Silences which can't match or never end
+func (r *Silence) ValidateCreate() error {
+	silencelog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate rejects Silences which can't match or never end
+func (r *Silence) ValidateUpdate(old runtime.Object) error {
+	silencelog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+func (r *Silence) validate() error {
+	var allErrs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if err := r.Spec.Match.Validate(); err != nil {
+		allErrs = append(allErrs, field.Invalid(spec.Child("match"), "", err.Error()))
+	}
+	if r.Spec.EndsAt.IsZero() {
+		allErrs = append(allErrs, field.Required(spec.Child("endsAt"), "silences have to end"))
+	} else if r.Spec.StartsAt != nil && !r.Spec.EndsAt.After(r.Spec.StartsAt.Time) {
+		allErrs = append(allErrs, field.Invalid(spec.Child("endsAt"), r.Spec.EndsAt.String(), "must be after startsAt"))
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Silence").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,132 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TemplateSubjectKey is the ConfigMap key holding the subject template
+	TemplateSubjectKey = "subject"
+	// TemplateBodyKey is the ConfigMap key holding the body template
+	TemplateBodyKey = "body"
+)
+
+// MessageTemplate replaces the default notification subject and body with Go templates.
+// Templates are executed with a TemplateData, see the README for the fields.
+type MessageTemplate struct {
+	// Subject is a text/template for the one line summary
+	// +optional
+	Subject string `json:"subject,omitempty"`
+
+	// Body is a text/template for the message, or an html/template when html is set
+	// +optional
+	Body string `json:"body,omitempty"`
+
+	// HTML renders the body with html/template, and sends emails as HTML
+	// +optional
+	HTML bool `json:"html,omitempty"`
+
+	// ConfigMapRef points to a ConfigMap in the Notifier namespace holding the subject and body keys.
+	// Inline templates take precedence over the ConfigMap.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+// GetConfigMapName is the name of the referenced ConfigMap, if any
+func (t *MessageTemplate) GetConfigMapName() string {
+	if t == nil || t.ConfigMapRef == nil {
+		return ""
+	}
+	return t.ConfigMapRef.Name
+}
+
+// Template is a parsed text/template or html/template
+// +kubebuilder:object:generate=false
+type Template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// TemplateFuncs are available in every template, on top of the builtin ones
+var TemplateFuncs = map[string]interface{}{
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"join":       strings.Join,
+	"replace":    strings.Replace,
+	"contains":   strings.Contains,
+	"hasPrefix":  strings.HasPrefix,
+	"truncate":   truncate,
+	"formatTime": formatTime,
+	"default":    defaultString,
+}
+
+// ParseTemplate parses text as a text/template, or as an html/template, which escapes the values it renders
+func ParseTemplate(name, text string, html bool) (Template, error) {
+	if html {
+		tmpl, err := htmltemplate.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return tmpl, nil
+	}
+	tmpl, err := texttemplate.New(name).Funcs(TemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// truncate cuts s to at most n characters
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// formatTime renders a time with a Go reference layout, like "2006-01-02 15:04"
+func formatTime(layout string, t interface{}) string {
+	switch t := t.(type) {
+	case time.Time:
+		return t.Format(layout)
+	case metav1.Time:
+		return t.Format(layout)
+	case *metav1.Time:
+		if t != nil {
+			return t.Format(layout)
+		}
+	}
+	return ""
+}
+
+// defaultString is value, unless it is empty
+func defaultString(fallback, value string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
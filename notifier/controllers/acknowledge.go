@@ -0,0 +1,227 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	emailv1 "std/api/v1"
+)
+
+// AcknowledgePath is where the acknowledgement links point to
+const AcknowledgePath = "/acknowledge"
+
+// DefaultAcknowledgeLinkTTL is how long the links are valid without a TTL of the server
+const DefaultAcknowledgeLinkTTL = 24 * time.Hour
+
+// maxAcknowledgedBy bounds the name recipients type into the acknowledgement form
+const maxAcknowledgedBy = 64
+
+// AcknowledgeServer serves the signed links acknowledging escalated notifications.
+// Opening a link shows a confirmation form, so link previews and mail scanners don't acknowledge by accident.
+// The acknowledgement is recorded as the annotation on the NotificationRecord, like a manual one.
+type AcknowledgeServer struct {
+	Client client.Client
+	Log    logr.Logger
+	// URL is the external address of the server, which the links point to
+	URL string
+	// Key signs the links
+	Key []byte
+	// LinkTTL is how long a link is valid after it was sent, 24h by default
+	LinkTTL time.Duration
+	// BindAddress is the address the server listens on
+	BindAddress string
+}
+
+// Link is the signed acknowledgement link of the record, empty without a server.
+// The link expires after the LinkTTL, so a leaked link can't acknowledge later notifications about the record.
+func (s *AcknowledgeServer) Link(record *emailv1.NotificationRecord) string {
+	if s == nil || s.URL == "" {
+		return ""
+	}
+	ttl := s.LinkTTL
+	if ttl <= 0 {
+		ttl = DefaultAcknowledgeLinkTTL
+	}
+	return s.link(record, time.Now().Add(ttl))
+}
+
+func (s *AcknowledgeServer) link(record *emailv1.NotificationRecord, expires time.Time) string {
+	query := url.Values{}
+	query.Set("namespace", record.GetNamespace())
+	query.Set("name", record.GetName())
+	query.Set("uid", string(record.GetUID()))
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", s.sign(record.GetNamespace(), record.GetName(), string(record.GetUID()), query.Get("expires")))
+	return strings.TrimSuffix(s.URL, "/") + AcknowledgePath + "?" + query.Encode()
+}
+
+func (s *AcknowledgeServer) sign(namespace, name, uid, expires string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(namespace + "/" + name + "/" + uid + "/" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start serves the links until the manager stops
+func (s *AcknowledgeServer) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(AcknowledgePath, s)
+	server := &http.Server{Addr: s.BindAddress, Handler: mux}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	s.Log.Info("Serving acknowledgement links", "address", s.BindAddress)
+
+	select {
+	case <-stop:
+		shutdown, cancel := ctx.WithTimeout(ctx.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdown)
+	case err := <-errs:
+		return err
+	}
+}
+
+var acknowledgePage = template.Must(template.New("acknowledge").Parse(`<!DOCTYPE html>
+<html><head><title>{{ .Title }}</title></head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- with .Record }}
+<p>{{ .Spec.Event.Reason }} on {{ .Spec.Event.InvolvedObject.Kind }} {{ .Spec.Event.InvolvedObject.Namespace }}/{{ .Spec.Event.InvolvedObject.Name }}: {{ .Spec.Event.Message }}</p>
+{{- end }}
+{{- if .Form }}
+<form method="post">
+<label>Your name <input name="by" maxlength="64"></label>
+<button type="submit">Acknowledge</button>
+</form>
+{{- end }}
+</body></html>
+`))
+
+type acknowledgePageData struct {
+	Title  string
+	Record *emailv1.NotificationRecord
+	Form   bool
+}
+
+func (s *AcknowledgeServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	key := types.NamespacedName{Namespace: query.Get("namespace"), Name: query.Get("name")}
+	uid := query.Get("uid")
+	signature := s.sign(key.Namespace, key.Name, uid, query.Get("expires"))
+	if !hmac.Equal([]byte(signature), []byte(query.Get("signature"))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	} else if time.Now().Unix() > expires {
+		s.render(w, http.StatusGone, acknowledgePageData{Title: "The link expired"})
+		return
+	}
+
+	record := &emailv1.NotificationRecord{}
+	err = s.Client.Get(ctx.TODO(), key, record)
+	if k8serror.IsNotFound(err) || (err == nil && string(record.GetUID()) != uid) {
+		s.render(w, http.StatusNotFound, acknowledgePageData{Title: "The notification is gone"})
+		return
+	} else if err != nil {
+		s.Log.Error(err, "Failed to get NotificationRecord", "record", key)
+		http.Error(w, "failed to get the notification", http.StatusInternalServerError)
+		return
+	}
+
+	if by, found := record.GetAnnotations()[emailv1.AcknowledgedByAnnotation]; found {
+		s.render(w, http.StatusOK, acknowledgePageData{Title: "Already acknowledged by " + by, Record: record})
+		return
+	}
+	if req.Method == http.MethodGet {
+		s.render(w, http.StatusOK, acknowledgePageData{Title: "Acknowledge the notification?", Record: record, Form: true})
+		return
+	}
+
+	by := acknowledgedBy(req.PostFormValue("by"))
+	err = s.acknowledge(key, by)
+	if err != nil {
+		s.Log.Error(err, "Failed to acknowledge NotificationRecord", "record", key)
+		http.Error(w, "failed to acknowledge the notification", http.StatusInternalServerError)
+		return
+	}
+	s.Log.Info("Acknowledged", "record", key, "by", by)
+	s.render(w, http.StatusOK, acknowledgePageData{Title: "Acknowledged by " + by, Record: record})
+}
+
+// acknowledge annotates the record, the Notifier stops its escalation
+func (s *AcknowledgeServer) acknowledge(key types.NamespacedName, by string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		record := &emailv1.NotificationRecord{}
+		if err := s.Client.Get(ctx.TODO(), key, record); err != nil {
+			return err
+		}
+		if _, found := record.GetAnnotations()[emailv1.AcknowledgedByAnnotation]; found {
+			return nil
+		}
+		annotations := record.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[emailv1.AcknowledgedByAnnotation] = by
+		record.SetAnnotations(annotations)
+		return s.Client.Update(ctx.TODO(), record)
+	})
+}
+
+// acknowledgedBy cleans up the name typed into the form, anonymous acknowledgements are made by the link
+func acknowledgedBy(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > maxAcknowledgedBy {
+		name = string(runes[:maxAcknowledgedBy])
+	}
+	if name == "" {
+		return "link"
+	}
+	return name
+}
+
+func (s *AcknowledgeServer) render(w http.ResponseWriter, status int, data acknowledgePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := acknowledgePage.Execute(w, data); err != nil {
+		s.Log.Error(err, "Failed to render the acknowledgement page")
+	}
+}
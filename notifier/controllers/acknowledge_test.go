@@ -0,0 +1,157 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("AcknowledgeServer", func() {
+	var (
+		server *AcknowledgeServer
+		record *emailv1.NotificationRecord
+		c      client.Client
+	)
+
+	BeforeEach(func() {
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		record = &emailv1.NotificationRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-3f2a9c1b7e", Namespace: "apps", UID: "1234"},
+			Spec: emailv1.NotificationRecordSpec{
+				Notifier: "team",
+				Event:    emailv1.EventSnapshot{Reason: "BackOff", Message: "<b>restarting</b>"},
+			},
+		}
+		c = fake.NewFakeClientWithScheme(s, record)
+		server = &AcknowledgeServer{
+			Client: c,
+			Log:    ctrl.Log.WithName("acknowledge"),
+			URL:    "https://notifier.example.com/",
+			Key:    []byte("secret"),
+		}
+	})
+
+	request := func(method, link string, form url.Values) *httptest.ResponseRecorder {
+		var body *strings.Reader
+		if form == nil {
+			body = strings.NewReader("")
+		} else {
+			body = strings.NewReader(form.Encode())
+		}
+		req := httptest.NewRequest(method, link, body)
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		resp := httptest.NewRecorder()
+		server.ServeHTTP(resp, req)
+		return resp
+	}
+
+	acknowledgedBy := func() string {
+		fetched := &emailv1.NotificationRecord{}
+		Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: "apps", Name: "team-3f2a9c1b7e"}, fetched)).To(Succeed())
+		return fetched.GetAnnotations()[emailv1.AcknowledgedByAnnotation]
+	}
+
+	It("should sign links to the record", func() {
+		link, err := url.Parse(server.Link(record))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(link.Host).To(Equal("notifier.example.com"))
+		Expect(link.Path).To(Equal(AcknowledgePath))
+		Expect(link.Query().Get("name")).To(Equal("team-3f2a9c1b7e"))
+		Expect(link.Query().Get("signature")).To(HaveLen(64))
+
+		var none *AcknowledgeServer
+		Expect(none.Link(record)).To(BeEmpty())
+	})
+
+	It("should ask for confirmation before acknowledging", func() {
+		resp := request(http.MethodGet, server.Link(record), nil)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(ContainSubstring(`<form method="post">`))
+		Expect(resp.Body.String()).To(ContainSubstring("&lt;b&gt;restarting&lt;/b&gt;"))
+		Expect(acknowledgedBy()).To(BeEmpty())
+
+		resp = request(http.MethodPost, server.Link(record), url.Values{"by": {"  Alice   Smith "}})
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(ContainSubstring("Acknowledged by Alice Smith"))
+		Expect(acknowledgedBy()).To(Equal("Alice Smith"))
+
+		resp = request(http.MethodPost, server.Link(record), url.Values{"by": {"Bob"}})
+		Expect(resp.Body.String()).To(ContainSubstring("Already acknowledged by Alice Smith"))
+		Expect(acknowledgedBy()).To(Equal("Alice Smith"))
+	})
+
+	It("should acknowledge anonymously by the link", func() {
+		resp := request(http.MethodPost, server.Link(record), url.Values{})
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(acknowledgedBy()).To(Equal("link"))
+	})
+
+	It("should reject forged links", func() {
+		link := strings.Replace(server.Link(record), "name=team-3f2a9c1b7e", "name=other-3f2a9c1b7e", 1)
+		Expect(request(http.MethodPost, link, url.Values{}).Code).To(Equal(http.StatusForbidden))
+
+		server.Key = []byte("rotated")
+		Expect(request(http.MethodGet, strings.Replace(server.Link(record), "uid=1234", "uid=5678", 1), nil).Code).
+			To(Equal(http.StatusForbidden))
+		Expect(acknowledgedBy()).To(BeEmpty())
+	})
+
+	It("should reject expired links", func() {
+		link, err := url.Parse(server.Link(record))
+		Expect(err).NotTo(HaveOccurred())
+		expires, err := strconv.ParseInt(link.Query().Get("expires"), 10, 64)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Unix(expires, 0)).To(BeTemporally("~", time.Now().Add(DefaultAcknowledgeLinkTTL), time.Minute))
+
+		expired := server.link(record, time.Now().Add(-time.Minute))
+		resp := request(http.MethodPost, expired, url.Values{"by": {"Alice"}})
+		Expect(resp.Code).To(Equal(http.StatusGone))
+		Expect(resp.Body.String()).To(ContainSubstring("The link expired"))
+		Expect(acknowledgedBy()).To(BeEmpty())
+
+		// Extending the link breaks the signature
+		extended := strings.Replace(expired, "expires=", "expires=9", 1)
+		Expect(request(http.MethodPost, extended, url.Values{}).Code).To(Equal(http.StatusForbidden))
+		Expect(acknowledgedBy()).To(BeEmpty())
+	})
+
+	It("should tell when the record is gone", func() {
+		Expect(c.Delete(context.TODO(), record)).To(Succeed())
+		resp := request(http.MethodGet, server.Link(record), nil)
+		Expect(resp.Code).To(Equal(http.StatusNotFound))
+		Expect(resp.Body.String()).To(ContainSubstring("The notification is gone"))
+	})
+})
@@ -0,0 +1,470 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	emailv1 "std/api/v1"
+)
+
+// DefaultWebhookTimeout bounds a single webhook request
+const DefaultWebhookTimeout = 30 * time.Second
+
+// Notification is a message about an Event, or a digest of Events, sent through every channel of the Notifier
+type Notification struct {
+	Notifier *emailv1.Notifier
+	Event    *corev1.Event
+	// Digest replaces the Event, when the Notifier batches notifications
+	Digest []DigestGroup
+	// Context is the owner workload, node and containers of the involved object, nil when unknown
+	Context *emailv1.ObjectContext
+	// Logs are the previous logs of the failing container, when the Notifier attaches them
+	Logs *ContainerLogs
+	// Escalation is set when a tier of the escalation policy is notified
+	Escalation *EscalationNotice
+	// AcknowledgeURL is the signed link stopping the escalation, empty when there is none
+	AcknowledgeURL string
+	// Resolved is set when the notification tells that an incident is over
+	Resolved *ResolvedNotice
+	// Incident is the name of the Incident grouping further Events with this one, empty when not grouped
+	Incident string
+	// Record is the name of the NotificationRecord of the Event, or of the resolved one, empty for digests
+	Record string
+	// Records are the names of the NotificationRecords in a digest
+	Records []string
+	// Message is the output of the Notifier template, replacing the default subject and text
+	Message *RenderedMessage
+}
+
+// AlertKey identifies the alert about the record in PagerDuty or Opsgenie, so it is triggered once
+// and resolved later. Digests are identified by a hash of their records, so a retried digest triggers the same alert.
+func (n *Notification) AlertKey() string {
+	if n.Record != "" {
+		return n.Notifier.GetNamespace() + "/" + n.Record
+	}
+	if len(n.Records) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(strings.Join(n.Records, "\n")))
+	return n.Notifier.GetNamespace() + "/digest-" + hex.EncodeToString(hash[:8])
+}
+
+// IsDigest reports whether the notification aggregates several Events
+func (n *Notification) IsDigest() bool {
+	return n.Event == nil
+}
+
+// Subject is a one line summary of the notification
+func (n *Notification) Subject() string {
+	if n.Message != nil && n.Message.Subject != "" {
+		return n.Message.Subject
+	}
+	prefix := "[" + n.Notifier.GetName() + "]"
+	if n.Escalation != nil {
+		prefix += "[escalated to " + n.Escalation.Tier + "]"
+	}
+	if n.Resolved != nil {
+		prefix += "[resolved]"
+	}
+	if n.IsDigest() {
+		return fmt.Sprintf("%s Digest: %d events in %d groups",
+			prefix,
+			n.digestCount(),
+			len(n.Digest))
+	}
+	return fmt.Sprintf("%s %s: %s",
+		prefix,
+		n.Event.Reason,
+		objectName(n.Event))
+}
+
+// Text is the plain text body of the notification
+func (n *Notification) Text() string {
+	if n.Message != nil && n.Message.Body != "" && !n.Message.HTML {
+		return n.Message.Body
+	}
+	if n.IsDigest() {
+		text := "Events occured!\n"
+		for _, group := range n.Digest {
+			text += "\n" + group.Title() + "\n"
+			for _, message := range group.Messages {
+				text += "  " + message + "\n"
+			}
+		}
+		return text + n.escalationText()
+	}
+	if n.Resolved != nil {
+		return fmt.Sprintf("Resolved!\n\nReason: %s\n%s: %s\nResolution: %s\nOpen for: %s\nFirst notified: %s, record %s\n",
+			n.Event.Reason,
+			n.Event.InvolvedObject.Kind,
+			objectName(n.Event),
+			n.Resolved.Description,
+			n.Resolved.Duration.Duration,
+			n.Resolved.OpenedTime.UTC().Format(time.RFC3339),
+			n.Resolved.Record)
+	}
+
+	text := fmt.Sprintf("Event occured!\n\nReason: %s\nMessage: %s\n%s: %s\n",
+		n.Event.Reason,
+		n.Event.Message,
+		n.Event.InvolvedObject.Kind,
+		objectName(n.Event))
+	if n.Event.Count > 1 {
+		text += fmt.Sprintf("Occurrences: %d\n", n.Event.Count)
+	}
+	if n.Incident != "" {
+		text += "Incident: " + n.Incident + "\n"
+	}
+	return text + contextText(n.Context) + logsText(n.Logs) + n.escalationText()
+}
+
+// escalationText tells the tier why it is notified, and how to stop the escalation
+func (n *Notification) escalationText() string {
+	text := ""
+	if n.Escalation != nil {
+		text += fmt.Sprintf("\nEscalated to %s, unacknowledged for %s\n", n.Escalation.Tier, n.Escalation.Unacknowledged.Duration)
+	}
+	if n.AcknowledgeURL != "" {
+		text += "\nAcknowledge: " + n.AcknowledgeURL + "\n"
+	}
+	return text
+}
+
+// HTML is the HTML body of the notification, if the Notifier template renders one
+func (n *Notification) HTML() string {
+	if n.Message != nil && n.Message.HTML {
+		return n.Message.Body
+	}
+	return ""
+}
+
+func (n *Notification) digestCount() int32 {
+	count := int32(0)
+	for _, group := range n.Digest {
+		count += group.Count
+	}
+	return count
+}
+
+// Channel delivers notifications to a single destination
+type Channel interface {
+	Send(n *Notification) error
+}
+
+// Acknowledger is a Channel tracking alerts, which is told once the notification is acknowledged
+type Acknowledger interface {
+	Acknowledge(n *Notification, by string) error
+}
+
+// channelKey identifies the channel in the record status, webhook URLs may carry a token so only their hash is used
+func channelKey(channel Channel) string {
+	switch c := channel.(type) {
+	case *emailChannel:
+		return string(emailv1.EmailChannel) + ":" + c.to
+	case *webhookChannel:
+		hash := sha256.Sum256([]byte(c.url))
+		return string(c.channelType) + ":" + hex.EncodeToString(hash[:])[:12]
+	case *pagerDutyChannel:
+		hash := sha256.Sum256([]byte(c.url + "#" + c.routingKey))
+		return string(c.channelType) + ":" + hex.EncodeToString(hash[:])[:12]
+	case *opsgenieChannel:
+		hash := sha256.Sum256([]byte(c.url + "#" + c.apiKey))
+		return string(c.channelType) + ":" + hex.EncodeToString(hash[:])[:12]
+	}
+	return fmt.Sprintf("%T", channel)
+}
+
+// emailChannel sends the notification as a plain text email, with an HTML alternative when there is one
+type emailChannel struct {
+	mailer Mailer
+	config SMTPConfig
+	to     string
+}
+
+func (c *emailChannel) Send(n *Notification) error {
+	mail := &Mail{
+		To:      []string{c.to},
+		Subject: n.Subject(),
+		Body:    n.Text(),
+		HTML:    n.HTML(),
+	}
+	return errors.Wrapf(c.mailer.Send(c.config, mail), "Failed to send email to %s", c.to)
+}
+
+// PayloadFormatter renders the JSON document a webhook channel posts
+type PayloadFormatter func(n *Notification) interface{}
+
+// webhookChannel posts the formatted notification as JSON
+type webhookChannel struct {
+	client      *http.Client
+	url         string
+	format      PayloadFormatter
+	channelType emailv1.ChannelType
+	// headers are added to every request
+	headers http.Header
+	// signingKey signs the body into the SignatureHeader, when set
+	signingKey []byte
+}
+
+func newWebhookChannel(client *http.Client, endpoint string, channelType emailv1.ChannelType) (*webhookChannel, error) {
+	formatters := map[emailv1.ChannelType]PayloadFormatter{
+		emailv1.WebhookChannel: WebhookPayload,
+		emailv1.SlackChannel:   SlackPayload,
+		emailv1.TeamsChannel:   TeamsPayload,
+	}
+	format, found := formatters[channelType]
+	if !found {
+		return nil, errors.Errorf("unknown channel type %q", channelType)
+	}
+	if endpoint == "" {
+		return nil, errors.Errorf("%s channel has no url", channelType)
+	}
+	return &webhookChannel{client: client, url: endpoint, format: format, channelType: channelType}, nil
+}
+
+func (c *webhookChannel) Send(n *Notification) error {
+	return c.post(c.url, c.format(n))
+}
+
+// post sends the document as JSON to the endpoint, with the headers and signature of the channel
+func (c *webhookChannel) post(endpoint string, document interface{}) error {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		// Don't leak the URL into the status
+		return errors.New("invalid webhook url")
+	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.signingKey != nil {
+		req.Header.Set(SignatureHeader, signBody(c.signingKey, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if urlErr, ok := err.(*url.Error); ok {
+		// The URL may carry a token, don't leak it into the status
+		err = urlErr.Err
+	}
+	if err != nil {
+		return errors.Wrap(err, "webhook request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("webhook responded with %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// WebhookPayload is the generic JSON document describing the Event, or the digest.
+// Fields are only added within a WebhookPayloadVersion.
+func WebhookPayload(n *Notification) interface{} {
+	payload := map[string]interface{}{
+		"version": WebhookPayloadVersion,
+		"sentAt":  time.Now().UTC().Format(time.RFC3339),
+		"notifier": map[string]string{
+			"name":      n.Notifier.GetName(),
+			"namespace": n.Notifier.GetNamespace(),
+		},
+		"subject": n.Subject(),
+		"text":    n.Text(),
+	}
+	if n.Escalation != nil {
+		payload["escalation"] = n.Escalation
+	}
+	if n.AcknowledgeURL != "" {
+		payload["acknowledgeURL"] = n.AcknowledgeURL
+	}
+	if n.Resolved != nil {
+		payload["resolved"] = n.Resolved
+	}
+	if n.Incident != "" {
+		payload["incident"] = n.Incident
+	}
+	if n.IsDigest() {
+		payload["digest"] = n.Digest
+		return payload
+	}
+
+	payload["event"] = map[string]interface{}{
+		"name":           n.Event.GetName(),
+		"namespace":      n.Event.GetNamespace(),
+		"uid":            n.Event.GetUID(),
+		"type":           n.Event.Type,
+		"reason":         n.Event.Reason,
+		"message":        n.Event.Message,
+		"count":          n.Event.Count,
+		"firstTimestamp": n.Event.FirstTimestamp,
+		"lastTimestamp":  n.Event.LastTimestamp,
+		"source":         n.Event.Source.Component,
+		"involvedObject": map[string]string{
+			"kind":      n.Event.InvolvedObject.Kind,
+			"namespace": n.Event.InvolvedObject.Namespace,
+			"name":      n.Event.InvolvedObject.Name,
+		},
+	}
+	if n.Context != nil {
+		payload["context"] = n.Context
+	}
+	if n.Logs != nil {
+		payload["logs"] = n.Logs
+	}
+	return payload
+}
+
+// SlackPayload is a message for a Slack incoming webhook
+func SlackPayload(n *Notification) interface{} {
+	if n.IsDigest() {
+		attachments := []map[string]interface{}{}
+		for _, group := range n.Digest {
+			attachments = append(attachments, map[string]interface{}{
+				"color":    "danger",
+				"fallback": group.Title(),
+				"title":    group.Title(),
+				"text":     strings.Join(group.Messages, "\n"),
+			})
+		}
+		return map[string]interface{}{
+			"text":        n.Subject(),
+			"attachments": attachments,
+		}
+	}
+
+	fields := []map[string]interface{}{
+		{"title": "Reason", "value": n.Event.Reason, "short": true},
+		{"title": n.Event.InvolvedObject.Kind, "value": objectName(n.Event), "short": true},
+	}
+	color, text := "danger", n.Event.Message
+	if n.Resolved != nil {
+		color, text = "good", "Resolved, "+n.Resolved.Description
+		fields = append(fields, map[string]interface{}{"title": "Open for", "value": n.Resolved.Duration.Duration.String(), "short": true})
+	}
+	for _, fact := range contextFacts(n.Context) {
+		fields = append(fields, map[string]interface{}{"title": fact[0], "value": fact[1], "short": fact[0] != "Containers"})
+	}
+	if n.AcknowledgeURL != "" {
+		fields = append(fields, map[string]interface{}{"title": "Acknowledge", "value": "<" + n.AcknowledgeURL + "|Stop the escalation>", "short": false})
+	}
+	attachments := []map[string]interface{}{{
+		"color":    color,
+		"fallback": n.Text(),
+		"text":     text,
+		"fields":   fields,
+	}}
+	if n.Logs != nil {
+		attachments = append(attachments, map[string]interface{}{
+			"fallback":  "Logs of container " + n.Logs.Container,
+			"title":     "Previous logs of container " + n.Logs.Container,
+			"text":      "```" + n.Logs.Lines + "```",
+			"mrkdwn_in": []string{"text"},
+		})
+	}
+	return map[string]interface{}{
+		"text":        n.Subject(),
+		"attachments": attachments,
+	}
+}
+
+// TeamsPayload is a legacy actionable message card for a Microsoft Teams connector
+func TeamsPayload(n *Notification) interface{} {
+	sections := []map[string]interface{}{}
+	if n.IsDigest() {
+		for _, group := range n.Digest {
+			sections = append(sections, map[string]interface{}{
+				"activityTitle": group.Title(),
+				"text":          strings.Join(group.Messages, "\n\n"),
+			})
+		}
+	} else {
+		facts := []map[string]string{
+			{"name": "Reason", "value": n.Event.Reason},
+			{"name": n.Event.InvolvedObject.Kind, "value": objectName(n.Event)},
+		}
+		for _, fact := range contextFacts(n.Context) {
+			facts = append(facts, map[string]string{"name": fact[0], "value": fact[1]})
+		}
+		text := n.Event.Message
+		if n.Resolved != nil {
+			text = "Resolved, " + n.Resolved.Description
+			facts = append(facts, map[string]string{"name": "Open for", "value": n.Resolved.Duration.Duration.String()})
+		}
+		sections = append(sections, map[string]interface{}{
+			"text":  text,
+			"facts": facts,
+		})
+		if n.Logs != nil {
+			sections = append(sections, map[string]interface{}{
+				"activityTitle": "Previous logs of container " + n.Logs.Container,
+				"text":          "<pre>" + html.EscapeString(n.Logs.Lines) + "</pre>",
+			})
+		}
+	}
+
+	themeColor := "D70000"
+	if n.Resolved != nil {
+		themeColor = "2DC72D"
+	}
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": themeColor,
+		"summary":    n.Subject(),
+		"title":      n.Subject(),
+		"sections":   sections,
+	}
+	if n.AcknowledgeURL != "" {
+		card["potentialAction"] = []map[string]interface{}{{
+			"@type":   "OpenUri",
+			"name":    "Acknowledge",
+			"targets": []map[string]string{{"os": "default", "uri": n.AcknowledgeURL}},
+		}}
+	}
+	return card
+}
+
+// objectName is namespace/name of the involved object, or just the name of cluster scoped objects
+func objectName(event *corev1.Event) string {
+	return referenceName(event.InvolvedObject)
+}
+
+func referenceName(ref corev1.ObjectReference) string {
+	if ref.Namespace == "" {
+		return ref.Name
+	}
+	return ref.Namespace + "/" + ref.Name
+}
@@ -0,0 +1,260 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+// webhookRecorder is a local endpoint remembering every JSON document posted to it
+type webhookRecorder struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	payloads []map[string]interface{}
+	status   int
+}
+
+func newWebhookRecorder() *webhookRecorder {
+	w := &webhookRecorder{status: http.StatusOK}
+	w.Server = httptest.NewServer(http.HandlerFunc(w.handle))
+	return w
+}
+
+func (w *webhookRecorder) handle(resp http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	payload := map[string]interface{}{}
+	json.Unmarshal(body, &payload)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.requests = append(w.requests, req)
+	w.bodies = append(w.bodies, body)
+	w.payloads = append(w.payloads, payload)
+	resp.WriteHeader(w.status)
+	resp.Write([]byte("recorded"))
+}
+
+func (w *webhookRecorder) Payloads() []map[string]interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]map[string]interface{}{}, w.payloads...)
+}
+
+func (w *webhookRecorder) LastRequest() *http.Request {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.requests[len(w.requests)-1]
+}
+
+func (w *webhookRecorder) LastBody() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bodies[len(w.bodies)-1]
+}
+
+func (w *webhookRecorder) RespondWith(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = status
+}
+
+var _ = Describe("Channel", func() {
+	var (
+		recorder     *webhookRecorder
+		notification *Notification
+	)
+
+	BeforeEach(func() {
+		recorder = newWebhookRecorder()
+		notification = &Notification{
+			Notifier: &emailv1.Notifier{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"},
+			},
+			Event: &corev1.Event{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1.15f", Namespace: "apps"},
+				InvolvedObject: corev1.ObjectReference{
+					Kind:      "Pod",
+					Name:      "web-1",
+					Namespace: "apps",
+				},
+				Type:    corev1.EventTypeWarning,
+				Reason:  "BackOff",
+				Message: "Back-off restarting failed container",
+				Count:   3,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		recorder.Close()
+	})
+
+	send := func(channelType emailv1.ChannelType) error {
+		channel, err := newWebhookChannel(recorder.Client(), recorder.URL, channelType)
+		Expect(err).NotTo(HaveOccurred())
+		return channel.Send(notification)
+	}
+
+	It("should post the generic webhook document", func() {
+		Expect(send(emailv1.WebhookChannel)).To(Succeed())
+
+		Expect(recorder.LastRequest().Header.Get("Content-Type")).To(Equal("application/json"))
+		payload := recorder.Payloads()[0]
+		Expect(payload["version"]).To(Equal(WebhookPayloadVersion))
+		Expect(payload["sentAt"]).NotTo(BeEmpty())
+		Expect(payload["subject"]).To(Equal("[team] BackOff: apps/web-1"))
+		Expect(payload["notifier"]).To(Equal(map[string]interface{}{"name": "team", "namespace": "apps"}))
+
+		event := payload["event"].(map[string]interface{})
+		Expect(event["reason"]).To(Equal("BackOff"))
+		Expect(event["message"]).To(Equal("Back-off restarting failed container"))
+		Expect(event["count"]).To(BeEquivalentTo(3))
+		Expect(event["involvedObject"]).To(Equal(map[string]interface{}{
+			"kind": "Pod", "name": "web-1", "namespace": "apps",
+		}))
+	})
+
+	It("should post a Slack message", func() {
+		Expect(send(emailv1.SlackChannel)).To(Succeed())
+
+		payload := recorder.Payloads()[0]
+		Expect(payload["text"]).To(Equal("[team] BackOff: apps/web-1"))
+		attachment := payload["attachments"].([]interface{})[0].(map[string]interface{})
+		Expect(attachment["color"]).To(Equal("danger"))
+		Expect(attachment["text"]).To(Equal("Back-off restarting failed container"))
+		Expect(attachment["fields"]).To(ContainElement(map[string]interface{}{
+			"title": "Pod", "value": "apps/web-1", "short": true,
+		}))
+	})
+
+	It("should post a Teams message card", func() {
+		Expect(send(emailv1.TeamsChannel)).To(Succeed())
+
+		payload := recorder.Payloads()[0]
+		Expect(payload["@type"]).To(Equal("MessageCard"))
+		Expect(payload["summary"]).To(Equal("[team] BackOff: apps/web-1"))
+		section := payload["sections"].([]interface{})[0].(map[string]interface{})
+		Expect(section["facts"]).To(ContainElement(map[string]interface{}{
+			"name": "Reason", "value": "BackOff",
+		}))
+	})
+
+	It("should tell which workload broke", func() {
+		notification.Context = &emailv1.ObjectContext{
+			Owners:     []emailv1.WorkloadReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+			NodeName:   "node-1",
+			Containers: []emailv1.ContainerContext{{Name: "app", State: "Waiting", Reason: "CrashLoopBackOff", RestartCount: 3}},
+		}
+		Expect(notification.Text()).To(ContainSubstring("Workload: Deployment/web\nNode: node-1\n"))
+
+		Expect(send(emailv1.WebhookChannel)).To(Succeed())
+		context := recorder.Payloads()[0]["context"].(map[string]interface{})
+		Expect(context["nodeName"]).To(Equal("node-1"))
+		Expect(context["owners"]).To(Equal([]interface{}{
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+		}))
+
+		Expect(send(emailv1.SlackChannel)).To(Succeed())
+		attachment := recorder.Payloads()[1]["attachments"].([]interface{})[0].(map[string]interface{})
+		Expect(attachment["fields"]).To(ContainElement(map[string]interface{}{
+			"title": "Workload", "value": "Deployment/web", "short": true,
+		}))
+		Expect(attachment["fields"]).To(ContainElement(map[string]interface{}{
+			"title": "Containers", "value": "app: Waiting CrashLoopBackOff, 3 restarts", "short": false,
+		}))
+	})
+
+	It("should tell the tier why it is escalated", func() {
+		notification.Escalation = &EscalationNotice{Tier: "on-call", Level: 1, Unacknowledged: metav1.Duration{Duration: 15 * time.Minute}}
+		notification.AcknowledgeURL = "https://notifier.example.com/acknowledge?name=x"
+		Expect(notification.Subject()).To(Equal("[team][escalated to on-call] BackOff: apps/web-1"))
+		Expect(notification.Text()).To(HaveSuffix("\nEscalated to on-call, unacknowledged for 15m0s\n\nAcknowledge: https://notifier.example.com/acknowledge?name=x\n"))
+
+		Expect(send(emailv1.WebhookChannel)).To(Succeed())
+		payload := recorder.Payloads()[0]
+		Expect(payload["acknowledgeURL"]).To(Equal("https://notifier.example.com/acknowledge?name=x"))
+		Expect(payload["escalation"]).To(HaveKeyWithValue("tier", "on-call"))
+
+		Expect(send(emailv1.TeamsChannel)).To(Succeed())
+		Expect(recorder.Payloads()[1]["potentialAction"]).To(HaveLen(1))
+	})
+
+	It("should fail on error responses", func() {
+		recorder.RespondWith(http.StatusForbidden)
+
+		err := send(emailv1.SlackChannel)
+		Expect(err).To(MatchError("webhook responded with 403 Forbidden: recorded"))
+	})
+
+	It("should not leak the URL on connection errors", func() {
+		channel, err := newWebhookChannel(recorder.Client(), recorder.URL+"/token-abc", emailv1.WebhookChannel)
+		Expect(err).NotTo(HaveOccurred())
+		recorder.Close()
+
+		err = channel.Send(notification)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).NotTo(ContainSubstring("token-abc"))
+	})
+
+	It("should reject incomplete channels", func() {
+		_, err := newWebhookChannel(recorder.Client(), "", emailv1.SlackChannel)
+		Expect(err).To(MatchError("slack channel has no url"))
+
+		_, err = newWebhookChannel(recorder.Client(), recorder.URL, "pager")
+		Expect(err).To(MatchError(`unknown channel type "pager"`))
+	})
+
+	It("should email through the SMTP server", func() {
+		server := newFakeSMTPServer()
+		defer server.Close()
+
+		channel := &emailChannel{mailer: SMTPMailer{}, config: server.Config(), to: "team@example.com"}
+		Expect(channel.Send(notification)).To(Succeed())
+
+		messages := server.MessagesTo("team@example.com")
+		Expect(messages).To(HaveLen(1))
+		Expect(mailHeader(messages[0].Data, "Subject")).To(Equal("[team] BackOff: apps/web-1"))
+	})
+	It("should email the rendered template", func() {
+		server := newFakeSMTPServer()
+		defer server.Close()
+
+		notification.Message = &RenderedMessage{Subject: "web-1 is failing", Body: "<p>web-1</p>", HTML: true}
+		channel := &emailChannel{mailer: SMTPMailer{}, config: server.Config(), to: "team@example.com"}
+		Expect(channel.Send(notification)).To(Succeed())
+
+		messages := server.MessagesTo("team@example.com")
+		Expect(messages).To(HaveLen(1))
+		Expect(mailHeader(messages[0].Data, "Subject")).To(Equal("web-1 is failing"))
+		Expect(messages[0].Data).To(ContainSubstring("<p>web-1</p>"))
+		Expect(messages[0].Data).To(ContainSubstring("Event occured!"))
+	})
+})
@@ -0,0 +1,151 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	emailv1 "std/api/v1"
+)
+
+// ClusterNotifierReconciler reconciles a ClusterNotifier object.
+// A ClusterNotifier delivers like a Notifier living in Namespace, which holds its Secrets, ConfigMaps and NotificationRecords.
+type ClusterNotifierReconciler struct {
+	NotifierReconciler
+	// Namespace holds the Secrets, ConfigMaps and NotificationRecords of ClusterNotifiers
+	Namespace string
+}
+
+// +kubebuilder:rbac:groups=email.notify.io,resources=clusternotifiers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=email.notify.io,resources=clusternotifiers/status,verbs=get;update;patch
+
+func (r *ClusterNotifierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("clusternotifier", req.Name)
+
+	clusterNotifier := &emailv1.ClusterNotifier{}
+	err := r.Get(ctx.TODO(), req.NamespacedName, clusterNotifier)
+	if k8serror.IsNotFound(err) {
+		r.Filters.Forget(recordNotifierKey(emailv1.ClusterNotifierKind, req.Name))
+		forgetMetrics("", req.Name)
+	}
+	if err != nil {
+		log.Error(err, "Can't get cluster notifier")
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	notifier := clusterNotifier.AsNotifier(r.Namespace)
+	result := r.deliver(log, notifier)
+
+	if equality.Semantic.DeepEqual(&notifier.Status, &clusterNotifier.Status) {
+		return result, nil
+	}
+	clusterNotifier.Status = notifier.Status
+	err = r.Status().Update(ctx.TODO(), clusterNotifier)
+	if k8serror.IsConflict(err) {
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to update ClusterNotifier status")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	return result, nil
+}
+
+// SetupWithManager relies on the NotificationRecord and EscalationPolicy indexes set up by the NotifierReconciler
+func (r *ClusterNotifierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(&emailv1.ClusterNotifier{}, secretsField, r.secretsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.ClusterNotifier{}, configMapsField, r.configMapsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.ClusterNotifier{}, escalationPoliciesField, r.escalationPoliciesIndex)
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&emailv1.ClusterNotifier{}).
+		Owns(&emailv1.NotificationRecord{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.clusterNotifiersForSecret)}).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.clusterNotifiersForConfigMap)}).
+		Watches(
+			&source.Kind{Type: &emailv1.EscalationPolicy{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.clusterNotifiersForEscalationPolicy)}).
+		Complete(r)
+}
+
+func (r *ClusterNotifierReconciler) secretsIndex(obj runtime.Object) []string {
+	return r.NotifierReconciler.secretsIndex(obj.(*emailv1.ClusterNotifier).AsNotifier(r.Namespace))
+}
+
+func (r *ClusterNotifierReconciler) configMapsIndex(obj runtime.Object) []string {
+	return configMapsIndex(obj.(*emailv1.ClusterNotifier).AsNotifier(r.Namespace))
+}
+
+func (r *ClusterNotifierReconciler) escalationPoliciesIndex(obj runtime.Object) []string {
+	return escalationPoliciesIndex(obj.(*emailv1.ClusterNotifier).AsNotifier(r.Namespace))
+}
+
+func (r *ClusterNotifierReconciler) clusterNotifiersForSecret(obj handler.MapObject) []reconcile.Request {
+	webhookTransports.forget(obj.Meta.GetNamespace(), obj.Meta.GetName())
+	requests := r.clusterNotifiersReferencing(secretsField, obj)
+	for _, policy := range r.policiesReferencing(obj) {
+		requests = append(requests, r.clusterNotifiersReferencing(escalationPoliciesField, policy)...)
+	}
+	return requests
+}
+
+func (r *ClusterNotifierReconciler) clusterNotifiersForConfigMap(obj handler.MapObject) []reconcile.Request {
+	return r.clusterNotifiersReferencing(configMapsField, obj)
+}
+
+func (r *ClusterNotifierReconciler) clusterNotifiersForEscalationPolicy(obj handler.MapObject) []reconcile.Request {
+	return r.clusterNotifiersReferencing(escalationPoliciesField, obj)
+}
+
+func (r *ClusterNotifierReconciler) clusterNotifiersReferencing(field string, obj handler.MapObject) []reconcile.Request {
+	// The index holds the namespace of every reference, like the default SMTP Secret outside the controller namespace
+	key := types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()}
+	notifiers := &emailv1.ClusterNotifierList{}
+	err := r.List(ctx.TODO(), notifiers, client.MatchingField(field, key.String()))
+	if err != nil {
+		r.Log.Error(err, "Failed to list ClusterNotifiers", "field", field, "object", key)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, notifier := range notifiers.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: notifier.GetName()}})
+	}
+	return requests
+}
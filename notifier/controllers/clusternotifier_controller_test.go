@@ -0,0 +1,199 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	emailv1 "std/api/v1"
+)
+
+// indexedClient filters ClusterNotifier lists by the field indexes, like the cache does, the fake client ignores field selectors
+type indexedClient struct {
+	client.Client
+	indexes map[string]client.IndexerFunc
+}
+
+func (c *indexedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOptionFunc) error {
+	if err := c.Client.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	notifiers, ok := list.(*emailv1.ClusterNotifierList)
+	if !ok || options.FieldSelector == nil {
+		return nil
+	}
+	matching := []emailv1.ClusterNotifier{}
+	for _, notifier := range notifiers.Items {
+		for field, index := range c.indexes {
+			value, found := options.FieldSelector.RequiresExactMatch(field)
+			if found && containsString(index(&notifier), value) {
+				matching = append(matching, notifier)
+				break
+			}
+		}
+	}
+	notifiers.Items = matching
+	return nil
+}
+
+var _ = Describe("ClusterNotifierReconciler", func() {
+	var clusterNotifier *emailv1.ClusterNotifier
+
+	BeforeEach(func() {
+		smtpServer.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(context.TODO(), clusterNotifier)).To(Succeed())
+	})
+
+	newNamespace := func(name string, labels map[string]string) {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+	}
+
+	It("should notify about Events in the selected namespaces", func() {
+		newNamespace("platform-selected", map[string]string{"notify": "platform"})
+		newNamespace("platform-ignored", nil)
+
+		clusterNotifier = &emailv1.ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec: emailv1.ClusterNotifierSpec{
+				NotifierSpec: emailv1.NotifierSpec{
+					Email:   "platform@example.com",
+					Filters: []string{"Evicted"},
+				},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"notify": "platform"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), clusterNotifier)).To(Succeed())
+
+		for _, namespace := range []string{"platform-ignored", "platform-selected"} {
+			event := newWarningEvent("evicted-pod.evicted", "Evicted", "Pod", "evicted-pod")
+			event.Namespace = namespace
+			event.InvolvedObject.Namespace = namespace
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		}
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("platform@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		mail := smtpServer.MessagesTo("platform@example.com")[0]
+		Expect(mail.Data).To(ContainSubstring("Pod: platform-selected/evicted-pod"))
+
+		Eventually(func() int64 {
+			fetched := &emailv1.ClusterNotifier{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: "platform"}, fetched)).To(Succeed())
+			return fetched.Status.DeliveredCount
+		}, timeout, interval).Should(BeEquivalentTo(1))
+
+		records := &emailv1.NotificationRecordList{}
+		Expect(k8sClient.List(context.TODO(), records, client.InNamespace("default"))).To(Succeed())
+		owned := []emailv1.NotificationRecord{}
+		for _, record := range records.Items {
+			if record.Spec.NotifierKind == emailv1.ClusterNotifierKind && record.Spec.Notifier == "platform" {
+				owned = append(owned, record)
+			}
+		}
+		Expect(owned).To(HaveLen(1))
+		Expect(owned[0].Spec.EventRef.Namespace).To(Equal("platform-selected"))
+		Expect(owned[0].GetOwnerReferences()[0].Kind).To(Equal(emailv1.ClusterNotifierKind))
+	})
+
+	It("should notify alongside a Notifier of the same name", func() {
+		clusterNotifier = &emailv1.ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared-name"},
+			Spec: emailv1.ClusterNotifierSpec{
+				NotifierSpec: emailv1.NotifierSpec{
+					Email:   "cluster@example.com",
+					Filters: []string{"NodeNotReady"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), clusterNotifier)).To(Succeed())
+		notifier := newNotifier("shared-name", "namespaced@example.com", "NodeNotReady")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), notifier)
+
+		event := newWarningEvent("notready-pod.notready", "NodeNotReady", "Pod", "notready-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("cluster@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("namespaced@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+	})
+})
+
+var _ = Describe("ClusterNotifier watches", func() {
+	It("should requeue on Secrets outside the controller namespace", func() {
+		platform := &emailv1.ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec: emailv1.ClusterNotifierSpec{
+				NotifierSpec: emailv1.NotifierSpec{Email: "platform@example.com"},
+			},
+		}
+		team := &emailv1.ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: emailv1.ClusterNotifierSpec{
+				NotifierSpec: emailv1.NotifierSpec{Email: "team@example.com", SMTPSecretRef: &corev1.LocalObjectReference{Name: "team-smtp"}},
+			},
+		}
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &ClusterNotifierReconciler{
+			NotifierReconciler: NotifierReconciler{
+				Log:               ctrl.Log.WithName("clusternotifier"),
+				DefaultSMTPSecret: types.NamespacedName{Namespace: "notifier-system", Name: "smtp"},
+			},
+			Namespace: "default",
+		}
+		r.Client = &indexedClient{
+			Client:  fake.NewFakeClientWithScheme(s, platform, team),
+			indexes: map[string]client.IndexerFunc{secretsField: r.secretsIndex},
+		}
+		Expect(r.secretsIndex(platform)).To(Equal([]string{"notifier-system/smtp"}))
+		Expect(r.secretsIndex(team)).To(Equal([]string{"default/team-smtp"}))
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "notifier-system", Name: "smtp"}}
+		Expect(r.clusterNotifiersForSecret(handler.MapObject{Meta: secret, Object: secret})).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "platform"}},
+		}))
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "team-smtp"}}
+		Expect(r.clusterNotifiersForSecret(handler.MapObject{Meta: secret, Object: secret})).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "team"}},
+		}))
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "team-smtp"}}
+		Expect(r.clusterNotifiersForSecret(handler.MapObject{Meta: secret, Object: secret})).To(BeEmpty())
+	})
+})
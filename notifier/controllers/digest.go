@@ -0,0 +1,155 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+// DigestGroup aggregates the Events with the same reason about the same object
+type DigestGroup struct {
+	Reason         string                 `json:"reason"`
+	InvolvedObject corev1.ObjectReference `json:"involvedObject"`
+	// Count sums the occurrences of all Events in the group
+	Count int32 `json:"count"`
+	// Messages are the distinct Event messages, in the order they were seen
+	Messages       []string    `json:"messages"`
+	FirstTimestamp metav1.Time `json:"firstTimestamp"`
+	LastTimestamp  metav1.Time `json:"lastTimestamp"`
+}
+
+// Title is a one line summary of the group
+func (g DigestGroup) Title() string {
+	title := fmt.Sprintf("%s - %s %s", g.Reason, g.InvolvedObject.Kind, referenceName(g.InvolvedObject))
+	if g.Count > 1 {
+		title += fmt.Sprintf(" (x%d)", g.Count)
+	}
+	return title
+}
+
+// groupDigest groups the Events by reason and involved object, ordered by reason and object
+func groupDigest(events []*corev1.Event) []DigestGroup {
+	groups := []DigestGroup{}
+	index := map[string]int{}
+	for _, event := range events {
+		ref := event.InvolvedObject
+		key := strings.Join([]string{event.Reason, ref.Kind, ref.Namespace, ref.Name}, "/")
+		i, found := index[key]
+		if !found {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, DigestGroup{
+				Reason:         event.Reason,
+				InvolvedObject: ref,
+				FirstTimestamp: event.FirstTimestamp,
+				LastTimestamp:  event.LastTimestamp,
+			})
+		}
+
+		group := &groups[i]
+		group.Count += occurrences(event)
+		if !containsString(group.Messages, event.Message) {
+			group.Messages = append(group.Messages, event.Message)
+		}
+		if event.FirstTimestamp.Before(&group.FirstTimestamp) {
+			group.FirstTimestamp = event.FirstTimestamp
+		}
+		if group.LastTimestamp.Before(&event.LastTimestamp) {
+			group.LastTimestamp = event.LastTimestamp
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Reason != groups[j].Reason {
+			return groups[i].Reason < groups[j].Reason
+		}
+		return referenceName(groups[i].InvolvedObject) < referenceName(groups[j].InvolvedObject)
+	})
+	return groups
+}
+
+// digestDue decides whether the pending records are flushed now, or how long to wait for more.
+// The window opens with the oldest pending record, reaching maxItems flushes early.
+func digestDue(policy *emailv1.DigestPolicy, records []emailv1.NotificationRecord, now time.Time) (bool, time.Duration) {
+	if len(records) == 0 {
+		return false, 0
+	}
+	if policy.MaxItems > 0 && int32(len(records)) >= policy.MaxItems {
+		return true, 0
+	}
+
+	opened := pendingSince(&records[0])
+	for i := range records[1:] {
+		if since := pendingSince(&records[i+1]); since.Before(opened) {
+			opened = since
+		}
+	}
+	wait := policy.Window.Duration - now.Sub(opened)
+	if wait <= 0 {
+		return true, 0
+	}
+	return false, wait
+}
+
+// digestBatches splits the records into digests of at most maxItems
+func digestBatches(policy *emailv1.DigestPolicy, records []emailv1.NotificationRecord) [][]emailv1.NotificationRecord {
+	size := len(records)
+	if policy.MaxItems > 0 && int(policy.MaxItems) < size {
+		size = int(policy.MaxItems)
+	}
+
+	batches := [][]emailv1.NotificationRecord{}
+	for len(records) > 0 {
+		if size > len(records) {
+			size = len(records)
+		}
+		batches = append(batches, records[:size])
+		records = records[size:]
+	}
+	return batches
+}
+
+// pendingSince is when the record started waiting for delivery
+func pendingSince(record *emailv1.NotificationRecord) time.Time {
+	if record.Status.LastAttemptTime != nil {
+		return record.Status.LastAttemptTime.Time
+	}
+	return record.CreationTimestamp.Time
+}
+
+// occurrences counts an Event without a count as a single one
+func occurrences(event *corev1.Event) int32 {
+	if event.Count < 1 {
+		return 1
+	}
+	return event.Count
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("Digest", func() {
+	var start time.Time
+
+	at := func(minutes int) metav1.Time {
+		return metav1.NewTime(start.Add(time.Duration(minutes) * time.Minute))
+	}
+
+	event := func(reason, kind, name, message string, count int32, first, last int) *corev1.Event {
+		event := newWarningEvent(name+"."+reason, reason, kind, name)
+		event.Message = message
+		event.Count = count
+		event.FirstTimestamp = at(first)
+		event.LastTimestamp = at(last)
+		return event
+	}
+
+	records := func(created ...int) []emailv1.NotificationRecord {
+		records := []emailv1.NotificationRecord{}
+		for _, minutes := range created {
+			record := emailv1.NotificationRecord{}
+			record.CreationTimestamp = at(minutes)
+			records = append(records, record)
+		}
+		return records
+	}
+
+	BeforeEach(func() {
+		start = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("should group Events by reason and involved object", func() {
+		groups := groupDigest([]*corev1.Event{
+			event("NodeNotReady", "Pod", "web-2", "Node is not ready", 1, 0, 0),
+			event("BackOff", "Pod", "web-1", "Back-off restarting failed container", 3, 1, 4),
+			event("NodeNotReady", "Pod", "web-1", "Node is not ready", 1, 0, 0),
+			event("BackOff", "Pod", "web-1", "Back-off pulling image", 2, 0, 2),
+			event("BackOff", "Pod", "web-1", "Back-off restarting failed container", 0, 5, 5),
+		})
+
+		Expect(groups).To(HaveLen(3))
+		Expect(groups[0].Title()).To(Equal("BackOff - Pod default/web-1 (x6)"))
+		Expect(groups[0].Messages).To(Equal([]string{
+			"Back-off restarting failed container",
+			"Back-off pulling image",
+		}))
+		Expect(groups[0].FirstTimestamp).To(Equal(at(0)))
+		Expect(groups[0].LastTimestamp).To(Equal(at(5)))
+		Expect(groups[1].Title()).To(Equal("NodeNotReady - Pod default/web-1"))
+		Expect(groups[2].Title()).To(Equal("NodeNotReady - Pod default/web-2"))
+	})
+
+	It("should wait for the window opened by the oldest record", func() {
+		policy := &emailv1.DigestPolicy{Window: metav1.Duration{Duration: 10 * time.Minute}}
+		Expect(digestDue(policy, nil, start)).To(BeFalse())
+
+		due, wait := digestDue(policy, records(6, 2, 8), start.Add(5*time.Minute))
+		Expect(due).To(BeFalse())
+		Expect(wait).To(Equal(7 * time.Minute))
+
+		Expect(digestDue(policy, records(6, 2, 8), start.Add(12*time.Minute))).To(BeTrue())
+	})
+
+	It("should flush early and split at maxItems", func() {
+		policy := &emailv1.DigestPolicy{Window: metav1.Duration{Duration: time.Hour}, MaxItems: 2}
+		due, _ := digestDue(policy, records(0), start)
+		Expect(due).To(BeFalse())
+		Expect(digestDue(policy, records(0, 1), start)).To(BeTrue())
+
+		batches := digestBatches(policy, records(0, 1, 2, 3, 4))
+		Expect(batches).To(HaveLen(3))
+		Expect(batches[0]).To(HaveLen(2))
+		Expect(batches[2]).To(HaveLen(1))
+
+		Expect(digestBatches(&emailv1.DigestPolicy{}, records(0, 1, 2))).To(HaveLen(1))
+	})
+
+	It("should render the digest", func() {
+		notification := &Notification{
+			Notifier: newNotifier("team", "team@example.com"),
+			Digest: groupDigest([]*corev1.Event{
+				event("BackOff", "Pod", "web-1", "Back-off restarting failed container", 3, 0, 1),
+				event("FailedMount", "Pod", "web-2", "Unable to attach volumes", 1, 0, 0),
+			}),
+		}
+		Expect(notification.IsDigest()).To(BeTrue())
+		Expect(notification.Subject()).To(Equal("[team] Digest: 4 events in 2 groups"))
+		Expect(notification.Text()).To(Equal("Events occured!\n" +
+			"\nBackOff - Pod default/web-1 (x3)\n  Back-off restarting failed container\n" +
+			"\nFailedMount - Pod default/web-2\n  Unable to attach volumes\n"))
+
+		payload := SlackPayload(notification).(map[string]interface{})
+		Expect(payload["attachments"]).To(HaveLen(2))
+		Expect(WebhookPayload(notification)).To(HaveKeyWithValue("digest", notification.Digest))
+		Expect(WebhookPayload(notification)).NotTo(HaveKey("event"))
+	})
+})
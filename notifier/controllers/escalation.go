@@ -0,0 +1,296 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	emailv1 "std/api/v1"
+)
+
+// EscalationNotice tells the recipients of a tier why they are notified
+type EscalationNotice struct {
+	// Tier is the name of the notified tier
+	Tier string `json:"tier"`
+	// Level counts the tiers notified so far, starting at 1
+	Level int32 `json:"level"`
+	// Unacknowledged is how long the notification was left unacknowledged
+	Unacknowledged metav1.Duration `json:"unacknowledged"`
+}
+
+// scheduleEscalation applies the acknowledgement annotation and plans the next tier of the policy.
+// A nil policy ends the escalation. It reports whether the status changed.
+func scheduleEscalation(record *emailv1.NotificationRecord, policy *emailv1.EscalationPolicy, now time.Time) bool {
+	escalation := record.Status.Escalation
+	if escalation == nil || escalation.AcknowledgedTime != nil {
+		return false
+	}
+	previous := escalation.DeepCopy()
+
+	if by, found := record.GetAnnotations()[emailv1.AcknowledgedByAnnotation]; found {
+		acknowledged := metav1.NewTime(now)
+		escalation.AcknowledgedTime = &acknowledged
+		escalation.AcknowledgedBy = by
+		escalation.NextTime = nil
+	} else if policy == nil || record.Status.ResolvedTime != nil {
+		// Nobody has to react to a resolved incident
+		escalation.NextTime = nil
+	} else {
+		escalation.Policy = policy.GetName()
+		next, found := policy.NextTier(escalation.StartTime.Time, escalation.Level)
+		if !found {
+			escalation.NextTime = nil
+		} else if escalation.NextTime == nil || escalation.NextTime.Time.Before(next) {
+			// A later time is a tier waiting for its retry
+			nextTime := metav1.NewTime(next)
+			escalation.NextTime = &nextTime
+		}
+	}
+	return !escalation.NextTime.Equal(previous.NextTime) ||
+		escalation.AcknowledgedTime != previous.AcknowledgedTime ||
+		escalation.Policy != previous.Policy
+}
+
+// escalationDue tells whether the next tier is notified now, or how long to wait for it
+func escalationDue(record *emailv1.NotificationRecord, now time.Time) (bool, time.Duration) {
+	escalation := record.Status.Escalation
+	if !escalation.IsPending() {
+		return false, 0
+	}
+	wait := escalation.NextTime.Sub(now)
+	if wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// escalate notifies the tiers of the escalation policy about the sent records nobody acknowledged,
+// and returns when the next tier is due. A missing policy, or one using missing Secrets, is errInvalidConfig.
+// The alerts about newly acknowledged records are acknowledged in the channels.
+func (r *NotifierReconciler) escalate(notifier *emailv1.Notifier, channels []Channel, template *messageTemplate, records []emailv1.NotificationRecord, now time.Time) (time.Duration, error) {
+	policy, err := r.getEscalationPolicy(notifier)
+	if err != nil {
+		return 0, err
+	}
+
+	var next time.Duration
+	due := map[int32][]emailv1.NotificationRecord{}
+	for i := range records {
+		record := &records[i]
+		unacknowledged := record.Status.Escalation != nil && record.Status.Escalation.AcknowledgedTime == nil
+		if scheduleEscalation(record, policy, now) {
+			if err := r.Status().Update(ctx.TODO(), record); err != nil {
+				return 0, err
+			}
+			if unacknowledged && record.Status.Escalation.AcknowledgedTime != nil {
+				r.acknowledge(notifier, channels, policy, record)
+			}
+		}
+		notify, wait := escalationDue(record, now)
+		if notify {
+			level := record.Status.Escalation.Level
+			due[level] = append(due[level], *record)
+		} else if wait > 0 {
+			next = minDuration(next, wait)
+		}
+	}
+	if len(due) == 0 {
+		return next, nil
+	}
+
+	levels := []int32{}
+	for level := range due {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	for _, level := range levels {
+		tier := policy.Spec.Tiers[level]
+		tierChannels, err := r.buildChannels(notifier, tier.Channels)
+		if err != nil {
+			return 0, errors.Wrapf(err, "escalation tier %s", tier.Name)
+		}
+		for _, batch := range escalationBatches(notifier, due[level]) {
+			wait, err := r.escalateRecords(notifier, template, policy, tierChannels, batch, now)
+			if err != nil {
+				return 0, err
+			}
+			next = minDuration(next, wait)
+		}
+	}
+	return next, nil
+}
+
+// escalationBatches notifies a tier about every record on its own, or in a digest for digest Notifiers
+func escalationBatches(notifier *emailv1.Notifier, records []emailv1.NotificationRecord) [][]emailv1.NotificationRecord {
+	if notifier.Spec.Digest != nil {
+		return [][]emailv1.NotificationRecord{records}
+	}
+	batches := [][]emailv1.NotificationRecord{}
+	for i := range records {
+		batches = append(batches, records[i:i+1])
+	}
+	return batches
+}
+
+// escalateRecords notifies the current tier about the records, and schedules the next one.
+// A failed tier is retried with the backoff of the Notifier retry policy, and skipped after the last attempt.
+func (r *NotifierReconciler) escalateRecords(notifier *emailv1.Notifier, template *messageTemplate, policy *emailv1.EscalationPolicy,
+	channels []Channel, records []emailv1.NotificationRecord, now time.Time) (time.Duration, error) {
+	escalation := records[0].Status.Escalation
+	tier := policy.Spec.Tiers[escalation.Level]
+	notification := &Notification{
+		Notifier: notifier,
+		Escalation: &EscalationNotice{
+			Tier:           tier.Name,
+			Level:          escalation.Level + 1,
+			Unacknowledged: metav1.Duration{Duration: now.Sub(escalation.StartTime.Time).Round(time.Second)},
+		},
+	}
+	if len(records) == 1 && notifier.Spec.Digest == nil {
+		notification.Event = recordEvent(&records[0])
+		notification.Context = records[0].Spec.Context
+		notification.Record = records[0].GetName()
+		notification.AcknowledgeURL = r.Acknowledge.Link(&records[0])
+	} else {
+		events := []*corev1.Event{}
+		for i := range records {
+			events = append(events, recordEvent(&records[i]))
+		}
+		notification.Digest = groupDigest(events)
+	}
+	r.render(notifier, template, notification)
+
+	deliveredTo := []*[]string{}
+	for i := range records {
+		deliveredTo = append(deliveredTo, &records[i].Status.Escalation.DeliveredTo)
+	}
+	sendErr := r.sendAll(notifier, channels, notification, deliveredTo...)
+	retry := notifier.Spec.Retry
+	var wait time.Duration
+	for i := range records {
+		escalation := records[i].Status.Escalation
+		if sendErr != nil {
+			escalation.FailedAttempts++
+			escalation.LastError = fmt.Sprintf("escalation to %s failed: %v", tier.Name, sendErr)
+		}
+		if sendErr != nil && escalation.FailedAttempts < retry.GetMaxAttempts() {
+			next := metav1.NewTime(now.Add(retryBackoff(retry, escalation.FailedAttempts)))
+			escalation.NextTime = &next
+			wait = minDuration(wait, next.Sub(now))
+		} else {
+			// Notified, or given up on, the next tier takes over
+			if sendErr != nil {
+				r.skipTier(notifier, &records[i], tier.Name)
+			} else {
+				escalation.LastError = ""
+			}
+			escalation.Level++
+			escalation.FailedAttempts = 0
+			escalation.DeliveredTo = nil
+			scheduleEscalation(&records[i], policy, now)
+			if escalation.NextTime != nil {
+				wait = minDuration(wait, escalation.NextTime.Sub(now))
+			}
+		}
+		if err := r.Status().Update(ctx.TODO(), &records[i]); err != nil {
+			return 0, err
+		}
+	}
+	if sendErr != nil {
+		notifier.Status.LastError = fmt.Sprintf("escalation to %s failed: %v", tier.Name, sendErr)
+		r.Log.Info("Failed to escalate", "notifier", notifier.GetName(), "tier", tier.Name, "error", sendErr.Error())
+	}
+	return wait, nil
+}
+
+// skipTier reports a tier given up after the last retry, like a dead-lettered record
+func (r *NotifierReconciler) skipTier(notifier *emailv1.Notifier, record *emailv1.NotificationRecord, tier string) {
+	escalation := record.Status.Escalation
+	r.Log.Info("Giving up on escalation tier", "notifier", notifier.GetName(), "record", record.GetName(), "tier", tier,
+		"attempts", escalation.FailedAttempts, "error", escalation.LastError)
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(notifierObject(notifier), corev1.EventTypeWarning, "EscalationFailed",
+		"Skipped tier %s for %s about %s %s after %d attempts: %s",
+		tier,
+		record.Spec.Event.Reason,
+		record.Spec.Event.InvolvedObject.Kind,
+		referenceName(record.Spec.Event.InvolvedObject),
+		escalation.FailedAttempts,
+		escalation.LastError)
+}
+
+// acknowledge tells the channels tracking alerts about the acknowledgement of the record:
+// the channels of the Notifier, and of the tiers notified so far.
+// Failures are only reported, the alert is resolved later anyway.
+func (r *NotifierReconciler) acknowledge(notifier *emailv1.Notifier, channels []Channel, policy *emailv1.EscalationPolicy, record *emailv1.NotificationRecord) {
+	if notifier.Spec.Digest != nil {
+		// The alerts are about digests, not records
+		return
+	}
+	escalation := record.Status.Escalation
+	all := append([]Channel{}, channels...)
+	for level := int32(0); policy != nil && level < escalation.Level && int(level) < len(policy.Spec.Tiers); level++ {
+		tierChannels, err := r.buildChannels(notifier, policy.Spec.Tiers[level].Channels)
+		if err != nil {
+			r.Log.Info("Failed to build the tier channels", "notifier", notifier.GetName(), "tier", policy.Spec.Tiers[level].Name, "error", err.Error())
+			continue
+		}
+		all = append(all, tierChannels...)
+	}
+
+	notification := &Notification{
+		Notifier: notifier,
+		Event:    recordEvent(record),
+		Context:  record.Spec.Context,
+		Record:   record.GetName(),
+	}
+	acknowledged := map[string]bool{}
+	for _, channel := range all {
+		acknowledger, ok := channel.(Acknowledger)
+		if !ok || acknowledged[channelKey(channel)] {
+			continue
+		}
+		acknowledged[channelKey(channel)] = true
+		if err := acknowledger.Acknowledge(notification, escalation.AcknowledgedBy); err != nil {
+			notifier.Status.LastError = fmt.Sprintf("acknowledging %s failed: %v", record.GetName(), err)
+			r.Log.Info("Failed to acknowledge", "notifier", notifier.GetName(), "record", record.GetName(), "error", err.Error())
+		}
+	}
+}
+
+// getEscalationPolicy reads the policy of the Notifier, nil when it doesn't escalate
+func (r *NotifierReconciler) getEscalationPolicy(notifier *emailv1.Notifier) (*emailv1.EscalationPolicy, error) {
+	name := notifier.GetEscalationPolicyName()
+	if name == "" {
+		return nil, nil
+	}
+	policy := &emailv1.EscalationPolicy{}
+	err := r.Get(ctx.TODO(), types.NamespacedName{Namespace: notifier.GetNamespace(), Name: name}, policy)
+	if k8serror.IsNotFound(err) {
+		return nil, errors.Wrapf(errInvalidConfig, "EscalationPolicy %s/%s not found", notifier.GetNamespace(), name)
+	}
+	return policy, err
+}
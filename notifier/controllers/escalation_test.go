@@ -0,0 +1,215 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("escalation", func() {
+	var (
+		now    time.Time
+		policy *emailv1.EscalationPolicy
+		record *emailv1.NotificationRecord
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+		policy = &emailv1.EscalationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall"},
+			Spec: emailv1.EscalationPolicySpec{Tiers: []emailv1.EscalationTier{
+				{Name: "on-call", After: metav1.Duration{Duration: 15 * time.Minute}},
+				{Name: "manager", After: metav1.Duration{Duration: time.Hour}},
+			}},
+		}
+		// Sent now, as deliverRecords leaves it
+		sent := metav1.NewTime(now)
+		record = &emailv1.NotificationRecord{ObjectMeta: metav1.ObjectMeta{Name: "record"}}
+		record.Status.State = emailv1.RecordSent
+		record.Status.SentTime = &sent
+		record.Status.Escalation = &emailv1.EscalationStatus{Policy: "oncall", StartTime: sent, NextTime: &sent}
+	})
+
+	nextTime := func() time.Time {
+		return record.Status.Escalation.NextTime.Time
+	}
+
+	It("should schedule the tiers from the first notification", func() {
+		Expect(scheduleEscalation(record, policy, now)).To(BeTrue())
+		Expect(nextTime()).To(Equal(now.Add(15 * time.Minute)))
+		Expect(record.CompletionTime()).To(BeNil())
+
+		due, wait := escalationDue(record, now)
+		Expect(due).To(BeFalse())
+		Expect(wait).To(Equal(15 * time.Minute))
+		due, _ = escalationDue(record, now.Add(15*time.Minute))
+		Expect(due).To(BeTrue())
+
+		Expect(scheduleEscalation(record, policy, now)).To(BeFalse())
+		record.Status.Escalation.Level = 1
+		Expect(scheduleEscalation(record, policy, now)).To(BeTrue())
+		Expect(nextTime()).To(Equal(now.Add(time.Hour)))
+	})
+
+	It("should end once every tier was notified", func() {
+		record.Status.Escalation.Level = 2
+		Expect(scheduleEscalation(record, policy, now)).To(BeTrue())
+		Expect(record.Status.Escalation.NextTime).To(BeNil())
+		Expect(record.CompletionTime()).To(Equal(record.Status.SentTime))
+
+		due, wait := escalationDue(record, now.Add(time.Hour))
+		Expect(due).To(BeFalse())
+		Expect(wait).To(BeZero())
+	})
+
+	It("should stop when acknowledged", func() {
+		scheduleEscalation(record, policy, now)
+		record.SetAnnotations(map[string]string{emailv1.AcknowledgedByAnnotation: "alice"})
+
+		Expect(scheduleEscalation(record, policy, now.Add(time.Minute))).To(BeTrue())
+		Expect(record.Status.Escalation.AcknowledgedBy).To(Equal("alice"))
+		Expect(record.Status.Escalation.AcknowledgedTime.Time).To(Equal(now.Add(time.Minute)))
+		Expect(record.Status.Escalation.NextTime).To(BeNil())
+		Expect(record.CompletionTime()).NotTo(BeNil())
+
+		// The acknowledgement is final, even if the annotation goes away
+		record.SetAnnotations(nil)
+		Expect(scheduleEscalation(record, policy, now.Add(time.Hour))).To(BeFalse())
+	})
+
+	It("should keep a tier waiting for its retry", func() {
+		retry := metav1.NewTime(now.Add(20 * time.Minute))
+		record.Status.Escalation.NextTime = &retry
+		Expect(scheduleEscalation(record, policy, now)).To(BeFalse())
+		Expect(nextTime()).To(Equal(retry.Time))
+	})
+
+	It("should end when the Notifier stops escalating", func() {
+		Expect(scheduleEscalation(record, nil, now)).To(BeTrue())
+		Expect(record.Status.Escalation.IsPending()).To(BeFalse())
+	})
+
+	It("should end once the incident is resolved", func() {
+		resolved := metav1.NewTime(now.Add(time.Minute))
+		record.Status.ResolvedTime = &resolved
+		Expect(scheduleEscalation(record, policy, now.Add(time.Minute))).To(BeTrue())
+		Expect(record.Status.Escalation.IsPending()).To(BeFalse())
+	})
+
+	It("should leave records without escalation alone", func() {
+		record.Status.Escalation = nil
+		Expect(scheduleEscalation(record, policy, now)).To(BeFalse())
+		Expect(record.CompletionTime()).To(Equal(record.Status.SentTime))
+	})
+
+	It("should batch the records of digest Notifiers", func() {
+		notifier := &emailv1.Notifier{}
+		records := []emailv1.NotificationRecord{*record, *record, *record}
+		Expect(escalationBatches(notifier, records)).To(HaveLen(3))
+
+		notifier.Spec.Digest = &emailv1.DigestPolicy{Window: metav1.Duration{Duration: time.Minute}}
+		Expect(escalationBatches(notifier, records)).To(HaveLen(1))
+	})
+
+	It("should retry a failing tier and skip it after the last attempt", func() {
+		jitter := retryJitter
+		defer func() { retryJitter = jitter }()
+		retryJitter = func(max time.Duration) time.Duration { return max }
+
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+		recorder.RespondWith(http.StatusBadGateway)
+		second := newWebhookRecorder()
+		defer second.Close()
+
+		notifier := &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"}}
+		notifier.Spec.Retry = &emailv1.RetryPolicy{MaxAttempts: 2, Backoff: &metav1.Duration{Duration: time.Minute}}
+		record.Namespace = "apps"
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(s, record), Log: ctrl.Log.WithName("escalation")}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{
+			{Type: emailv1.WebhookChannel, URL: second.URL},
+			{Type: emailv1.WebhookChannel, URL: recorder.URL},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		records := []emailv1.NotificationRecord{*record}
+		escalation := records[0].Status.Escalation
+
+		wait, err := r.escalateRecords(notifier, nil, policy, channels, records, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wait).To(Equal(time.Minute))
+		Expect(escalation.Level).To(BeZero())
+		Expect(escalation.FailedAttempts).To(BeEquivalentTo(1))
+		Expect(escalation.DeliveredTo).To(HaveLen(1))
+		Expect(escalation.LastError).To(ContainSubstring("escalation to on-call failed"))
+
+		// The channel which got it isn't notified again, the tier is given up after the second attempt
+		wait, err = r.escalateRecords(notifier, nil, policy, channels, records, now.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Payloads()).To(HaveLen(1))
+		Expect(recorder.Payloads()).To(HaveLen(2))
+		Expect(escalation.Level).To(BeEquivalentTo(1))
+		Expect(escalation.FailedAttempts).To(BeZero())
+		Expect(escalation.DeliveredTo).To(BeEmpty())
+		Expect(nextTime()).To(Equal(now.Add(time.Hour)))
+		Expect(wait).To(Equal(59 * time.Minute))
+	})
+
+	It("should acknowledge the alerts about the record once", func() {
+		standIn := newPagerDutyStandIn()
+		defer standIn.Close()
+		pagerDuty := emailv1.Channel{
+			Type:                emailv1.PagerDutyChannel,
+			URL:                 standIn.URL + "/v2/enqueue",
+			RoutingKeySecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "pagerduty"}, Key: "routingKey"},
+		}
+		notifier := &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"}}
+		r := &NotifierReconciler{
+			Client: fake.NewFakeClientWithScheme(scheme.Scheme, newKeySecret("pagerduty", "routingKey", testRoutingKey)),
+			Log:    ctrl.Log.WithName("escalation"),
+		}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{pagerDuty, {Type: emailv1.WebhookChannel, URL: standIn.URL + "/hook"}})
+		Expect(err).NotTo(HaveOccurred())
+		// The tier shares the channel of the Notifier, the next tier wasn't notified yet
+		policy.Spec.Tiers[0].Channels = []emailv1.Channel{pagerDuty}
+		policy.Spec.Tiers[1].Channels = []emailv1.Channel{{Type: emailv1.OpsgenieChannel, URL: standIn.URL + "/v2/alerts"}}
+
+		record.Status.Escalation.Level = 1
+		record.Status.Escalation.AcknowledgedBy = "Alice"
+		r.acknowledge(notifier, channels, policy, record)
+		Expect(standIn.Events()).To(Equal([]map[string]interface{}{
+			{"routing_key": testRoutingKey, "event_action": "acknowledge", "dedup_key": "apps/record"},
+		}))
+		Expect(notifier.Status.LastError).To(BeEmpty())
+
+		notifier.Spec.Digest = &emailv1.DigestPolicy{Window: metav1.Duration{Duration: time.Minute}}
+		r.acknowledge(notifier, channels, policy, record)
+		Expect(standIn.Events()).To(HaveLen(1))
+	})
+})
@@ -18,12 +18,17 @@ package controllers
 import (
 	ctx "context"
 	emailv1 "std/api/v1"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,10 +38,21 @@ type EventReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	// ClusterNamespace holds the NotificationRecords of ClusterNotifiers, which are ignored when empty
+	ClusterNamespace string
+	// Filters caches the compiled filters, shared with the NotifierReconciler
+	Filters *FilterIndex
 }
 
-// +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=core,resources=events/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods;nodes,verbs=get
+// +kubebuilder:rbac:groups=apps,resources=replicasets;deployments;statefulsets;daemonsets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get
+// +kubebuilder:rbac:groups=email.notify.io,resources=notificationrecords,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=email.notify.io,resources=clusternotifiers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=email.notify.io,resources=silences,verbs=get;list;watch
 
 func (r *EventReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("event", req.NamespacedName)
@@ -50,71 +66,262 @@ func (r *EventReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	eventsObserved.WithLabelValues(event.Type).Inc()
+
 	// Skip purely informational events
 	if event.Type != "Warning" {
 		return ctrl.Result{}, nil
 	}
 
-	notifiers, err := r.getMatchingNotifiers(event)
+	// Don't notify about our own Events, a failing channel would feed itself
+	if event.InvolvedObject.APIVersion == emailv1.GroupVersion.String() {
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	notifiers, silence, err := r.getMatchingNotifiers(event, now)
 	if err != nil {
 		log.Error(err, "Can't match notifiers for event")
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	// No Notifier CRs found in the namespace
+	// No Notifier CRs found for the namespace
 	if len(notifiers) == 0 {
 		return ctrl.Result{}, nil
 	}
 
+	resolver := &contextResolver{reader: r, ref: event.InvolvedObject}
 	for _, notifier := range notifiers {
-		err = r.requestNotify(event, &notifier)
-		if k8serror.IsConflict(err) {
+		err = r.requestNotify(event, &notifier, r.silencedBy(&notifier, silence, now), resolver, now)
+		if k8serror.IsConflict(err) || k8serror.IsAlreadyExists(err) {
 			return ctrl.Result{Requeue: true}, nil
 		} else if err != nil {
-			log.Error(err, "Error on updating Event with notify label")
+			log.Error(err, "Error on creating NotificationRecord", "notifier", notifier.GetName())
 			return ctrl.Result{Requeue: true}, nil
 		}
+		eventsMatched.WithLabelValues(notifierLabels(&notifier)...).Inc()
 	}
 
 	return ctrl.Result{}, nil
 }
 
 func (r *EventReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(&emailv1.Notifier{}, kindsField, kindsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.ClusterNotifier{}, kindsField, clusterKindsIndex)
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Event{}).
-		WithEventFilter(EventPredicate{}).
+		WithEventFilter(EventPredicate{Client: mgr.GetClient(), Cluster: r.ClusterNamespace != ""}).
 		Complete(r)
 }
 
-func (r *EventReconciler) getMatchingNotifiers(event *corev1.Event) ([]emailv1.Notifier, error) {
+// getMatchingNotifiers matches the Notifiers in the Event namespace and the ClusterNotifiers selecting it,
+// along with the active Silence matching the Event, if any.
+// ClusterNotifiers are returned as Notifiers in the ClusterNamespace.
+func (r *EventReconciler) getMatchingNotifiers(event *corev1.Event, now time.Time) ([]emailv1.Notifier, *emailv1.Silence, error) {
 	matchedNotifiers := []emailv1.Notifier{}
 	notifierList := &emailv1.NotifierList{}
 	err := r.Client.List(ctx.TODO(), notifierList, client.InNamespace(event.GetNamespace()))
 	if err != nil {
-		return matchedNotifiers, err
+		return matchedNotifiers, nil, err
+	}
+
+	clusterNotifiers, err := r.getClusterNotifiers(event.GetNamespace())
+	if err != nil {
+		return matchedNotifiers, nil, err
+	}
+	notifierList.Items = append(notifierList.Items, clusterNotifiers...)
+	if len(notifierList.Items) == 0 {
+		return matchedNotifiers, nil, nil
 	}
 
-	return notifierList.Matching(event.Reason)
+	silences, err := r.getActiveSilences(event.GetNamespace(), now)
+	if err != nil {
+		return matchedNotifiers, nil, err
+	}
+
+	input := emailv1.FilterInput{Event: event}
+	if r.Filters.UsesLabels(notifierList.Items) || silencesUseLabels(silences) {
+		input.Labels, err = r.getObjectLabels(event.InvolvedObject)
+		if err != nil {
+			return matchedNotifiers, nil, err
+		}
+	}
+
+	matchedNotifiers = r.Filters.Matching(notifierList.Items, input)
+	for i := range silences {
+		if len(matchedNotifiers) > 0 && silences[i].Spec.Match.Match(input) {
+			return matchedNotifiers, &silences[i], nil
+		}
+	}
+	return matchedNotifiers, nil, nil
 }
 
-func (r *EventReconciler) requestNotify(event *corev1.Event, notify *emailv1.Notifier) error {
-	event = event.DeepCopy()
-	setNotifyLabel(event, notify)
+// getActiveSilences lists the Silences in the namespace and the ClusterNamespace, which apply now
+func (r *EventReconciler) getActiveSilences(namespace string, now time.Time) ([]emailv1.Silence, error) {
+	namespaces := []string{namespace}
+	if r.ClusterNamespace != "" && r.ClusterNamespace != namespace {
+		namespaces = append(namespaces, r.ClusterNamespace)
+	}
+
+	active := []emailv1.Silence{}
+	for _, ns := range namespaces {
+		silences := &emailv1.SilenceList{}
+		err := r.Client.List(ctx.TODO(), silences, client.InNamespace(ns))
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to list Silences")
+		}
+		active = append(active, silences.Active(now)...)
+	}
+	return active, nil
+}
+
+func silencesUseLabels(silences []emailv1.Silence) bool {
+	for _, silence := range silences {
+		if silence.Spec.Match.UsesLabels() {
+			return true
+		}
+	}
+	return false
+}
 
-	err := ctrl.SetControllerReference(notify, event, r.Scheme)
+// silencedBy tells why the Notifier must not notify about the Event, empty when it may
+func (r *EventReconciler) silencedBy(notifier *emailv1.Notifier, silence *emailv1.Silence, now time.Time) string {
+	if silence != nil {
+		return "Silence/" + silence.GetName()
+	}
+	quiet, err := notifier.Spec.QuietHours.IsActive(now)
 	if err != nil {
-		return errors.Wrap(err, "Failed to set Event referense to Notifier")
+		// Reported by the webhook, notify rather than drop the Event
+		r.Log.Info("Invalid quiet hours", "notifier", notifier.GetName(), "error", err.Error())
+	}
+	if quiet {
+		return "QuietHours"
+	}
+	return ""
+}
+
+// getClusterNotifiers lists the ClusterNotifiers selecting the namespace
+func (r *EventReconciler) getClusterNotifiers(namespace string) ([]emailv1.Notifier, error) {
+	notifiers := []emailv1.Notifier{}
+	if r.ClusterNamespace == "" {
+		return notifiers, nil
+	}
+
+	clusterNotifiers := &emailv1.ClusterNotifierList{}
+	err := r.Client.List(ctx.TODO(), clusterNotifiers)
+	if err != nil || len(clusterNotifiers.Items) == 0 {
+		return notifiers, err
 	}
 
-	return r.Update(ctx.TODO(), event)
+	var namespaceLabels map[string]string
+	if clusterNotifiers.UsesNamespaceLabels() {
+		ns := &corev1.Namespace{}
+		err = r.Get(ctx.TODO(), types.NamespacedName{Name: namespace}, ns)
+		if client.IgnoreNotFound(err) != nil {
+			return notifiers, errors.Wrapf(err, "Failed to get Namespace %s", namespace)
+		}
+		namespaceLabels = ns.GetLabels()
+	}
+
+	for i := range clusterNotifiers.Items {
+		clusterNotifier := &clusterNotifiers.Items[i]
+		selected, err := clusterNotifier.SelectsNamespace(namespaceLabels)
+		if err != nil {
+			// Reported by the webhook, don't block the other notifiers
+			r.Log.Info("Invalid namespace selector", "clusternotifier", clusterNotifier.GetName(), "error", err.Error())
+			continue
+		}
+		if selected {
+			notifiers = append(notifiers, *clusterNotifier.AsNotifier(r.ClusterNamespace))
+		}
+	}
+	return notifiers, nil
+}
+
+// getObjectLabels reads the labels of the involved object, which may be of any kind.
+// The object is read directly from the API server, so no informer is started for it.
+// Objects the manager may not read have no labels, label filters don't match them.
+func (r *EventReconciler) getObjectLabels(ref corev1.ObjectReference) (map[string]string, error) {
+	if ref.APIVersion == "" || ref.Kind == "" {
+		return nil, nil
+	}
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(ref.GroupVersionKind())
+	err := r.Get(ctx.TODO(), types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, object)
+	if k8serror.IsNotFound(err) || k8serror.IsForbidden(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get %s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+	}
+	return object.GetLabels(), nil
 }
 
-func setNotifyLabel(event *corev1.Event, notify *emailv1.Notifier) {
-	updatedLabels := make(map[string]string)
-	for label, value := range event.GetLabels() {
-		updatedLabels[label] = value
+// requestNotify records the Event for the Notifier, the Event itself is left untouched.
+// The record is owned by the Notifier, which is woken up by its creation.
+// New records carry the context of the involved object, resolved once per Event.
+// Repeated occurrences refresh the snapshot, when the Notifier may notify about them again
+// or has to know the warning recurs to resolve the incident.
+// Silenced records are suppressed by the Notifier, which counts them.
+// When the Notifier groups Events, new unsilenced records join an Incident, which silences all but its first record.
+func (r *EventReconciler) requestNotify(event *corev1.Event, notify *emailv1.Notifier, silenced string, resolver *contextResolver, now time.Time) error {
+	record := newNotificationRecord(notify, event)
+	record.Spec.Silenced = silenced
+	err := ctrl.SetControllerReference(notifierObject(notify).(metav1.Object), record, r.Scheme)
+	if err != nil {
+		return errors.Wrap(err, "Failed to set NotificationRecord reference to Notifier")
 	}
-	updatedLabels[notify.GetNotifyLabel()] = "true"
 
-	event.SetLabels(updatedLabels)
+	key := types.NamespacedName{Namespace: record.GetNamespace(), Name: record.GetName()}
+	existing := &emailv1.NotificationRecord{}
+	err = r.Get(ctx.TODO(), key, existing)
+	if k8serror.IsNotFound(err) {
+		record.Spec.Context, err = resolver.get()
+		if err != nil {
+			return err
+		}
+		if silenced == "" {
+			incident, notifies, err := r.joinIncident(event, notify, record.Spec.Context, now)
+			if err != nil {
+				return err
+			}
+			if incident != nil {
+				record.Spec.Incident = incident.GetName()
+				if !notifies {
+					record.Spec.Silenced = incidentSilencePrefix + incident.GetName()
+				}
+			}
+		}
+		err = r.Create(ctx.TODO(), record)
+		if !k8serror.IsAlreadyExists(err) {
+			return err
+		} else if !refreshesRecords(notify) {
+			return nil
+		}
+		err = r.Get(ctx.TODO(), key, existing)
+	}
+	if err != nil {
+		return err
+	}
+	if !refreshesRecords(notify) || existing.Spec.Event.Count >= record.Spec.Event.Count {
+		return nil
+	}
+	existing.Spec.Event = record.Spec.Event
+	existing.Spec.EventRef.ResourceVersion = record.Spec.EventRef.ResourceVersion
+	if !isGrouped(existing) {
+		// Grouped records stay silenced by their Incident
+		existing.Spec.Silenced = record.Spec.Silenced
+	}
+	return r.Update(ctx.TODO(), existing)
+}
+
+// refreshesRecords tells whether the repeated occurrences of an Event matter to the Notifier
+func refreshesRecords(notify *emailv1.Notifier) bool {
+	return notify.Spec.Renotify != nil || notify.Spec.Resolve != nil
 }
@@ -0,0 +1,63 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// failingReader fails every read with the given error
+type failingReader struct {
+	client.Client
+	err error
+}
+
+func (r *failingReader) Get(_ context.Context, _ types.NamespacedName, _ runtime.Object) error {
+	return r.err
+}
+
+var _ = Describe("Object labels", func() {
+	claim := corev1.ObjectReference{APIVersion: "v1", Kind: "PersistentVolumeClaim", Namespace: "apps", Name: "data"}
+
+	It("should read no labels from objects the manager may not read", func() {
+		forbidden := k8serror.NewForbidden(schema.GroupResource{Resource: "persistentvolumeclaims"}, "data", nil)
+		r := &EventReconciler{Client: &failingReader{err: forbidden}}
+		labels, err := r.getObjectLabels(claim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(BeNil())
+
+		r.Client = &failingReader{err: &meta.NoKindMatchError{GroupKind: schema.GroupKind{Kind: "PersistentVolumeClaim"}}}
+		labels, err = r.getObjectLabels(claim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(BeNil())
+	})
+
+	It("should fail on other read errors", func() {
+		r := &EventReconciler{Client: &failingReader{err: k8serror.NewServiceUnavailable("down")}}
+		_, err := r.getObjectLabels(claim)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,25 +1,55 @@
 package controllers
 
 import (
+	ctx "context"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	emailv1 "std/api/v1"
 )
 
+// kindsField indexes Notifiers by the kinds of objects they watch
+const kindsField = ".spec.kinds"
+
+// EventPredicate passes Events about kinds watched by any Notifier in the Event namespace, or any ClusterNotifier
 type EventPredicate struct {
 	predicate.Funcs
+	// Client reads the Notifiers from the cache, indexed by kindsIndex
+	Client client.Reader
+	// Cluster considers the ClusterNotifiers too, indexed by clusterKindsIndex
+	Cluster bool
+}
+
+func kindsIndex(obj runtime.Object) []string {
+	return obj.(*emailv1.Notifier).GetKinds()
+}
+
+func clusterKindsIndex(obj runtime.Object) []string {
+	return obj.(*emailv1.ClusterNotifier).AsNotifier("").GetKinds()
 }
 
 func (r EventPredicate) Create(e event.CreateEvent) bool {
 	event, cast := e.Object.(*corev1.Event)
 	if cast {
-		return event.InvolvedObject.Kind == "Pod"
+		return r.watched(event)
 	}
 	return false
 }
 
+// Update passes repeated occurrences, which Kubernetes counts on the existing Event
 func (r EventPredicate) Update(e event.UpdateEvent) bool {
-	return false
+	oldEvent, cast := e.ObjectOld.(*corev1.Event)
+	if !cast {
+		return false
+	}
+	newEvent, cast := e.ObjectNew.(*corev1.Event)
+	if !cast || newEvent.Count <= oldEvent.Count {
+		return false
+	}
+	return r.watched(newEvent)
 }
 
 func (r EventPredicate) Delete(e event.DeleteEvent) bool {
@@ -29,3 +59,28 @@ func (r EventPredicate) Delete(e event.DeleteEvent) bool {
 func (r EventPredicate) Generic(e event.GenericEvent) bool {
 	return false
 }
+
+// watched looks up Notifiers and ClusterNotifiers for the kind of the involved object, or any kind.
+// Namespace selectors are left to the reconciler.
+func (r EventPredicate) watched(event *corev1.Event) bool {
+	for _, kind := range []string{event.InvolvedObject.Kind, emailv1.AnyKind} {
+		notifiers := &emailv1.NotifierList{}
+		err := r.Client.List(ctx.TODO(), notifiers,
+			client.InNamespace(event.GetNamespace()),
+			client.MatchingField(kindsField, kind))
+		if err != nil || len(notifiers.Items) > 0 {
+			// On errors let the reconciler retry
+			return true
+		}
+
+		if !r.Cluster {
+			continue
+		}
+		clusterNotifiers := &emailv1.ClusterNotifierList{}
+		err = r.Client.List(ctx.TODO(), clusterNotifiers, client.MatchingField(kindsField, kind))
+		if err != nil || len(clusterNotifiers.Items) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	emailv1 "std/api/v1"
+)
+
+// kindsReader serves Notifiers and ClusterNotifiers by the kinds index, like the cache does
+type kindsReader struct {
+	notifiers        []emailv1.Notifier
+	clusterNotifiers []emailv1.ClusterNotifier
+}
+
+func (r *kindsReader) Get(_ context.Context, _ types.NamespacedName, _ runtime.Object) error {
+	return nil
+}
+
+func (r *kindsReader) List(_ context.Context, list runtime.Object, opts ...client.ListOptionFunc) error {
+	options := &client.ListOptions{}
+	options.ApplyOptions(opts)
+	kind, _ := options.FieldSelector.RequiresExactMatch(kindsField)
+
+	if clusterNotifiers, ok := list.(*emailv1.ClusterNotifierList); ok {
+		for _, notifier := range r.clusterNotifiers {
+			for _, indexed := range clusterKindsIndex(&notifier) {
+				if indexed == kind {
+					clusterNotifiers.Items = append(clusterNotifiers.Items, notifier)
+					break
+				}
+			}
+		}
+		return nil
+	}
+
+	notifiers := list.(*emailv1.NotifierList)
+	for _, notifier := range r.notifiers {
+		for _, indexed := range kindsIndex(&notifier) {
+			if indexed == kind && notifier.GetNamespace() == options.Namespace {
+				notifiers.Items = append(notifiers.Items, notifier)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+var _ = Describe("EventPredicate", func() {
+	var predicate EventPredicate
+
+	BeforeEach(func() {
+		nodes := newNotifier("nodes", "nodes@example.com")
+		nodes.Spec.Kinds = []string{"Node"}
+		predicate = EventPredicate{Client: &kindsReader{notifiers: []emailv1.Notifier{
+			*newNotifier("pods", "pods@example.com"),
+			*nodes,
+		}}}
+	})
+
+	It("should pass created Events about watched kinds", func() {
+		pod := newWarningEvent("pod.backoff", "BackOff", "Pod", "pod")
+		Expect(predicate.Create(event.CreateEvent{Meta: pod, Object: pod})).To(BeTrue())
+
+		node := newWarningEvent("node.rebooted", "Rebooted", "Node", "node")
+		Expect(predicate.Create(event.CreateEvent{Meta: node, Object: node})).To(BeTrue())
+
+		job := newWarningEvent("job.failed", "BackoffLimitExceeded", "Job", "job")
+		Expect(predicate.Create(event.CreateEvent{Meta: job, Object: job})).To(BeFalse())
+
+		job.Namespace = "other"
+		job.InvolvedObject.Kind = "Pod"
+		Expect(predicate.Create(event.CreateEvent{Meta: job, Object: job})).To(BeFalse())
+	})
+
+	It("should pass updates counting another occurrence", func() {
+		old := newWarningEvent("pod.backoff", "BackOff", "Pod", "pod")
+		old.Count = 3
+		updated := old.DeepCopy()
+		Expect(predicate.Update(event.UpdateEvent{
+			MetaOld: old, ObjectOld: old, MetaNew: updated, ObjectNew: updated,
+		})).To(BeFalse())
+
+		updated.Count = 4
+		Expect(predicate.Update(event.UpdateEvent{
+			MetaOld: old, ObjectOld: old, MetaNew: updated, ObjectNew: updated,
+		})).To(BeTrue())
+
+		updated.InvolvedObject.Kind = "Job"
+		Expect(predicate.Update(event.UpdateEvent{
+			MetaOld: old, ObjectOld: old, MetaNew: updated, ObjectNew: updated,
+		})).To(BeFalse())
+	})
+	It("should pass Events about kinds watched by a ClusterNotifier", func() {
+		reader := predicate.Client.(*kindsReader)
+		reader.clusterNotifiers = []emailv1.ClusterNotifier{{
+			ObjectMeta: metav1.ObjectMeta{Name: "jobs"},
+			Spec: emailv1.ClusterNotifierSpec{
+				NotifierSpec: emailv1.NotifierSpec{Email: "jobs@example.com", Kinds: []string{"Job"}},
+			},
+		}}
+
+		job := newWarningEvent("job.failed", "BackoffLimitExceeded", "Job", "job")
+		job.Namespace = "other"
+		Expect(predicate.Create(event.CreateEvent{Meta: job, Object: job})).To(BeFalse())
+
+		predicate.Cluster = true
+		Expect(predicate.Create(event.CreateEvent{Meta: job, Object: job})).To(BeTrue())
+
+		job.InvolvedObject.Kind = "CronJob"
+		Expect(predicate.Create(event.CreateEvent{Meta: job, Object: job})).To(BeFalse())
+	})
+})
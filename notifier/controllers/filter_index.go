@@ -0,0 +1,119 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	emailv1 "std/api/v1"
+)
+
+// FilterIndex caches the compiled filters of every Notifier and ClusterNotifier.
+// Entries are compiled once per generation, so an update invalidates them.
+// A nil FilterIndex compiles the filters on every call.
+type FilterIndex struct {
+	lock    sync.RWMutex
+	entries map[string]*filterEntry
+}
+
+type filterEntry struct {
+	uid        types.UID
+	generation int64
+	compiled   *emailv1.CompiledNotifier
+	err        error
+}
+
+func NewFilterIndex() *FilterIndex {
+	return &FilterIndex{entries: map[string]*filterEntry{}}
+}
+
+// filterKey is namespace/name of a Notifier, and ClusterNotifier/name of a ClusterNotifier
+func filterKey(notifier *emailv1.Notifier) string {
+	if notifier.IsClusterNotifier() {
+		return recordNotifierKey(notifier.Kind, notifier.GetName())
+	}
+	return types.NamespacedName{Namespace: notifier.GetNamespace(), Name: notifier.GetName()}.String()
+}
+
+// Get returns the compiled Notifier, compiling it when it is new or has changed since
+func (i *FilterIndex) Get(notifier *emailv1.Notifier) (*emailv1.CompiledNotifier, error) {
+	if i == nil {
+		return notifier.Compile()
+	}
+
+	key := filterKey(notifier)
+	i.lock.RLock()
+	entry, found := i.entries[key]
+	i.lock.RUnlock()
+	if found && entry.uid == notifier.GetUID() && entry.generation == notifier.GetGeneration() {
+		return entry.compiled, entry.err
+	}
+
+	compiled, err := notifier.Compile()
+	entry = &filterEntry{uid: notifier.GetUID(), generation: notifier.GetGeneration(), compiled: compiled, err: err}
+	i.lock.Lock()
+	// Keep the newer entry, if an outdated Notifier from the cache was compiled concurrently
+	if current, found := i.entries[key]; !found || current.uid != entry.uid || current.generation <= entry.generation {
+		i.entries[key] = entry
+	}
+	i.lock.Unlock()
+	return compiled, err
+}
+
+// Forget drops the entry of a deleted Notifier
+func (i *FilterIndex) Forget(key string) {
+	if i == nil {
+		return
+	}
+	i.lock.Lock()
+	delete(i.entries, key)
+	i.lock.Unlock()
+}
+
+// Len is the number of compiled Notifiers
+func (i *FilterIndex) Len() int {
+	if i == nil {
+		return 0
+	}
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	return len(i.entries)
+}
+
+// Matching returns the Notifiers matching the input. Notifiers with invalid filters never match,
+// they are reported in their status and must not hold back the others.
+func (i *FilterIndex) Matching(notifiers []emailv1.Notifier, input emailv1.FilterInput) []emailv1.Notifier {
+	matchedNotifiers := []emailv1.Notifier{}
+	for _, notifier := range notifiers {
+		compiled, err := i.Get(&notifier)
+		if err == nil && compiled.Match(input) {
+			matchedNotifiers = append(matchedNotifiers, notifier)
+		}
+	}
+	return matchedNotifiers
+}
+
+// UsesLabels reports whether any Notifier needs the involved object labels
+func (i *FilterIndex) UsesLabels(notifiers []emailv1.Notifier) bool {
+	for _, notifier := range notifiers {
+		compiled, err := i.Get(&notifier)
+		if err == nil && compiled.UsesLabels() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	emailv1 "std/api/v1"
+)
+
+// manyNotifiers builds Notifiers with a mix of filters, kinds and match blocks, matching a BackOff about a Pod now and then
+func manyNotifiers(count int) []emailv1.Notifier {
+	notifiers := []emailv1.Notifier{}
+	for i := 0; i < count; i++ {
+		notifier := emailv1.Notifier{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       fmt.Sprintf("notifier-%d", i),
+				Namespace:  "default",
+				UID:        types.UID(fmt.Sprintf("uid-%d", i)),
+				Generation: 1,
+			},
+			Spec: emailv1.NotifierSpec{
+				Filters: []string{fmt.Sprintf("^(Back|Failed)[A-Z][a-z]+%d?$", i%10)},
+			},
+		}
+		switch i % 4 {
+		case 1:
+			notifier.Spec.Kinds = []string{"Deployment", "StatefulSet"}
+		case 2:
+			notifier.Spec.Match = &emailv1.EventFilter{
+				All:  []emailv1.EventMatcher{{Name: &emailv1.StringMatch{Glob: fmt.Sprintf("api-%d-*", i)}}},
+				None: []emailv1.EventMatcher{{Message: &emailv1.StringMatch{Regex: "(?i)probe.*timeout"}}},
+			}
+		case 3:
+			notifier.Spec.Match = &emailv1.EventFilter{
+				Any: []emailv1.EventMatcher{
+					{Reason: &emailv1.StringMatch{Exact: "BackOff"}},
+					{Message: &emailv1.StringMatch{Regex: "OOM.*killed"}},
+				},
+			}
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers
+}
+
+var _ = Describe("FilterIndex", func() {
+	var (
+		index *FilterIndex
+		input emailv1.FilterInput
+	)
+
+	BeforeEach(func() {
+		index = NewFilterIndex()
+		input = emailv1.FilterInput{Event: newWarningEvent("api.1", "BackOff", "Pod", "api-2-x2x8z")}
+	})
+
+	It("should match like the Notifiers themselves", func() {
+		notifiers := manyNotifiers(200)
+		expected := emailv1.NotifierList{Items: notifiers}.Matching(input)
+		Expect(expected).NotTo(BeEmpty())
+		Expect(index.Matching(notifiers, input)).To(Equal(expected))
+		Expect(index.Len()).To(Equal(200))
+
+		// Served from the cache the second time
+		Expect(index.Matching(notifiers, input)).To(Equal(expected))
+		Expect(index.Len()).To(Equal(200))
+	})
+
+	It("should compile once per generation", func() {
+		notifier := manyNotifiers(1)[0]
+		compiled, err := index.Get(&notifier)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(compiled.Match(input)).To(BeTrue())
+		Expect(index.Get(&notifier)).To(BeIdenticalTo(compiled))
+
+		notifier.Spec.Filters = []string{"OOM"}
+		Expect(index.Get(&notifier)).To(BeIdenticalTo(compiled), "the generation hasn't changed")
+
+		notifier.Generation++
+		updated, err := index.Get(&notifier)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated).NotTo(BeIdenticalTo(compiled))
+		Expect(updated.Match(input)).To(BeFalse())
+
+		recreated := manyNotifiers(1)[0]
+		recreated.UID = "recreated"
+		Expect(index.Get(&recreated)).NotTo(BeIdenticalTo(updated))
+		Expect(index.Len()).To(Equal(1))
+	})
+
+	It("should not let an outdated Notifier replace a newer one", func() {
+		notifier := manyNotifiers(1)[0]
+		notifier.Generation = 2
+		compiled, err := index.Get(&notifier)
+		Expect(err).NotTo(HaveOccurred())
+
+		outdated := manyNotifiers(1)[0]
+		outdated.Spec.Filters = []string{"OOM"}
+		Expect(index.Get(&outdated)).NotTo(BeIdenticalTo(compiled))
+		Expect(index.Get(&notifier)).To(BeIdenticalTo(compiled))
+	})
+
+	It("should skip Notifiers with invalid filters", func() {
+		notifiers := manyNotifiers(3)
+		notifiers[0].Spec.Filters = []string{"Back(Off"}
+		_, err := index.Get(&notifiers[0])
+		Expect(err).To(MatchError(ContainSubstring(`invalid filter "Back(Off"`)))
+
+		matched := index.Matching(notifiers, input)
+		Expect(matched).To(HaveLen(1))
+		Expect(matched[0].GetName()).To(Equal("notifier-2"))
+	})
+
+	It("should tell ClusterNotifiers apart and forget deleted Notifiers", func() {
+		notifier := manyNotifiers(1)[0]
+		clusterNotifier := emailv1.ClusterNotifier{ObjectMeta: notifier.ObjectMeta}
+		clusterNotifier.Namespace = ""
+		clusterNotifier.Spec.Filters = []string{"OOM"}
+		view := clusterNotifier.AsNotifier("default")
+
+		Expect(index.Matching([]emailv1.Notifier{notifier, *view}, input)).To(HaveLen(1))
+		Expect(index.Len()).To(Equal(2))
+
+		index.Forget(recordNotifierKey(emailv1.ClusterNotifierKind, view.GetName()))
+		Expect(index.Len()).To(Equal(1))
+		index.Forget(types.NamespacedName{Namespace: "default", Name: notifier.GetName()}.String())
+		Expect(index.Len()).To(Equal(0))
+	})
+
+	It("should compile on every call when nil", func() {
+		var index *FilterIndex
+		notifiers := manyNotifiers(10)
+		Expect(index.Matching(notifiers, input)).To(Equal(emailv1.NotifierList{Items: notifiers}.Matching(input)))
+		Expect(index.UsesLabels(notifiers)).To(BeFalse())
+		index.Forget("default/notifier-0")
+		Expect(index.Len()).To(BeZero())
+	})
+})
+
+// Run with: go test ./controllers/ -run '^$' -bench Matching
+func benchmarkMatching(b *testing.B, count int, match func(notifiers []emailv1.Notifier, input emailv1.FilterInput) []emailv1.Notifier) {
+	notifiers := manyNotifiers(count)
+	input := emailv1.FilterInput{Event: newWarningEvent("api.1", "BackOff", "Pod", "api-2-x2x8z")}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		match(notifiers, input)
+	}
+}
+
+func uncompiledMatching(notifiers []emailv1.Notifier, input emailv1.FilterInput) []emailv1.Notifier {
+	return emailv1.NotifierList{Items: notifiers}.Matching(input)
+}
+
+func BenchmarkUncompiledMatching1000(b *testing.B) { benchmarkMatching(b, 1000, uncompiledMatching) }
+func BenchmarkUncompiledMatching5000(b *testing.B) { benchmarkMatching(b, 5000, uncompiledMatching) }
+func BenchmarkFilterIndexMatching1000(b *testing.B) {
+	benchmarkMatching(b, 1000, NewFilterIndex().Matching)
+}
+func BenchmarkFilterIndexMatching5000(b *testing.B) {
+	benchmarkMatching(b, 5000, NewFilterIndex().Matching)
+}
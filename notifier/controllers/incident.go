@@ -0,0 +1,122 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	emailv1 "std/api/v1"
+)
+
+// incidentSilencePrefix marks the records silenced by the Incident they were grouped into
+const incidentSilencePrefix = "Incident/"
+
+// incidentGroup is what the Events about the object have in common: the workload owning it, or else its node.
+// Objects without either are not grouped.
+func incidentGroup(ref corev1.ObjectReference, context *emailv1.ObjectContext) (emailv1.IncidentSpec, bool) {
+	if workload := context.Workload(); workload != nil {
+		return emailv1.IncidentSpec{Namespace: ref.Namespace, Workload: workload.DeepCopy()}, true
+	}
+	if ref.Kind == "Node" {
+		return emailv1.IncidentSpec{NodeName: ref.Name}, true
+	}
+	if context != nil && context.NodeName != "" {
+		return emailv1.IncidentSpec{NodeName: context.NodeName}, true
+	}
+	return emailv1.IncidentSpec{}, false
+}
+
+// incidentName is the stable ID of the Incident of the group, for the Notifier
+func incidentName(notifier *emailv1.Notifier, group emailv1.IncidentSpec) string {
+	key := "Node/" + group.NodeName
+	if group.Workload != nil {
+		key = strings.Join([]string{"Workload", group.Namespace, group.Workload.APIVersion, group.Workload.Kind, group.Workload.Name}, "/")
+	}
+	if notifier.IsClusterNotifier() {
+		key = emailv1.ClusterNotifierKind + "/" + key
+	}
+	hash := sha256.Sum256([]byte(key))
+	return namePrefix(notifier.GetName()) + "-" + hex.EncodeToString(hash[:])[:10]
+}
+
+// isGrouped tells whether the record is silenced by its Incident
+func isGrouped(record *emailv1.NotificationRecord) bool {
+	return record.Spec.Incident != "" && record.Spec.Silenced == incidentSilencePrefix+record.Spec.Incident
+}
+
+// joinIncident groups the Event into the Incident of its workload or node, creating the Incident on its first Event.
+// It returns nil when the Notifier doesn't group Events or there is nothing to group by,
+// and tells whether the Event is the one notifying about the Incident.
+// A stale Incident from the cache fails to update with a conflict, so concurrent Events don't open it twice.
+func (r *EventReconciler) joinIncident(event *corev1.Event, notify *emailv1.Notifier, context *emailv1.ObjectContext, now time.Time) (*emailv1.Incident, bool, error) {
+	if notify.Spec.Grouping == nil {
+		return nil, false, nil
+	}
+	group, found := incidentGroup(event.InvolvedObject, context)
+	if !found {
+		return nil, false, nil
+	}
+	group.Notifier = notify.GetName()
+	if notify.IsClusterNotifier() {
+		group.NotifierKind = emailv1.ClusterNotifierKind
+	}
+	group.Window = metav1.Duration{Duration: notify.Spec.Grouping.GetWindow()}
+
+	incident := &emailv1.Incident{}
+	key := types.NamespacedName{Namespace: notify.GetNamespace(), Name: incidentName(notify, group)}
+	err := r.Get(ctx.TODO(), key, incident)
+	if k8serror.IsNotFound(err) {
+		incident = &emailv1.Incident{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       group,
+		}
+		err = ctrl.SetControllerReference(notifierObject(notify).(metav1.Object), incident, r.Scheme)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "Failed to set Incident reference to Notifier")
+		}
+		err = r.Create(ctx.TODO(), incident)
+	} else if err == nil && !incident.IsOpen(now) && incident.Spec.Window != group.Window {
+		// Reopened with the current window
+		incident.Spec.Window = group.Window
+		err = r.Update(ctx.TODO(), incident)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := recordName(notify, event)
+	if !incident.IsOpen(now) {
+		incident.AddEvent(event.Reason, event.InvolvedObject, now)
+		incident.Status.Record = record
+	} else if incident.Status.Record != record {
+		// The notifying Event is retried when its record failed to be created, don't count it twice
+		incident.AddEvent(event.Reason, event.InvolvedObject, now)
+	}
+	if err := r.Status().Update(ctx.TODO(), incident); err != nil {
+		return nil, false, err
+	}
+	return incident, incident.Status.Record == record, nil
+}
@@ -0,0 +1,94 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"time"
+
+	"github.com/go-logr/logr"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	emailv1 "std/api/v1"
+)
+
+// IncidentReconciler closes the Incidents once their window passed, and deletes them after the TTL.
+// Events join the Incidents in the EventReconciler.
+type IncidentReconciler struct {
+	client.Client
+	Log logr.Logger
+
+	// TTL is how long closed Incidents are kept, zero keeps them forever
+	TTL time.Duration
+}
+
+// +kubebuilder:rbac:groups=email.notify.io,resources=incidents,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=email.notify.io,resources=incidents/status,verbs=get;update;patch
+
+func (r *IncidentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("incident", req.NamespacedName)
+
+	incident := &emailv1.Incident{}
+	err := r.Get(ctx.TODO(), req.NamespacedName, incident)
+	if k8serror.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	} else if err != nil {
+		log.Error(err, "Can't get incident")
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	now := time.Now()
+	switch incident.Status.State {
+	case emailv1.IncidentOpen:
+		if wait := incident.ClosesIn(now); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+		closed := metav1.NewTime(incident.Status.LastEventTime.Add(incident.Spec.Window.Duration))
+		incident.Status.State = emailv1.IncidentClosed
+		incident.Status.ClosedTime = &closed
+		err = r.Status().Update(ctx.TODO(), incident)
+		if k8serror.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		} else if err != nil {
+			log.Error(err, "Failed to close incident")
+			return ctrl.Result{Requeue: true}, nil
+		}
+		log.Info("Incident closed", "group", incident.Group(), "events", incident.Status.EventCount)
+		return ctrl.Result{RequeueAfter: r.TTL}, nil
+
+	case emailv1.IncidentClosed:
+		if r.TTL == 0 || incident.Status.ClosedTime == nil {
+			return ctrl.Result{}, nil
+		}
+		if remaining := r.TTL - now.Sub(incident.Status.ClosedTime.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+		err = r.Delete(ctx.TODO(), incident)
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete expired incident")
+			return ctrl.Result{Requeue: true}, nil
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *IncidentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&emailv1.Incident{}).
+		Complete(r)
+}
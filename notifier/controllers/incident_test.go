@@ -0,0 +1,184 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("incident grouping", func() {
+	var (
+		now        time.Time
+		notifier   *emailv1.Notifier
+		reconciler *EventReconciler
+	)
+
+	web := &emailv1.ObjectContext{
+		Owners: []emailv1.WorkloadReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7c9"},
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+		},
+		NodeName: "node-1",
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+		notifier = &emailv1.Notifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps", UID: "uid-team"},
+			Spec:       emailv1.NotifierSpec{Grouping: &emailv1.GroupingPolicy{}},
+		}
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		reconciler = &EventReconciler{
+			Client: fake.NewFakeClientWithScheme(s),
+			Log:    ctrl.Log.WithName("incident"),
+			Scheme: s,
+		}
+	})
+
+	podEvent := func(pod, reason string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: pod + ".15c3d6e1b2a4f7c8", Namespace: "apps", UID: types.UID("uid-" + pod)},
+			Type:           corev1.EventTypeWarning,
+			Reason:         reason,
+			InvolvedObject: podRef(pod),
+		}
+	}
+
+	It("should group by the workload, or else the node", func() {
+		group, ok := incidentGroup(podRef("web-7c9-x2k"), web)
+		Expect(ok).To(BeTrue())
+		Expect(group).To(Equal(emailv1.IncidentSpec{
+			Namespace: "apps",
+			Workload:  &emailv1.WorkloadReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+		}))
+
+		group, _ = incidentGroup(podRef("static"), &emailv1.ObjectContext{NodeName: "node-1"})
+		Expect(group).To(Equal(emailv1.IncidentSpec{NodeName: "node-1"}))
+		group, _ = incidentGroup(corev1.ObjectReference{Kind: "Node", Name: "node-2"}, nil)
+		Expect(group).To(Equal(emailv1.IncidentSpec{NodeName: "node-2"}))
+
+		_, ok = incidentGroup(corev1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "apps", Name: "data"}, nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should name the incident after the group", func() {
+		workload, _ := incidentGroup(podRef("web-7c9-x2k"), web)
+		other, _ := incidentGroup(podRef("web-7c9-k8p"), web)
+		node, _ := incidentGroup(podRef("static"), &emailv1.ObjectContext{NodeName: "node-1"})
+		Expect(incidentName(notifier, workload)).To(Equal(incidentName(notifier, other)))
+		Expect(incidentName(notifier, workload)).To(HavePrefix("team-"))
+		Expect(incidentName(notifier, workload)).NotTo(Equal(incidentName(notifier, node)))
+
+		cluster := &emailv1.Notifier{
+			TypeMeta:   metav1.TypeMeta{Kind: emailv1.ClusterNotifierKind},
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"},
+		}
+		Expect(incidentName(cluster, workload)).NotTo(Equal(incidentName(notifier, workload)))
+	})
+
+	It("should notify once about the crashing replicas", func() {
+		var records []string
+		for i := 0; i < 20; i++ {
+			event := podEvent(fmt.Sprintf("web-7c9-%02d", i), "BackOff")
+			incident, notifies, err := reconciler.joinIncident(event, notifier, web, now.Add(time.Duration(i)*time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notifies).To(Equal(i == 0))
+			if notifies {
+				records = append(records, recordName(notifier, event))
+			}
+			Expect(incident.GetName()).To(Equal(incidentName(notifier, incident.Spec)))
+		}
+
+		incidents := &emailv1.IncidentList{}
+		Expect(reconciler.List(context.TODO(), incidents)).To(Succeed())
+		Expect(incidents.Items).To(HaveLen(1))
+		incident := incidents.Items[0]
+		Expect(incident.Spec.Notifier).To(Equal("team"))
+		Expect(incident.Spec.Window.Duration).To(Equal(emailv1.DefaultGroupingWindow))
+		Expect(incident.OwnerReferences).To(HaveLen(1))
+		Expect(incident.Status.Record).To(Equal(records[0]))
+		Expect(incident.Status.EventCount).To(BeEquivalentTo(20))
+		Expect(incident.Status.ObjectCount).To(BeEquivalentTo(20))
+		Expect(incident.Status.Reasons).To(Equal([]string{"BackOff"}))
+	})
+
+	It("should not count the notifying Event twice when retried", func() {
+		event := podEvent("web-7c9-x2k", "BackOff")
+		_, _, err := reconciler.joinIncident(event, notifier, web, now)
+		Expect(err).NotTo(HaveOccurred())
+		incident, notifies, err := reconciler.joinIncident(event, notifier, web, now.Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifies).To(BeTrue())
+		Expect(incident.Status.EventCount).To(BeEquivalentTo(1))
+	})
+
+	It("should open a new incident after the window", func() {
+		notifier.Spec.Grouping.Window = &metav1.Duration{Duration: time.Minute}
+		first := podEvent("web-7c9-x2k", "BackOff")
+		_, _, err := reconciler.joinIncident(first, notifier, web, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		notifier.Spec.Grouping.Window = &metav1.Duration{Duration: 2 * time.Minute}
+		later := podEvent("web-7c9-k8p", "Unhealthy")
+		incident, notifies, err := reconciler.joinIncident(later, notifier, web, now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifies).To(BeTrue())
+		Expect(incident.Status.Record).To(Equal(recordName(notifier, later)))
+		Expect(incident.Status.EventCount).To(BeEquivalentTo(1))
+		Expect(incident.Spec.Window.Duration).To(Equal(2 * time.Minute))
+	})
+
+	It("should count the grouped records apart from the suppressed ones", func() {
+		grouped := newNotificationRecord(notifier, podEvent("web-7c9-k8p", "BackOff"))
+		grouped.Spec.Incident = "team-8d41c0e97a"
+		grouped.Spec.Silenced = incidentSilencePrefix + grouped.Spec.Incident
+		silenced := newNotificationRecord(notifier, podEvent("web-7c9-x2k", "BackOff"))
+		silenced.Spec.Silenced = "QuietHours"
+
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(s, grouped, silenced)}
+		_, err := r.suppressSilenced(notifier, []emailv1.NotificationRecord{*grouped, *silenced})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notifier.Status.GroupedCount).To(BeEquivalentTo(1))
+		Expect(notifier.Status.SuppressedCount).To(BeEquivalentTo(1))
+	})
+
+	It("should not group without a grouping policy or a group", func() {
+		incident, _, err := reconciler.joinIncident(podEvent("data", "FailedMount"), notifier, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incident).To(BeNil())
+
+		notifier.Spec.Grouping = nil
+		incident, _, err = reconciler.joinIncident(podEvent("web-7c9-x2k", "BackOff"), notifier, web, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incident).To(BeNil())
+	})
+})
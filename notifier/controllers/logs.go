@@ -0,0 +1,153 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	emailv1 "std/api/v1"
+)
+
+// ContainerLogs are the last lines the failing container logged before it was restarted
+type ContainerLogs struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Lines     string `json:"lines"`
+	// Truncated is set when the oldest lines were cut to the size limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// LogFetcher reads container logs, tests replace it with canned logs
+type LogFetcher interface {
+	// PreviousLogs returns the last tailLines of the logs of the previous container instance.
+	// Logs longer than limitBytes may be cut, the newest bytes are kept.
+	PreviousLogs(namespace, pod, container string, tailLines, limitBytes int64) ([]byte, error)
+}
+
+// maxLogRequestBytes bounds the logs read from the API server, which cuts the newest lines when limiting bytes
+const maxLogRequestBytes = 1024 * 1024
+
+// podLogFetcher reads the logs through the log subresource of Pods
+type podLogFetcher struct {
+	pods corev1client.PodsGetter
+}
+
+// NewLogFetcher reads container logs from the API server
+func NewLogFetcher(config *rest.Config) (LogFetcher, error) {
+	client, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create the Pods client")
+	}
+	return &podLogFetcher{pods: client}, nil
+}
+
+func (f *podLogFetcher) PreviousLogs(namespace, pod, container string, tailLines, limitBytes int64) ([]byte, error) {
+	requestBytes := int64(maxLogRequestBytes)
+	options := &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   true,
+		TailLines:  &tailLines,
+		LimitBytes: &requestBytes,
+	}
+	stream, err := f.pods.Pods(namespace).GetLogs(pod, options).Stream()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get the logs of %s/%s container %s", namespace, pod, container)
+	}
+	defer stream.Close()
+	logs, err := ioutil.ReadAll(io.LimitReader(stream, requestBytes))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the logs of %s/%s container %s", namespace, pod, container)
+	}
+	// Keep a byte over the limit, so the caller knows the logs were cut
+	if int64(len(logs)) > limitBytes+1 {
+		logs = logs[int64(len(logs))-limitBytes-1:]
+	}
+	return logs, nil
+}
+
+// containerFieldPath is the field path kubelet sets on Events about a container, like spec.containers{app}
+var containerFieldPath = regexp.MustCompile(`^spec\.(?:initContainers|containers)\{(.+)\}$`)
+
+// failingContainer is the container the Event is about, or else the most restarted container of the Pod
+func failingContainer(event *corev1.Event, context *emailv1.ObjectContext) string {
+	if match := containerFieldPath.FindStringSubmatch(event.InvolvedObject.FieldPath); match != nil {
+		return match[1]
+	}
+	if context == nil {
+		return ""
+	}
+	name, restarts := "", int32(0)
+	for _, container := range context.Containers {
+		if container.RestartCount > restarts {
+			name, restarts = container.Name, container.RestartCount
+		}
+	}
+	if name == "" && len(context.Containers) == 1 {
+		name = context.Containers[0].Name
+	}
+	return name
+}
+
+// fetchLogs reads the previous logs of the failing container, when the policy asks for them.
+// Logs beyond the limit are cut at a line boundary, keeping the most recent lines.
+func fetchLogs(fetcher LogFetcher, policy *emailv1.LogsPolicy, event *corev1.Event, context *emailv1.ObjectContext) (*ContainerLogs, error) {
+	ref := event.InvolvedObject
+	if fetcher == nil || ref.Kind != "Pod" || !policy.AppliesTo(event.Reason) {
+		return nil, nil
+	}
+	container := failingContainer(event, context)
+	if container == "" {
+		return nil, nil
+	}
+
+	limit := policy.GetLimitBytes()
+	lines, err := fetcher.PreviousLogs(ref.Namespace, ref.Name, container, policy.GetTailLines(), limit)
+	if err != nil {
+		return nil, err
+	}
+	logs := &ContainerLogs{Pod: referenceName(ref), Container: container}
+	if int64(len(lines)) > limit {
+		lines = lines[int64(len(lines))-limit:]
+		if i := bytes.IndexByte(lines, '\n'); i >= 0 && i < len(lines)-1 {
+			lines = lines[i+1:]
+		}
+		logs.Truncated = true
+	}
+	logs.Lines = string(bytes.TrimRight(lines, "\n"))
+	if logs.Lines == "" {
+		return nil, nil
+	}
+	return logs, nil
+}
+
+// logsText appends the logs to plain text bodies
+func logsText(logs *ContainerLogs) string {
+	if logs == nil {
+		return ""
+	}
+	text := "\nPrevious logs of container " + logs.Container
+	if logs.Truncated {
+		text += " (truncated)"
+	}
+	return text + ":\n" + logs.Lines + "\n"
+}
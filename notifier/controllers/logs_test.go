@@ -0,0 +1,151 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	emailv1 "std/api/v1"
+)
+
+// fakeLogFetcher serves canned container logs, keyed by namespace/pod/container
+type fakeLogFetcher struct {
+	mu       sync.Mutex
+	logs     map[string]string
+	requests []string
+}
+
+func newFakeLogFetcher() *fakeLogFetcher {
+	return &fakeLogFetcher{logs: map[string]string{}}
+}
+
+func (f *fakeLogFetcher) Set(namespace, pod, container, logs string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs[namespace+"/"+pod+"/"+container] = logs
+}
+
+func (f *fakeLogFetcher) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.requests...)
+}
+
+// PreviousLogs tails the canned logs like the API server would, failing for unknown containers
+func (f *fakeLogFetcher) PreviousLogs(namespace, pod, container string, tailLines, limitBytes int64) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := namespace + "/" + pod + "/" + container
+	f.requests = append(f.requests, key)
+	logs, found := f.logs[key]
+	if !found {
+		return nil, errors.Errorf("previous terminated container %q in pod %q not found", container, pod)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(logs, "\n"), "\n")
+	if int64(len(lines)) > tailLines {
+		lines = lines[int64(len(lines))-tailLines:]
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+var _ = Describe("fetchLogs", func() {
+	var (
+		fetcher *fakeLogFetcher
+		policy  *emailv1.LogsPolicy
+		event   *corev1.Event
+	)
+
+	BeforeEach(func() {
+		fetcher = newFakeLogFetcher()
+		fetcher.Set("apps", "web-1", "app", "starting\nlistening on :8080\npanic: nil map\n")
+		policy = &emailv1.LogsPolicy{}
+		event = &corev1.Event{
+			InvolvedObject: corev1.ObjectReference{
+				Kind:      "Pod",
+				Namespace: "apps",
+				Name:      "web-1",
+				FieldPath: "spec.containers{app}",
+			},
+			Reason: "BackOff",
+		}
+	})
+
+	It("should attach the previous logs of the container in the field path", func() {
+		logs, err := fetchLogs(fetcher, policy, event, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs).To(Equal(&ContainerLogs{
+			Pod:       "apps/web-1",
+			Container: "app",
+			Lines:     "starting\nlistening on :8080\npanic: nil map",
+		}))
+		Expect(logsText(logs)).To(Equal("\nPrevious logs of container app:\nstarting\nlistening on :8080\npanic: nil map\n"))
+	})
+
+	It("should tail the configured number of lines", func() {
+		policy.TailLines = 1
+		logs, err := fetchLogs(fetcher, policy, event, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs.Lines).To(Equal("panic: nil map"))
+	})
+
+	It("should cut the oldest lines over the size limit", func() {
+		policy.LimitBytes = 30
+		logs, err := fetchLogs(fetcher, policy, event, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs.Lines).To(Equal("panic: nil map"))
+		Expect(logs.Truncated).To(BeTrue())
+		Expect(logsText(logs)).To(HavePrefix("\nPrevious logs of container app (truncated):\n"))
+	})
+
+	It("should pick the most restarted container without a field path", func() {
+		event.InvolvedObject.FieldPath = ""
+		context := &emailv1.ObjectContext{Containers: []emailv1.ContainerContext{
+			{Name: "sidecar", RestartCount: 1},
+			{Name: "app", RestartCount: 7},
+		}}
+		logs, err := fetchLogs(fetcher, policy, event, context)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs.Container).To(Equal("app"))
+
+		Expect(fetchLogs(fetcher, policy, event, nil)).To(BeNil())
+	})
+
+	It("should only attach logs when the policy asks for them", func() {
+		Expect(fetchLogs(fetcher, nil, event, nil)).To(BeNil())
+		Expect(fetchLogs(nil, policy, event, nil)).To(BeNil())
+
+		event.Reason = "FailedMount"
+		Expect(fetchLogs(fetcher, policy, event, nil)).To(BeNil())
+		policy.Reasons = []string{"FailedMount"}
+		Expect(fetchLogs(fetcher, policy, event, nil)).NotTo(BeNil())
+
+		event.InvolvedObject.Kind = "Node"
+		Expect(fetchLogs(fetcher, policy, event, nil)).To(BeNil())
+		Expect(fetcher.Requests()).To(HaveLen(1))
+	})
+
+	It("should fail when the logs are missing", func() {
+		event.InvolvedObject.Name = "web-2"
+		_, err := fetchLogs(fetcher, policy, event, nil)
+		Expect(err).To(MatchError(`previous terminated container "app" in pod "web-2" not found`))
+	})
+})
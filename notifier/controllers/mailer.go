@@ -0,0 +1,279 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TLSMode selects how the connection to the SMTP server is secured
+type TLSMode string
+
+const (
+	// TLSNone sends everything in plain text
+	TLSNone TLSMode = "none"
+	// TLSStartTLS upgrades a plain connection with the STARTTLS command
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit opens a TLS connection right away (usually port 465)
+	TLSImplicit TLSMode = "tls"
+)
+
+// AuthMethod selects the SMTP AUTH mechanism
+type AuthMethod string
+
+const (
+	// AuthNone skips authentication
+	AuthNone AuthMethod = "none"
+	// AuthPlain uses the PLAIN mechanism
+	AuthPlain AuthMethod = "plain"
+	// AuthLogin uses the LOGIN mechanism
+	AuthLogin AuthMethod = "login"
+)
+
+// DefaultSMTPTimeout bounds the whole SMTP conversation
+const DefaultSMTPTimeout = 30 * time.Second
+
+// SMTPConfig describes the mail relay used for delivering notifications
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	TLS                TLSMode
+	Auth               AuthMethod
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+// Address returns host:port of the SMTP server
+func (c SMTPConfig) Address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// Validate checks that the configuration is usable for sending mail
+func (c SMTPConfig) Validate() error {
+	if c.Host == "" {
+		return errors.New("SMTP host is not set")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return errors.Errorf("invalid SMTP port %d", c.Port)
+	}
+	if c.From == "" {
+		return errors.New("SMTP from address is not set")
+	}
+	switch c.TLS {
+	case "", TLSNone, TLSStartTLS, TLSImplicit:
+	default:
+		return errors.Errorf("unknown SMTP TLS mode %q", c.TLS)
+	}
+	switch c.Auth {
+	case "", AuthNone:
+	case AuthPlain, AuthLogin:
+		if c.Username == "" {
+			return errors.Errorf("SMTP auth %q requires a username", c.Auth)
+		}
+	default:
+		return errors.Errorf("unknown SMTP auth method %q", c.Auth)
+	}
+	return nil
+}
+
+// Mail is a single plain text message, optionally with an HTML alternative
+type Mail struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Mailer delivers mail through the given SMTP server
+type Mailer interface {
+	Send(config SMTPConfig, mail *Mail) error
+}
+
+// SMTPMailer is a Mailer talking to a real SMTP server
+type SMTPMailer struct{}
+
+// Send delivers the mail, securing and authenticating the connection as configured
+func (SMTPMailer) Send(config SMTPConfig, mail *Mail) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if len(mail.To) == 0 {
+		return errors.New("mail has no recipients")
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultSMTPTimeout
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         config.Host,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if config.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", config.Address(), tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", config.Address())
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	c, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to start SMTP session")
+	}
+	defer c.Close()
+
+	if config.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if err = c.StartTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "STARTTLS failed")
+		}
+	}
+
+	if auth := config.auth(); auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return errors.Wrap(err, "SMTP authentication failed")
+		}
+	}
+
+	if err = c.Mail(config.From); err != nil {
+		return errors.Wrap(err, "MAIL FROM rejected")
+	}
+	for _, rcpt := range mail.To {
+		if err = c.Rcpt(rcpt); err != nil {
+			return errors.Wrapf(err, "RCPT TO %s rejected", rcpt)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "DATA rejected")
+	}
+	if _, err = w.Write(mail.message(config.From)); err != nil {
+		return errors.Wrap(err, "failed to write message")
+	}
+	if err = w.Close(); err != nil {
+		return errors.Wrap(err, "message rejected")
+	}
+
+	return c.Quit()
+}
+
+func (c SMTPConfig) auth() smtp.Auth {
+	switch c.Auth {
+	case AuthPlain:
+		return smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	case AuthLogin:
+		return &loginAuth{username: c.Username, password: c.Password, host: c.Host}
+	}
+	return nil
+}
+
+// message renders headers and body in RFC 5322 format
+func (m *Mail) message(from string) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if m.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(strings.Replace(m.Body, "\n", "\r\n", -1))
+		buf.WriteString("\r\n")
+		return buf.Bytes()
+	}
+
+	// Clients pick the last alternative they can display
+	parts := multipart.NewWriter(buf)
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%q\r\n", parts.Boundary())
+	buf.WriteString("\r\n")
+	writePart(parts, "text/plain", m.Body)
+	writePart(parts, "text/html", m.HTML)
+	parts.Close()
+	return buf.Bytes()
+}
+
+// writePart adds a quoted-printable part, HTML often has lines too long for SMTP
+func writePart(parts *multipart.Writer, contentType, body string) {
+	w, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=\"utf-8\""},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(body))
+	qp.Close()
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide
+type loginAuth struct {
+	username, password, host string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same rule as smtp.PlainAuth: never send credentials unencrypted to a remote host
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, errors.Errorf("unexpected LOGIN challenge %q", fromServer)
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
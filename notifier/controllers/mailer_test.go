@@ -0,0 +1,161 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SMTPMailer", func() {
+	var (
+		server *fakeSMTPServer
+		mail   *Mail
+	)
+
+	BeforeEach(func() {
+		mail = &Mail{
+			To:      []string{"team@example.com"},
+			Subject: "Pod failed",
+			Body:    "Reason: BackOff\nMessage: Back-off restarting failed container",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("without TLS", func() {
+		BeforeEach(func() {
+			server = newFakeSMTPServer()
+		})
+
+		It("should deliver the message", func() {
+			Expect(SMTPMailer{}.Send(server.Config(), mail)).To(Succeed())
+
+			messages := server.Messages()
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].From).To(Equal("notifier@example.com"))
+			Expect(messages[0].To).To(Equal([]string{"team@example.com"}))
+			Expect(messages[0].TLS).To(BeFalse())
+			Expect(mailHeader(messages[0].Data, "Subject")).To(Equal("Pod failed"))
+			Expect(messages[0].Data).To(ContainSubstring("Reason: BackOff\nMessage: Back-off"))
+		})
+
+		It("should send HTML with a plain text alternative", func() {
+			mail.HTML = "<p>Reason: <b>BackOff</b></p>"
+			Expect(SMTPMailer{}.Send(server.Config(), mail)).To(Succeed())
+
+			messages := server.Messages()
+			Expect(messages).To(HaveLen(1))
+			Expect(mailHeader(messages[0].Data, "Content-Type")).To(HavePrefix("multipart/alternative; boundary="))
+			Expect(messages[0].Data).To(ContainSubstring("Content-Type: text/plain"))
+			Expect(messages[0].Data).To(ContainSubstring("Content-Type: text/html"))
+			Expect(messages[0].Data).To(ContainSubstring("<p>Reason: <b>BackOff</b></p>"))
+		})
+
+		It("should fail when the server requires authentication", func() {
+			server.Username, server.Password = "user", "secret"
+
+			Expect(SMTPMailer{}.Send(server.Config(), mail)).NotTo(Succeed())
+			Expect(server.Messages()).To(BeEmpty())
+		})
+	})
+
+	Context("with STARTTLS", func() {
+		BeforeEach(func() {
+			server = newFakeSMTPServer()
+			server.Username, server.Password = "user", "secret"
+		})
+
+		It("should authenticate with PLAIN", func() {
+			config := server.Config()
+			config.TLS = TLSStartTLS
+			config.Auth = AuthPlain
+			config.Username, config.Password = "user", "secret"
+
+			Expect(SMTPMailer{}.Send(config, mail)).To(Succeed())
+
+			messages := server.Messages()
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].TLS).To(BeTrue())
+			Expect(messages[0].User).To(Equal("user"))
+		})
+
+		It("should authenticate with LOGIN", func() {
+			config := server.Config()
+			config.TLS = TLSStartTLS
+			config.Auth = AuthLogin
+			config.Username, config.Password = "user", "secret"
+
+			Expect(SMTPMailer{}.Send(config, mail)).To(Succeed())
+			Expect(server.Messages()).To(HaveLen(1))
+		})
+
+		It("should reject wrong credentials", func() {
+			config := server.Config()
+			config.TLS = TLSStartTLS
+			config.Auth = AuthLogin
+			config.Username, config.Password = "user", "wrong"
+
+			Expect(SMTPMailer{}.Send(config, mail)).NotTo(Succeed())
+			Expect(server.Messages()).To(BeEmpty())
+		})
+
+		It("should refuse an untrusted certificate", func() {
+			config := server.Config()
+			config.TLS = TLSStartTLS
+			config.InsecureSkipVerify = false
+
+			Expect(SMTPMailer{}.Send(config, mail)).NotTo(Succeed())
+		})
+	})
+
+	Context("with implicit TLS", func() {
+		BeforeEach(func() {
+			server = newFakeSMTPSServer()
+			server.Username, server.Password = "user", "secret"
+		})
+
+		It("should deliver the message", func() {
+			config := server.Config()
+			config.Auth = AuthPlain
+			config.Username, config.Password = "user", "secret"
+
+			Expect(SMTPMailer{}.Send(config, mail)).To(Succeed())
+
+			messages := server.Messages()
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].TLS).To(BeTrue())
+		})
+	})
+
+	It("should validate the configuration", func() {
+		server = newFakeSMTPServer()
+		config := server.Config()
+
+		config.Auth = AuthLogin
+		Expect(config.Validate()).NotTo(Succeed())
+
+		config.Auth = AuthNone
+		config.TLS = "ssl"
+		Expect(config.Validate()).NotTo(Succeed())
+
+		config.TLS = TLSNone
+		config.From = ""
+		Expect(SMTPMailer{}.Send(config, mail)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,133 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	emailv1 "std/api/v1"
+)
+
+// Notifier metrics carry the namespace and name of the Notifier, ClusterNotifiers have an empty namespace
+var (
+	eventsObserved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifier_events_observed_total",
+		Help: "Events watched by any Notifier, observed by the Event controller",
+	}, []string{"type"})
+
+	eventsMatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifier_events_matched_total",
+		Help: "Events matched and recorded for delivery per Notifier",
+	}, []string{"namespace", "notifier"})
+
+	notificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifier_notifications_sent_total",
+		Help: "Notifications, or digests, sent per Notifier and channel type",
+	}, []string{"namespace", "notifier", "channel"})
+
+	notificationsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifier_notifications_failed_total",
+		Help: "Notifications, or digests, failed to send per Notifier and channel type",
+	}, []string{"namespace", "notifier", "channel"})
+
+	deliveryLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "notifier_delivery_latency_seconds",
+		Help:    "Time from the last occurrence of an Event until it was sent through every channel",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600},
+	})
+
+	pendingNotifications = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notifier_pending_notifications",
+		Help: "NotificationRecords waiting for delivery per Notifier",
+	}, []string{"namespace", "notifier"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		eventsObserved,
+		eventsMatched,
+		notificationsSent,
+		notificationsFailed,
+		deliveryLatency,
+		pendingNotifications,
+	)
+}
+
+// notifierLabels are the namespace and name labels of the Notifier
+func notifierLabels(notifier *emailv1.Notifier) []string {
+	if notifier.IsClusterNotifier() {
+		return []string{"", notifier.GetName()}
+	}
+	return []string{notifier.GetNamespace(), notifier.GetName()}
+}
+
+// channelType is the channel label of the metrics
+func channelType(channel Channel) string {
+	switch c := channel.(type) {
+	case *emailChannel:
+		return string(emailv1.EmailChannel)
+	case *webhookChannel:
+		return string(c.channelType)
+	case *pagerDutyChannel:
+		return string(c.channelType)
+	case *opsgenieChannel:
+		return string(c.channelType)
+	}
+	return "unknown"
+}
+
+// observeSent counts a notification sent through the channel
+func observeSent(notifier *emailv1.Notifier, channel Channel) {
+	notificationsSent.WithLabelValues(append(notifierLabels(notifier), channelType(channel))...).Inc()
+}
+
+// observeFailed counts a notification the channel failed to send
+func observeFailed(notifier *emailv1.Notifier, channel Channel) {
+	notificationsFailed.WithLabelValues(append(notifierLabels(notifier), channelType(channel))...).Inc()
+}
+
+// observeLatency measures from the last occurrence of the recorded Event, or the record creation
+func observeLatency(record *emailv1.NotificationRecord, now time.Time) {
+	occurred := record.Spec.Event.LastTimestamp
+	if occurred.IsZero() {
+		occurred = record.Spec.Event.FirstTimestamp
+	}
+	if occurred.IsZero() {
+		occurred = record.CreationTimestamp
+	}
+	if occurred.IsZero() {
+		return
+	}
+	deliveryLatency.Observe(now.Sub(occurred.Time).Seconds())
+}
+
+// observePending sets the number of records still waiting for delivery
+func observePending(notifier *emailv1.Notifier, records []emailv1.NotificationRecord) {
+	pending := 0
+	for _, record := range records {
+		if record.IsPending() {
+			pending++
+		}
+	}
+	pendingNotifications.WithLabelValues(notifierLabels(notifier)...).Set(float64(pending))
+}
+
+// forgetMetrics drops the gauge of a deleted Notifier, the counters are kept
+func forgetMetrics(namespace, name string) {
+	pendingNotifications.DeleteLabelValues(namespace, name)
+}
@@ -0,0 +1,144 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	emailv1 "std/api/v1"
+)
+
+// scrapeMetric reads a sample from the registry through the metrics endpoint, like Prometheus does.
+// The series is the metric name with its labels in alphabetical order, missing series are reported as -1.
+func scrapeMetric(series string) float64 {
+	handler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	Expect(resp.Code).To(Equal(http.StatusOK))
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, series+" ") {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+		Expect(err).NotTo(HaveOccurred())
+		return value
+	}
+	return -1
+}
+
+var _ = Describe("metrics", func() {
+	var (
+		recorder     *webhookRecorder
+		notifier     *emailv1.Notifier
+		notification *Notification
+	)
+
+	BeforeEach(func() {
+		recorder = newWebhookRecorder()
+		notifier = &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: "apps"}}
+		notification = &Notification{
+			Notifier: notifier,
+			Event:    newWarningEvent("api.1", "BackOff", "Pod", "api"),
+		}
+	})
+
+	AfterEach(func() {
+		recorder.Close()
+	})
+
+	It("should count sent and failed notifications per channel", func() {
+		slack, err := newWebhookChannel(recorder.Client(), recorder.URL, emailv1.SlackChannel)
+		Expect(err).NotTo(HaveOccurred())
+		r := &NotifierReconciler{}
+
+		Expect(r.send(notifier, []Channel{slack}, nil, notification)).To(Succeed())
+		Expect(scrapeMetric(`notifier_notifications_sent_total{channel="slack",namespace="apps",notifier="metrics"}`)).
+			To(BeEquivalentTo(1))
+		Expect(scrapeMetric(`notifier_notifications_failed_total{channel="slack",namespace="apps",notifier="metrics"}`)).
+			To(BeEquivalentTo(-1))
+
+		recorder.RespondWith(http.StatusBadGateway)
+		Expect(r.send(notifier, []Channel{slack}, nil, notification)).NotTo(Succeed())
+		Expect(scrapeMetric(`notifier_notifications_sent_total{channel="slack",namespace="apps",notifier="metrics"}`)).
+			To(BeEquivalentTo(1))
+		Expect(scrapeMetric(`notifier_notifications_failed_total{channel="slack",namespace="apps",notifier="metrics"}`)).
+			To(BeEquivalentTo(1))
+		Expect(notifier.Status.DeliveredCount).To(BeEquivalentTo(1))
+		Expect(notifier.Status.FailedCount).To(BeEquivalentTo(1))
+	})
+
+	It("should observe the delivery latency from the last occurrence", func() {
+		count := scrapeMetric("notifier_delivery_latency_seconds_count")
+		fast := scrapeMetric(`notifier_delivery_latency_seconds_bucket{le="10"}`)
+		slow := scrapeMetric(`notifier_delivery_latency_seconds_bucket{le="60"}`)
+
+		now := time.Now()
+		record := newNotificationRecord(notifier, notification.Event)
+		record.Spec.Event.FirstTimestamp = metav1.NewTime(now.Add(-time.Hour))
+		record.Spec.Event.LastTimestamp = metav1.NewTime(now.Add(-30 * time.Second))
+		observeLatency(record, now)
+
+		Expect(scrapeMetric("notifier_delivery_latency_seconds_count")).To(Equal(count + 1))
+		Expect(scrapeMetric(`notifier_delivery_latency_seconds_bucket{le="10"}`)).To(Equal(fast))
+		Expect(scrapeMetric(`notifier_delivery_latency_seconds_bucket{le="60"}`)).To(Equal(slow + 1))
+
+		// Nothing to measure from
+		observeLatency(&emailv1.NotificationRecord{}, now)
+		Expect(scrapeMetric("notifier_delivery_latency_seconds_count")).To(Equal(count + 1))
+	})
+
+	It("should report the pending records until the Notifier is gone", func() {
+		records := []emailv1.NotificationRecord{
+			*newNotificationRecord(notifier, notification.Event),
+			*newNotificationRecord(notifier, notification.Event),
+			*newNotificationRecord(notifier, notification.Event),
+		}
+		records[0].Status.State = emailv1.RecordSent
+		records[1].Status.State = emailv1.RecordFailed
+		observePending(notifier, records)
+		Expect(scrapeMetric(`notifier_pending_notifications{namespace="apps",notifier="metrics"}`)).To(BeEquivalentTo(2))
+
+		forgetMetrics("apps", "metrics")
+		Expect(scrapeMetric(`notifier_pending_notifications{namespace="apps",notifier="metrics"}`)).To(BeEquivalentTo(-1))
+	})
+
+	It("should label ClusterNotifiers without a namespace", func() {
+		clusterNotifier := &emailv1.ClusterNotifier{ObjectMeta: metav1.ObjectMeta{Name: "metrics"}}
+		view := clusterNotifier.AsNotifier("failure-informer-system")
+		Expect(notifierLabels(view)).To(Equal([]string{"", "metrics"}))
+		Expect(notifierLabels(notifier)).To(Equal([]string{"apps", "metrics"}))
+
+		email := &emailChannel{to: "team@example.com"}
+		teams, err := newWebhookChannel(recorder.Client(), recorder.URL, emailv1.TeamsChannel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channelType(email)).To(Equal("email"))
+		Expect(channelType(teams)).To(Equal("teams"))
+	})
+})
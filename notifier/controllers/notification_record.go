@@ -0,0 +1,152 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	emailv1 "std/api/v1"
+)
+
+// DefaultRecordTTL is how long sent NotificationRecords are kept
+const DefaultRecordTTL = 24 * time.Hour
+
+// maxRecordPrefix leaves room for the hash suffix within the object name limit
+const maxRecordPrefix = 200
+
+// recordName is unique for the Notifier and Event, so an Event reconciled twice is recorded once
+func recordName(notifier *emailv1.Notifier, event *corev1.Event) string {
+	key := event.GetNamespace() + "/" + event.GetName() + "/" + string(event.GetUID())
+	if notifier.IsClusterNotifier() {
+		// Don't collide with a Notifier of the same name
+		key = emailv1.ClusterNotifierKind + "/" + key
+	}
+	hash := sha256.Sum256([]byte(key))
+	return namePrefix(notifier.GetName()) + "-" + hex.EncodeToString(hash[:])[:10]
+}
+
+// namePrefix truncates name to maxRecordPrefix, so it still ends in an alphanumeric once cut
+func namePrefix(name string) string {
+	if len(name) > maxRecordPrefix {
+		name = strings.TrimRight(name[:maxRecordPrefix], ".-")
+	}
+	return name
+}
+
+// newNotificationRecord snapshots the Event for delivery by the Notifier
+func newNotificationRecord(notifier *emailv1.Notifier, event *corev1.Event) *emailv1.NotificationRecord {
+	record := &emailv1.NotificationRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      recordName(notifier, event),
+			Namespace: notifier.GetNamespace(),
+		},
+		Spec: emailv1.NotificationRecordSpec{
+			Notifier: notifier.GetName(),
+			EventRef: corev1.ObjectReference{
+				Kind:            "Event",
+				APIVersion:      "v1",
+				Namespace:       event.GetNamespace(),
+				Name:            event.GetName(),
+				UID:             event.GetUID(),
+				ResourceVersion: event.GetResourceVersion(),
+			},
+			Event: snapshotEvent(event),
+		},
+	}
+	if notifier.IsClusterNotifier() {
+		record.Spec.NotifierKind = emailv1.ClusterNotifierKind
+	}
+	return record
+}
+
+// recordNotifierKey identifies the delivering Notifier or ClusterNotifier in the records index
+func recordNotifierKey(kind, name string) string {
+	if kind == emailv1.ClusterNotifierKind {
+		return kind + "/" + name
+	}
+	return name
+}
+
+// snapshotEvent copies the Event fields used in notifications
+func snapshotEvent(event *corev1.Event) emailv1.EventSnapshot {
+	return emailv1.EventSnapshot{
+		Type:           event.Type,
+		Reason:         event.Reason,
+		Message:        event.Message,
+		InvolvedObject: event.InvolvedObject,
+		Source:         event.Source.Component,
+		Count:          event.Count,
+		FirstTimestamp: event.FirstTimestamp,
+		LastTimestamp:  event.LastTimestamp,
+	}
+}
+
+// notifierObject is the Notifier itself, or the ClusterNotifier it stands for
+func notifierObject(notifier *emailv1.Notifier) runtime.Object {
+	if notifier.IsClusterNotifier() {
+		return &emailv1.ClusterNotifier{ObjectMeta: metav1.ObjectMeta{Name: notifier.GetName(), UID: notifier.GetUID()}}
+	}
+	return notifier
+}
+
+// recordEvent rebuilds the Event from the snapshot, it may be deleted already
+func recordEvent(record *emailv1.NotificationRecord) *corev1.Event {
+	snapshot := record.Spec.Event
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      record.Spec.EventRef.Name,
+			Namespace: record.Spec.EventRef.Namespace,
+			UID:       record.Spec.EventRef.UID,
+		},
+		Type:           snapshot.Type,
+		Reason:         snapshot.Reason,
+		Message:        snapshot.Message,
+		InvolvedObject: snapshot.InvolvedObject,
+		Source:         corev1.EventSource{Component: snapshot.Source},
+		Count:          snapshot.Count,
+		FirstTimestamp: snapshot.FirstTimestamp,
+		LastTimestamp:  snapshot.LastTimestamp,
+	}
+}
+
+// renotifyDue decides whether a sent record is delivered again under the policy.
+// When only the time is missing, it returns how long to wait.
+func renotifyDue(record *emailv1.NotificationRecord, policy *emailv1.RenotifyPolicy, now time.Time) (bool, time.Duration) {
+	if policy == nil || record.Status.State != emailv1.RecordSent || record.Status.SentTime == nil {
+		return false, 0
+	}
+	occurred := record.Spec.Event.Count - record.Status.NotifiedCount
+	if occurred <= 0 {
+		return false, 0
+	}
+	if policy.AfterCount > 0 && occurred >= policy.AfterCount {
+		return true, 0
+	}
+	if policy.After == nil {
+		return false, 0
+	}
+	wait := policy.After.Duration - now.Sub(record.Status.SentTime.Time)
+	if wait <= 0 {
+		return true, 0
+	}
+	return false, wait
+}
@@ -0,0 +1,155 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("NotificationRecord", func() {
+	var event *corev1.Event
+
+	BeforeEach(func() {
+		event = newWarningEvent("record-pod.backoff", "BackOff", "Pod", "record-pod")
+		event.UID = types.UID("6c4e0a5e-8d2c-4b8e-9a57-1f7f2d1c0b3a")
+		event.Count = 3
+		event.Source.Component = "kubelet"
+	})
+
+	It("should name the record after the Notifier and Event", func() {
+		notifier := newNotifier("team", "team@example.com")
+		name := recordName(notifier, event)
+		Expect(name).To(HavePrefix("team-"))
+		Expect(recordName(notifier, event.DeepCopy())).To(Equal(name))
+		Expect(recordName(newNotifier("other", "other@example.com"), event)).To(HavePrefix("other-"))
+
+		recreated := event.DeepCopy()
+		recreated.UID = types.UID("0d7a8f5e-2f0b-4c55-8f3c-9b1a6c2e4d10")
+		Expect(recordName(notifier, recreated)).NotTo(Equal(name))
+	})
+
+	It("should tell ClusterNotifier records apart", func() {
+		notifier := newNotifier("team", "team@example.com")
+		clusterNotifier := (&emailv1.ClusterNotifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		}).AsNotifier("default")
+
+		Expect(recordName(clusterNotifier, event)).To(HavePrefix("team-"))
+		Expect(recordName(clusterNotifier, event)).NotTo(Equal(recordName(notifier, event)))
+
+		record := newNotificationRecord(clusterNotifier, event)
+		Expect(record.Spec.NotifierKind).To(Equal(emailv1.ClusterNotifierKind))
+		Expect(recordNotifierIndex(record)).To(Equal([]string{"ClusterNotifier/team"}))
+		Expect(recordNotifierIndex(newNotificationRecord(notifier, event))).To(Equal([]string{"team"}))
+	})
+
+	It("should keep long names within the object name limit", func() {
+		long := make([]byte, 253)
+		for i := range long {
+			long[i] = 'a'
+		}
+		notifier := newNotifier(string(long), "long@example.com")
+		Expect(len(recordName(notifier, event))).To(BeNumerically("<=", 253))
+
+		// Cut right after a dot or dash, the name must still be valid
+		long[maxRecordPrefix-2] = '-'
+		long[maxRecordPrefix-1] = '.'
+		notifier = newNotifier(string(long), "long@example.com")
+		name := recordName(notifier, event)
+		Expect(len(name)).To(BeNumerically("<=", 253))
+		Expect(validation.IsDNS1123Subdomain(name)).To(BeEmpty())
+		Expect(validation.IsDNS1123Subdomain(incidentName(notifier, emailv1.IncidentSpec{NodeName: "node-1"}))).To(BeEmpty())
+	})
+
+	It("should rebuild the Event from the snapshot", func() {
+		notifier := newNotifier("team", "team@example.com")
+		record := newNotificationRecord(notifier, event)
+		Expect(record.GetNamespace()).To(Equal("default"))
+		Expect(record.Spec.Notifier).To(Equal("team"))
+		Expect(record.Spec.EventRef.UID).To(Equal(event.UID))
+		Expect(record.IsPending()).To(BeTrue())
+
+		rebuilt := recordEvent(record)
+		Expect(rebuilt.GetName()).To(Equal(event.GetName()))
+		Expect(rebuilt.Reason).To(Equal("BackOff"))
+		Expect(rebuilt.Message).To(Equal(event.Message))
+		Expect(rebuilt.InvolvedObject).To(Equal(event.InvolvedObject))
+		Expect(rebuilt.Source.Component).To(Equal("kubelet"))
+		Expect(rebuilt.Count).To(BeEquivalentTo(3))
+
+		record.Status.State = emailv1.RecordSent
+		Expect(record.IsPending()).To(BeFalse())
+	})
+
+	Context("renotify", func() {
+		var (
+			record *emailv1.NotificationRecord
+			sent   time.Time
+		)
+
+		BeforeEach(func() {
+			sent = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+			record = newNotificationRecord(newNotifier("team", "team@example.com"), event)
+			record.Status.State = emailv1.RecordSent
+			record.Status.SentTime = &metav1.Time{Time: sent}
+			record.Status.NotifiedCount = 3
+		})
+
+		It("should never notify again without a policy", func() {
+			record.Spec.Event.Count = 100
+			Expect(renotifyDue(record, nil, sent.Add(time.Hour))).To(BeFalse())
+		})
+
+		It("should notify again after more occurrences", func() {
+			policy := &emailv1.RenotifyPolicy{AfterCount: 5}
+			record.Spec.Event.Count = 7
+			due, wait := renotifyDue(record, policy, sent)
+			Expect(due).To(BeFalse())
+			Expect(wait).To(BeZero())
+
+			record.Spec.Event.Count = 8
+			Expect(renotifyDue(record, policy, sent)).To(BeTrue())
+		})
+
+		It("should notify new occurrences once the time elapsed", func() {
+			policy := &emailv1.RenotifyPolicy{After: &metav1.Duration{Duration: 10 * time.Minute}}
+			Expect(renotifyDue(record, policy, sent.Add(time.Hour))).To(BeFalse())
+
+			record.Spec.Event.Count = 4
+			due, wait := renotifyDue(record, policy, sent.Add(4*time.Minute))
+			Expect(due).To(BeFalse())
+			Expect(wait).To(Equal(6 * time.Minute))
+
+			Expect(renotifyDue(record, policy, sent.Add(10*time.Minute))).To(BeTrue())
+		})
+
+		It("should leave pending records to the regular delivery", func() {
+			policy := &emailv1.RenotifyPolicy{AfterCount: 1}
+			record.Spec.Event.Count = 10
+			record.Status.State = emailv1.RecordFailed
+			Expect(renotifyDue(record, policy, sent)).To(BeFalse())
+		})
+	})
+})
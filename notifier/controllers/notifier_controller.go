@@ -18,98 +18,964 @@ package controllers
 import (
 	ctx "context"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	emailv1 "std/api/v1"
 )
 
+const (
+	// secretsField indexes Notifiers by the Secrets their channels use
+	secretsField = ".spec.secrets"
+	// configMapsField indexes Notifiers by the ConfigMap holding their template
+	configMapsField = ".spec.template.configMapRef"
+	// escalationPoliciesField indexes Notifiers by their EscalationPolicy
+	escalationPoliciesField = ".spec.escalationPolicyRef"
+	// recordNotifierField indexes NotificationRecords by the Notifier delivering them
+	recordNotifierField = ".spec.notifier"
+)
+
 // NotifierReconciler reconciles a Notifier object
 type NotifierReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	// Mailer delivers notifications through the SMTP server
+	Mailer Mailer
+	// SMTP holds the defaults used when a Notifier doesn't reference a Secret
+	SMTP SMTPConfig
+	// DefaultSMTPSecret is used by Notifiers without their own smtpSecretRef
+	DefaultSMTPSecret types.NamespacedName
+	// HTTPClient posts to webhook channels, a client with DefaultWebhookTimeout is used when nil
+	HTTPClient *http.Client
+	// RecordTTL is how long sent or suppressed NotificationRecords are kept, zero keeps them forever
+	RecordTTL time.Duration
+	// ClusterName is passed to the message templates
+	ClusterName string
+	// Filters caches the compiled filters, shared with the EventReconciler
+	Filters *FilterIndex
+	// Recorder reports dead-lettered notifications as Events on the Notifier, nothing is reported when nil
+	Recorder record.EventRecorder
+	// Logs reads the container logs attached to notifications, no logs are attached when nil
+	Logs LogFetcher
+	// Acknowledge signs the acknowledgement links of escalated notifications, no links are added when nil
+	Acknowledge *AcknowledgeServer
+	// PodCheckInterval is how often the Pods of open incidents are checked for readiness, DefaultPodCheckInterval when zero
+	PodCheckInterval time.Duration
 }
 
 // +kubebuilder:rbac:groups=email.notify.io,resources=notifiers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=email.notify.io,resources=notifiers/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups="",resources=event,verbs=get;list;watch
+// +kubebuilder:rbac:groups=email.notify.io,resources=notificationrecords,verbs=get;list;watch;update;delete
+// +kubebuilder:rbac:groups=email.notify.io,resources=notificationrecords/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups=email.notify.io,resources=escalationpolicies,verbs=get;list;watch
 
 func (r *NotifierReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("notifier", req.NamespacedName)
 
 	notifier := &emailv1.Notifier{}
 	err := r.Get(ctx.TODO(), req.NamespacedName, notifier)
+	if k8serror.IsNotFound(err) {
+		r.Filters.Forget(req.NamespacedName.String())
+		forgetMetrics(req.Namespace, req.Name)
+	}
 	if err != nil {
 		log.Error(err, "Can't get notifier")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	events, err := r.getFilteredEvents(notifier)
-	if err != nil {
-		log.Error(err, "Failed to list Pod related Events")
-		return ctrl.Result{Requeue: true}, nil
-	}
+	previous := notifier.Status.DeepCopy()
+	result := r.deliver(log, notifier)
 
-	err = r.notify(notifier, events)
+	err = r.updateStatus(notifier, previous)
 	if k8serror.IsConflict(err) {
 		return ctrl.Result{Requeue: true}, nil
 	} else if err != nil {
-		log.Error(err, "Failed to notify event")
+		log.Error(err, "Failed to update Notifier status")
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	return ctrl.Result{}, nil
+	return result, nil
+}
+
+// deliver validates the Notifier and sends the pending events, recording the outcome in the status
+func (r *NotifierReconciler) deliver(log logr.Logger, notifier *emailv1.Notifier) ctrl.Result {
+	status := &notifier.Status
+	status.ObservedGeneration = notifier.GetGeneration()
+	if emailv1.FindCondition(status.Conditions, emailv1.ConditionDeliveryDegraded) == nil {
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionDeliveryDegraded, corev1.ConditionFalse, "NoFailures", "")
+	}
+
+	_, err := r.Filters.Get(notifier)
+	if err != nil {
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionInvalidFilter, corev1.ConditionTrue, "ValidationFailed", err.Error())
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionReady, corev1.ConditionFalse, "InvalidFilter", err.Error())
+		status.LastError = err.Error()
+		return ctrl.Result{}
+	}
+	emailv1.SetCondition(&status.Conditions, emailv1.ConditionInvalidFilter, corev1.ConditionFalse, "FiltersValid", "")
+
+	channels, err := r.getChannels(notifier)
+	if errors.Cause(err) == errInvalidConfig {
+		// Wait for the Secret to be created or fixed, the watch brings us back
+		log.Info("Invalid channel configuration", "error", err.Error())
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionReady, corev1.ConditionFalse, "InvalidConfiguration", err.Error())
+		status.LastError = err.Error()
+		return ctrl.Result{}
+	} else if err != nil {
+		log.Error(err, "Failed to get channel configuration")
+		return ctrl.Result{Requeue: true}
+	}
+	if !emailv1.IsConditionTrue(status.Conditions, emailv1.ConditionReady) {
+		// The configuration error is fixed
+		status.LastError = ""
+	}
+	emailv1.SetCondition(&status.Conditions, emailv1.ConditionReady, corev1.ConditionTrue, "Configured",
+		fmt.Sprintf("%d channels configured", len(channels)))
+
+	template, err := r.getTemplate(notifier)
+	if errors.Cause(err) == errInvalidConfig {
+		// Keep notifying with the default message until the template is fixed
+		log.Info("Invalid message template", "error", err.Error())
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionInvalidTemplate, corev1.ConditionTrue, "ParseFailed", err.Error())
+		status.LastError = err.Error()
+		template = nil
+	} else if err != nil {
+		log.Error(err, "Failed to get message template")
+		return ctrl.Result{Requeue: true}
+	} else {
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionInvalidTemplate, corev1.ConditionFalse, "TemplateValid", "")
+	}
+
+	records, err := r.getRecords(notifier)
+	if err != nil {
+		log.Error(err, "Failed to list NotificationRecords")
+		return ctrl.Result{Requeue: true}
+	}
+
+	requeueAfter, err := r.pruneRecords(records)
+	if err != nil {
+		log.Error(err, "Failed to delete expired NotificationRecords")
+		return ctrl.Result{Requeue: true}
+	}
+
+	pending := []emailv1.NotificationRecord{}
+	backingOff := []emailv1.NotificationRecord{}
+	settled := []emailv1.NotificationRecord{}
+	now := time.Now()
+	for _, record := range records {
+		if retry, wait := retryDue(&record, now); !retry {
+			// Come back when the failed delivery may be retried
+			backingOff = append(backingOff, record)
+			requeueAfter = minDuration(requeueAfter, wait)
+			continue
+		}
+		due, wait := renotifyDue(&record, notifier.Spec.Renotify, now)
+		if record.IsPending() || due {
+			pending = append(pending, record)
+			continue
+		} else if wait > 0 {
+			// Come back when the repeated occurrences may be notified
+			requeueAfter = minDuration(requeueAfter, wait)
+		}
+		settled = append(settled, record)
+	}
+
+	pending, err = r.suppressSilenced(notifier, pending)
+	if k8serror.IsConflict(err) {
+		return ctrl.Result{Requeue: true}
+	} else if err != nil {
+		log.Error(err, "Failed to suppress silenced NotificationRecords")
+		return ctrl.Result{Requeue: true}
+	}
+
+	var wait time.Duration
+	throttle := newThrottle(notifier, now)
+	if notifier.Spec.Digest != nil {
+		wait, err = r.notifyDigest(notifier, channels, template, pending, throttle)
+	} else {
+		err = r.notify(notifier, channels, template, pending, throttle)
+	}
+	observePending(notifier, append(pending, backingOff...))
+	if k8serror.IsConflict(err) {
+		return ctrl.Result{Requeue: true}
+	} else if err != nil {
+		log.Error(err, "Failed to record the delivery")
+		return ctrl.Result{Requeue: true}
+	}
+
+	// Failed records wait for their retry, without holding back the others
+	failed, retryAfter := lastFailure(append(pending, backingOff...), now)
+	if failed != nil {
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionDeliveryDegraded, corev1.ConditionTrue, "DeliveryFailed", failed.Status.LastError)
+		status.LastError = failed.Status.LastError
+		requeueAfter = minDuration(requeueAfter, retryAfter)
+	}
+
+	// Come back to prune the dedup state once it expires
+	requeueAfter = minDuration(requeueAfter, throttle.expiresIn())
+
+	if wait > 0 {
+		// Flush the digest when the window is over
+		requeueAfter = minDuration(requeueAfter, wait)
+	} else if failed == nil && len(pending) > 0 {
+		emailv1.SetCondition(&status.Conditions, emailv1.ConditionDeliveryDegraded, corev1.ConditionFalse, "Delivered", "")
+		status.LastError = ""
+	}
+
+	// Resolve first, so escalate sees the resolved records
+	current := append(append(settled, pending...), backingOff...)
+	resolveAfter, err := r.resolve(notifier, channels, template, current, now)
+	if k8serror.IsConflict(err) {
+		return ctrl.Result{Requeue: true}
+	} else if err != nil {
+		log.Error(err, "Failed to resolve incidents")
+		return ctrl.Result{Requeue: true}
+	}
+	requeueAfter = minDuration(requeueAfter, resolveAfter)
+
+	escalateAfter, err := r.escalate(notifier, channels, template, current[:len(settled)+len(pending)], now)
+	if errors.Cause(err) == errInvalidConfig {
+		// Wait for the policy or its Secrets to be fixed, the notifications were sent anyway
+		log.Info("Invalid escalation policy", "error", err.Error())
+		status.LastError = err.Error()
+	} else if k8serror.IsConflict(err) {
+		return ctrl.Result{Requeue: true}
+	} else if err != nil {
+		log.Error(err, "Failed to escalate notifications")
+		return ctrl.Result{Requeue: true}
+	}
+	return ctrl.Result{RequeueAfter: minDuration(requeueAfter, escalateAfter)}
+}
+
+// minDuration is the shorter of the positive durations, zero means none
+func minDuration(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// updateStatus writes the status through the status subresource, if it changed
+func (r *NotifierReconciler) updateStatus(notifier *emailv1.Notifier, previous *emailv1.NotifierStatus) error {
+	if equality.Semantic.DeepEqual(&notifier.Status, previous) {
+		return nil
+	}
+	return r.Status().Update(ctx.TODO(), notifier)
 }
 
 func (r *NotifierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(&emailv1.Notifier{}, secretsField, r.secretsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.Notifier{}, configMapsField, configMapsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.Notifier{}, escalationPoliciesField, escalationPoliciesIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.EscalationPolicy{}, secretsField, escalationPolicySecretsIndex)
+	if err != nil {
+		return err
+	}
+	err = mgr.GetFieldIndexer().IndexField(&emailv1.NotificationRecord{}, recordNotifierField, recordNotifierIndex)
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&emailv1.Notifier{}).
-		Owns(&corev1.Event{}).
+		Owns(&emailv1.NotificationRecord{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.notifiersForSecret)}).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.notifiersForConfigMap)}).
+		Watches(
+			&source.Kind{Type: &emailv1.EscalationPolicy{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.notifiersForEscalationPolicy)}).
 		Complete(r)
 }
 
-// Lists all events, which match the filter
-func (r *NotifierReconciler) getFilteredEvents(notify *emailv1.Notifier) ([]corev1.Event, error) {
-	labelFilter := map[string]string{}
-	labelFilter[notify.GetNotifyLabel()] = "true"
+// errInvalidConfig marks errors which only a change of the Notifier or its Secrets can fix
+var errInvalidConfig = errors.New("invalid Notifier configuration")
+
+// getChannels builds all channels of the Notifier, resolving the referenced Secrets
+func (r *NotifierReconciler) getChannels(notifier *emailv1.Notifier) ([]Channel, error) {
+	return r.buildChannels(notifier, notifier.GetChannels())
+}
+
+// buildChannels builds the channels, resolving the Secrets in the Notifier namespace
+func (r *NotifierReconciler) buildChannels(notifier *emailv1.Notifier, specs []emailv1.Channel) ([]Channel, error) {
+	channels := []Channel{}
+	var smtpConfig *SMTPConfig
+	for i, spec := range specs {
+		switch spec.Type {
+		case emailv1.EmailChannel:
+			if smtpConfig == nil {
+				config, err := r.getSMTPConfig(notifier)
+				if err != nil {
+					return nil, err
+				}
+				smtpConfig = &config
+			}
+			if smtpConfig.Host == "" {
+				// Without a configured SMTP server notifications are only logged
+				continue
+			}
+			channels = append(channels, &emailChannel{mailer: r.Mailer, config: *smtpConfig, to: spec.Email})
+		case emailv1.PagerDutyChannel, emailv1.OpsgenieChannel:
+			channel, err := r.buildAlertChannel(notifier, spec)
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, channel)
+		default:
+			endpoint, err := r.getChannelURL(notifier, spec)
+			if err != nil {
+				return nil, err
+			}
+			channel, err := newWebhookChannel(r.httpClient(), endpoint, spec.Type)
+			if err != nil {
+				return nil, errors.Wrapf(errInvalidConfig, "channel %d: %v", i, err)
+			}
+			err = r.configureWebhook(notifier, channel, spec.Webhook)
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// buildAlertChannel builds a pagerduty or opsgenie channel, with the default endpoint unless the channel sets one
+func (r *NotifierReconciler) buildAlertChannel(notifier *emailv1.Notifier, spec emailv1.Channel) (Channel, error) {
+	endpoint, err := r.getChannelURL(notifier, spec)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == "" {
+		endpoint = spec.Type.DefaultURL()
+	}
+	if spec.RoutingKeySecretRef == nil {
+		return nil, errors.Wrapf(errInvalidConfig, "%s channel has no routingKeySecretRef", spec.Type)
+	}
+	key, err := r.getSecretValue(notifier.GetNamespace(), spec.RoutingKeySecretRef)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, errors.Wrapf(errInvalidConfig, "Secret %s/%s: empty key %q",
+			notifier.GetNamespace(), spec.RoutingKeySecretRef.Name, spec.RoutingKeySecretRef.Key)
+	}
+
+	poster := &webhookChannel{client: r.httpClient(), url: endpoint, channelType: spec.Type}
+	err = r.configureWebhook(notifier, poster, spec.Webhook)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type == emailv1.PagerDutyChannel {
+		return &pagerDutyChannel{webhookChannel: poster, routingKey: key}, nil
+	}
+	if poster.headers == nil {
+		poster.headers = http.Header{}
+	}
+	poster.headers.Set("Authorization", "GenieKey "+key)
+	return &opsgenieChannel{webhookChannel: poster, apiKey: key}, nil
+}
+
+func (r *NotifierReconciler) getChannelURL(notifier *emailv1.Notifier, spec emailv1.Channel) (string, error) {
+	if spec.URLSecretRef == nil {
+		return spec.URL, nil
+	}
+	return r.getSecretValue(notifier.GetNamespace(), spec.URLSecretRef)
+}
+
+// getSecretValue reads the selected Secret key, reporting a missing key as errInvalidConfig
+func (r *NotifierReconciler) getSecretValue(namespace string, ref *corev1.SecretKeySelector) (string, error) {
+	secret, err := r.getSecret(namespace, ref.Name)
+	if err != nil {
+		return "", err
+	}
+	value, found := secret.Data[ref.Key]
+	if !found {
+		return "", errors.Wrapf(errInvalidConfig, "Secret %s/%s: missing key %q",
+			secret.GetNamespace(), secret.GetName(), ref.Key)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// getSMTPConfig resolves the SMTP settings from the referenced or default Secret
+func (r *NotifierReconciler) getSMTPConfig(notifier *emailv1.Notifier) (SMTPConfig, error) {
+	key := r.smtpSecretKey(notifier)
+	if key.Name == "" {
+		return r.SMTP, nil
+	}
+
+	secret, err := r.getSecret(key.Namespace, key.Name)
+	if err != nil {
+		return r.SMTP, err
+	}
+
+	config, err := SMTPConfigFromSecret(secret, r.SMTP)
+	if err != nil {
+		return config, errors.Wrap(errInvalidConfig, err.Error())
+	}
+	return config, nil
+}
+
+// getSecret reports a missing Secret as errInvalidConfig
+func (r *NotifierReconciler) getSecret(namespace, name string) (*corev1.Secret, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx.TODO(), key, secret)
+	if k8serror.IsNotFound(err) {
+		return nil, errors.Wrapf(errInvalidConfig, "Secret %s not found", key)
+	}
+	return secret, err
+}
+
+func (r *NotifierReconciler) httpClient() *http.Client {
+	if r.HTTPClient == nil {
+		return &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return r.HTTPClient
+}
+
+func (r *NotifierReconciler) smtpSecretKey(notifier *emailv1.Notifier) types.NamespacedName {
+	if name := notifier.GetSMTPSecretName(); name != "" {
+		return types.NamespacedName{Namespace: notifier.GetNamespace(), Name: name}
+	}
+	return r.DefaultSMTPSecret
+}
+
+// secretsIndex lists every Secret the Notifier depends on
+func (r *NotifierReconciler) secretsIndex(obj runtime.Object) []string {
+	notifier := obj.(*emailv1.Notifier)
+
+	secrets := []string{}
+	for _, channel := range notifier.GetChannels() {
+		if channel.Type != emailv1.EmailChannel {
+			secrets = append(secrets, channelSecrets(notifier.GetNamespace(), channel)...)
+		} else if key := r.smtpSecretKey(notifier); key.Name != "" {
+			secrets = append(secrets, key.String())
+		}
+	}
+	return secrets
+}
+
+// escalationPolicySecretsIndex lists every Secret the channels of the policy tiers depend on
+func escalationPolicySecretsIndex(obj runtime.Object) []string {
+	policy := obj.(*emailv1.EscalationPolicy)
+
+	secrets := []string{}
+	for _, tier := range policy.Spec.Tiers {
+		for _, channel := range tier.Channels {
+			secrets = append(secrets, channelSecrets(policy.GetNamespace(), channel)...)
+		}
+	}
+	return secrets
+}
+
+// channelSecrets lists the Secrets referenced by the channel itself, email channels use the SMTP Secret of the Notifier
+func channelSecrets(namespace string, channel emailv1.Channel) []string {
+	names := []string{}
+	if channel.URLSecretRef != nil {
+		names = append(names, channel.URLSecretRef.Name)
+	}
+	if channel.RoutingKeySecretRef != nil {
+		names = append(names, channel.RoutingKeySecretRef.Name)
+	}
+	if options := channel.Webhook; options != nil {
+		if options.HeadersSecretRef != nil {
+			names = append(names, options.HeadersSecretRef.Name)
+		}
+		if options.SigningSecretRef != nil {
+			names = append(names, options.SigningSecretRef.Name)
+		}
+		if options.TLSSecretRef != nil {
+			names = append(names, options.TLSSecretRef.Name)
+		}
+	}
+
+	secrets := []string{}
+	for _, name := range names {
+		secrets = append(secrets, types.NamespacedName{Namespace: namespace, Name: name}.String())
+	}
+	return secrets
+}
+
+// notifiersForSecret requeues every Notifier using the Secret, or escalating with a policy using it,
+// so rotated settings are picked up. The transport of a changed or deleted TLS Secret is dropped.
+func (r *NotifierReconciler) notifiersForSecret(obj handler.MapObject) []reconcile.Request {
+	webhookTransports.forget(obj.Meta.GetNamespace(), obj.Meta.GetName())
+	requests := r.notifiersReferencing(secretsField, obj)
+	for _, policy := range r.policiesReferencing(obj) {
+		requests = append(requests, r.notifiersReferencing(escalationPoliciesField, policy)...)
+	}
+	return requests
+}
+
+// policiesReferencing lists the EscalationPolicies with tier channels using the Secret
+func (r *NotifierReconciler) policiesReferencing(obj handler.MapObject) []handler.MapObject {
+	key := types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()}
+
+	policies := &emailv1.EscalationPolicyList{}
+	err := r.List(ctx.TODO(), policies, client.MatchingField(secretsField, key.String()))
+	if err != nil {
+		r.Log.Error(err, "Failed to list EscalationPolicies", "field", secretsField, "object", key)
+		return nil
+	}
+
+	objects := []handler.MapObject{}
+	for i := range policies.Items {
+		objects = append(objects, handler.MapObject{Meta: &policies.Items[i], Object: &policies.Items[i]})
+	}
+	return objects
+}
+
+// notifiersForConfigMap requeues every Notifier using the ConfigMap as its template
+func (r *NotifierReconciler) notifiersForConfigMap(obj handler.MapObject) []reconcile.Request {
+	return r.notifiersReferencing(configMapsField, obj)
+}
+
+func (r *NotifierReconciler) notifiersReferencing(field string, obj handler.MapObject) []reconcile.Request {
+	key := types.NamespacedName{Namespace: obj.Meta.GetNamespace(), Name: obj.Meta.GetName()}
+
+	notifiers := &emailv1.NotifierList{}
+	err := r.List(ctx.TODO(), notifiers, client.MatchingField(field, key.String()))
+	if err != nil {
+		r.Log.Error(err, "Failed to list Notifiers", "field", field, "object", key)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, notifier := range notifiers.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: notifier.GetNamespace(),
+			Name:      notifier.GetName(),
+		}})
+	}
+	return requests
+}
+
+// notifiersForEscalationPolicy requeues every Notifier escalating with the policy
+func (r *NotifierReconciler) notifiersForEscalationPolicy(obj handler.MapObject) []reconcile.Request {
+	return r.notifiersReferencing(escalationPoliciesField, obj)
+}
+
+// escalationPoliciesIndex is the EscalationPolicy of the Notifier
+func escalationPoliciesIndex(obj runtime.Object) []string {
+	notifier := obj.(*emailv1.Notifier)
+	name := notifier.GetEscalationPolicyName()
+	if name == "" {
+		return nil
+	}
+	return []string{types.NamespacedName{Namespace: notifier.GetNamespace(), Name: name}.String()}
+}
+
+// configMapsIndex is the ConfigMap holding the Notifier template
+func configMapsIndex(obj runtime.Object) []string {
+	notifier := obj.(*emailv1.Notifier)
+	name := notifier.Spec.Template.GetConfigMapName()
+	if name == "" {
+		return nil
+	}
+	return []string{types.NamespacedName{Namespace: notifier.GetNamespace(), Name: name}.String()}
+}
+
+// getTemplate parses the Notifier template, inline templates take precedence over the ConfigMap
+func (r *NotifierReconciler) getTemplate(notifier *emailv1.Notifier) (*messageTemplate, error) {
+	spec := notifier.Spec.Template
+	if spec == nil {
+		return nil, nil
+	}
 
-	capturedEvents := &corev1.EventList{}
+	subject, body := spec.Subject, spec.Body
+	if name := spec.GetConfigMapName(); name != "" {
+		key := types.NamespacedName{Namespace: notifier.GetNamespace(), Name: name}
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx.TODO(), key, configMap)
+		if k8serror.IsNotFound(err) {
+			return nil, errors.Wrapf(errInvalidConfig, "ConfigMap %s not found", key)
+		} else if err != nil {
+			return nil, err
+		}
+		if subject == "" {
+			subject = configMap.Data[emailv1.TemplateSubjectKey]
+		}
+		if body == "" {
+			body = configMap.Data[emailv1.TemplateBodyKey]
+		}
+	}
 
+	tmpl, err := parseMessageTemplate(subject, body, spec.HTML)
+	if err != nil {
+		return nil, errors.Wrap(errInvalidConfig, err.Error())
+	}
+	return tmpl, nil
+}
+
+// render applies the Notifier template, a failing template leaves the default message
+func (r *NotifierReconciler) render(notifier *emailv1.Notifier, template *messageTemplate, notification *Notification) {
+	if template == nil {
+		return
+	}
+	message, err := template.render(notification, r.ClusterName)
+	if err != nil {
+		r.Log.Info("Failed to render message template", "notifier", notifier.GetName(), "error", err.Error())
+		emailv1.SetCondition(&notifier.Status.Conditions, emailv1.ConditionInvalidTemplate, corev1.ConditionTrue, "RenderFailed", err.Error())
+		notifier.Status.LastError = err.Error()
+		return
+	}
+	notification.Message = message
+}
+
+// fetchLogs attaches the container logs when the Notifier asks for them, the notification is sent without them on errors
+func (r *NotifierReconciler) fetchLogs(notifier *emailv1.Notifier, event *corev1.Event, context *emailv1.ObjectContext) *ContainerLogs {
+	logs, err := fetchLogs(r.Logs, notifier.Spec.Logs, event, context)
+	if err != nil {
+		r.Log.Info("Failed to fetch container logs", "notifier", notifier.GetName(), "error", err.Error())
+	}
+	return logs
+}
+
+func recordNotifierIndex(obj runtime.Object) []string {
+	record := obj.(*emailv1.NotificationRecord)
+	return []string{recordNotifierKey(record.Spec.NotifierKind, record.Spec.Notifier)}
+}
+
+// getRecords lists all NotificationRecords of the Notifier, oldest first
+func (r *NotifierReconciler) getRecords(notifier *emailv1.Notifier) ([]emailv1.NotificationRecord, error) {
+	records := &emailv1.NotificationRecordList{}
 	err := r.List(
 		ctx.TODO(),
-		capturedEvents,
-		client.InNamespace(notify.GetNamespace()),
-		client.MatchingLabels(labelFilter))
+		records,
+		client.InNamespace(notifier.GetNamespace()),
+		client.MatchingField(recordNotifierField, recordNotifierKey(notifier.Kind, notifier.GetName())))
 	if err != nil {
 		return nil, err
 	}
 
-	return capturedEvents.Items, nil
+	sort.SliceStable(records.Items, func(i, j int) bool {
+		return records.Items[i].CreationTimestamp.Before(&records.Items[j].CreationTimestamp)
+	})
+	return records.Items, nil
+}
+
+// pruneRecords deletes records completed longer than RecordTTL ago, and returns when the next one expires
+func (r *NotifierReconciler) pruneRecords(records []emailv1.NotificationRecord) (time.Duration, error) {
+	if r.RecordTTL == 0 {
+		return 0, nil
+	}
+
+	var next time.Duration
+	for i := range records {
+		record := &records[i]
+		completed := record.CompletionTime()
+		if completed == nil {
+			continue
+		}
+
+		remaining := r.RecordTTL - time.Since(completed.Time)
+		if remaining > 0 {
+			if next == 0 || remaining < next {
+				next = remaining
+			}
+			continue
+		}
+		err := r.Delete(ctx.TODO(), record)
+		if client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+	}
+	return next, nil
 }
 
-func (r *NotifierReconciler) notify(notifier *emailv1.Notifier, events []corev1.Event) error {
-	for _, event := range events {
+// notify delivers every record in a notification of its own.
+// Records notified again are not duplicates, their own earlier notification is in the dedup state.
+func (r *NotifierReconciler) notify(notifier *emailv1.Notifier, channels []Channel, template *messageTemplate, records []emailv1.NotificationRecord, throttle *throttle) error {
+	for i := range records {
+		event := recordEvent(&records[i])
+		if records[i].Status.State != emailv1.RecordSent && throttle.duplicate(event) {
+			if err := r.suppress(notifier, records[i:i+1], SuppressedDuplicate); err != nil {
+				return err
+			}
+			continue
+		}
+		if !throttle.allow() {
+			if err := r.suppress(notifier, records[i:i+1], SuppressedRateLimited); err != nil {
+				return err
+			}
+			continue
+		}
+
 		r.Log.Info(fmt.Sprintf(`
-		Event occured! Email sent: %v
+		Event occured! Notifying %d channels
 		Reason: %v,
 		Message: %#v,
-		Pod: %v`,
-			notifier.GetEmail(),
+		%v: %v,
+		Workload: %v`,
+			len(channels),
 			event.Reason,
 			event.Message,
-			event.InvolvedObject.Name))
+			event.InvolvedObject.Kind,
+			event.InvolvedObject.Name,
+			records[i].Spec.Context.Workload()))
 
-		eventCopy := event.DeepCopy()
-		eventCopy.SetLabels(nil)
-		err := r.Update(ctx.TODO(), eventCopy)
+		notification := &Notification{Notifier: notifier, Event: event, Context: records[i].Spec.Context, Incident: records[i].Spec.Incident, Record: records[i].GetName()}
+		notification.Logs = r.fetchLogs(notifier, event, records[i].Spec.Context)
+		if notifier.GetEscalationPolicyName() != "" {
+			notification.AcknowledgeURL = r.Acknowledge.Link(&records[i])
+		}
+		r.render(notifier, template, notification)
+		err := r.deliverRecords(notifier, channels, records[i:i+1], notification)
+		if err != nil {
+			return err
+		}
+		if records[i].Status.State == emailv1.RecordSent {
+			throttle.sent([]*corev1.Event{event})
+		}
+	}
+
+	return nil
+}
+
+// notifyDigest delivers the records in digests once the window is over, and returns how long it still lasts
+func (r *NotifierReconciler) notifyDigest(notifier *emailv1.Notifier, channels []Channel, template *messageTemplate, records []emailv1.NotificationRecord, throttle *throttle) (time.Duration, error) {
+	policy := notifier.Spec.Digest
+	due, wait := digestDue(policy, records, throttle.now)
+	if !due {
+		return wait, nil
+	}
+
+	unique := []emailv1.NotificationRecord{}
+	for i := range records {
+		if records[i].Status.State == emailv1.RecordSent || !throttle.duplicate(recordEvent(&records[i])) {
+			unique = append(unique, records[i])
+		} else if err := r.suppress(notifier, records[i:i+1], SuppressedDuplicate); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, batch := range digestBatches(policy, unique) {
+		if !throttle.allow() {
+			if err := r.suppress(notifier, batch, SuppressedRateLimited); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		events := []*corev1.Event{}
+		names := []string{}
+		for i := range batch {
+			events = append(events, recordEvent(&batch[i]))
+			names = append(names, batch[i].GetName())
+		}
+		notification := &Notification{Notifier: notifier, Digest: groupDigest(events), Records: names}
+		r.render(notifier, template, notification)
+		r.Log.Info("Sending digest", "notifier", notifier.GetName(), "events", len(events), "groups", len(notification.Digest))
+
+		err := r.deliverRecords(notifier, channels, batch, notification)
+		if err != nil {
+			return 0, err
+		}
+		if batch[0].Status.State == emailv1.RecordSent {
+			throttle.sent(events)
+		}
+	}
+	return 0, nil
+}
+
+// suppressSilenced drops the records silenced when the Event was recorded, and returns the others.
+// Sent records stay sent, their repeated occurrences are notified once the Event recurs unsilenced.
+func (r *NotifierReconciler) suppressSilenced(notifier *emailv1.Notifier, records []emailv1.NotificationRecord) ([]emailv1.NotificationRecord, error) {
+	unsilenced := []emailv1.NotificationRecord{}
+	for i := range records {
+		if records[i].Spec.Silenced == "" {
+			unsilenced = append(unsilenced, records[i])
+			continue
+		}
+		if !records[i].IsPending() {
+			continue
+		}
+		if err := r.suppress(notifier, records[i:i+1], records[i].Spec.Silenced); err != nil {
+			return nil, err
+		}
+	}
+	return unsilenced, nil
+}
+
+// suppress drops the records without delivery, counting them in the Notifier status.
+// Records grouped into an Incident are notified through it, they are counted apart.
+func (r *NotifierReconciler) suppress(notifier *emailv1.Notifier, records []emailv1.NotificationRecord, reason string) error {
+	now := metav1.Now()
+	for i := range records {
+		record := &records[i]
+		record.Status.State = emailv1.RecordSuppressed
+		record.Status.SuppressedBy = reason
+		record.Status.SuppressedTime = &now
+		err := r.Status().Update(ctx.TODO(), record)
+		if err != nil {
+			return err
+		}
+		if isGrouped(record) {
+			notifier.Status.GroupedCount++
+		} else {
+			notifier.Status.SuppressedCount++
+		}
+	}
+	return nil
+}
+
+// deliverRecords sends the notification covering the records, and records the outcome in their status.
+// A failed delivery is retried with a backoff, until the records are dead-lettered.
+// Only failures to update the records are returned.
+func (r *NotifierReconciler) deliverRecords(notifier *emailv1.Notifier, channels []Channel, records []emailv1.NotificationRecord, notification *Notification) error {
+	// Claim the attempt first, a conflict means our cache is stale and the records may be sent already
+	now := metav1.Now()
+	for i := range records {
+		record := &records[i]
+		record.Status.Attempts++
+		record.Status.LastAttemptTime = &now
+		err := r.Status().Update(ctx.TODO(), record)
 		if err != nil {
 			return err
 		}
 	}
 
+	sendErr := r.send(notifier, channels, records, notification)
+	policy := notifier.Spec.Retry
+	for i := range records {
+		record := &records[i]
+		record.Status.NextAttemptTime = nil
+		switch {
+		case sendErr == nil:
+			record.Status.State = emailv1.RecordSent
+			record.Status.SentTime = &now
+			record.Status.NotifiedCount = record.Spec.Event.Count
+			record.Status.FailedAttempts = 0
+			record.Status.DeliveredTo = nil
+			record.Status.LastError = ""
+			if name := notifier.GetEscalationPolicyName(); name != "" && record.Status.Escalation == nil {
+				// The tiers are scheduled by escalate, which reads the policy
+				record.Status.Escalation = &emailv1.EscalationStatus{Policy: name, StartTime: now, NextTime: &now}
+			}
+			observeLatency(record, now.Time)
+		case record.Status.FailedAttempts+1 >= policy.GetMaxAttempts():
+			record.Status.State = emailv1.RecordDeadLettered
+			record.Status.FailedAttempts++
+			record.Status.LastError = sendErr.Error()
+		default:
+			record.Status.State = emailv1.RecordFailed
+			record.Status.FailedAttempts++
+			record.Status.LastError = sendErr.Error()
+			next := metav1.NewTime(now.Add(retryBackoff(policy, record.Status.FailedAttempts)))
+			record.Status.NextAttemptTime = &next
+		}
+
+		err := r.Status().Update(ctx.TODO(), record)
+		if err != nil {
+			return err
+		}
+		if record.Status.State == emailv1.RecordDeadLettered {
+			r.deadLetter(notifier, record)
+		}
+	}
+	return nil
+}
+
+// deadLetter reports a record given up after the last retry
+func (r *NotifierReconciler) deadLetter(notifier *emailv1.Notifier, record *emailv1.NotificationRecord) {
+	notifier.Status.DeadLetteredCount++
+	r.Log.Info("Giving up on notification", "notifier", notifier.GetName(), "record", record.GetName(),
+		"attempts", record.Status.FailedAttempts, "error", record.Status.LastError)
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(notifierObject(notifier), corev1.EventTypeWarning, "DeadLettered",
+		"Gave up on %s about %s %s after %d attempts: %s",
+		record.Spec.Event.Reason,
+		record.Spec.Event.InvolvedObject.Kind,
+		referenceName(record.Spec.Event.InvolvedObject),
+		record.Status.FailedAttempts,
+		record.Status.LastError)
+}
+
+// send delivers the notification through every channel, counting the attempts in the Notifier status.
+// Channels which received the notification on an earlier attempt are skipped.
+func (r *NotifierReconciler) send(notifier *emailv1.Notifier, channels []Channel, records []emailv1.NotificationRecord, notification *Notification) error {
+	for _, channel := range channels {
+		key := channelKey(channel)
+		if delivered(records, key) {
+			continue
+		}
+		err := channel.Send(notification)
+		if err != nil {
+			observeFailed(notifier, channel)
+			notifier.Status.FailedCount++
+			return err
+		}
+		observeSent(notifier, channel)
+		notifier.Status.DeliveredCount++
+		now := metav1.Now()
+		notifier.Status.LastNotificationTime = &now
+		for i := range records {
+			if !containsString(records[i].Status.DeliveredTo, key) {
+				records[i].Status.DeliveredTo = append(records[i].Status.DeliveredTo, key)
+			}
+		}
+	}
+	return nil
+}
+
+// sendAll delivers the notification through every channel, counting them like regular deliveries.
+// Unlike send, there are no records to retry: the channels which got the notification are tracked in the deliveredTo lists,
+// and skipped when every list has them already.
+func (r *NotifierReconciler) sendAll(notifier *emailv1.Notifier, channels []Channel, notification *Notification, deliveredTo ...*[]string) error {
+	for _, channel := range channels {
+		key := channelKey(channel)
+		if deliveredToAll(deliveredTo, key) {
+			continue
+		}
+		if err := channel.Send(notification); err != nil {
+			observeFailed(notifier, channel)
+			notifier.Status.FailedCount++
+			return err
+		}
+		observeSent(notifier, channel)
+		notifier.Status.DeliveredCount++
+		for _, list := range deliveredTo {
+			if !containsString(*list, key) {
+				*list = append(*list, key)
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,1156 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	emailv1 "std/api/v1"
+)
+
+const (
+	timeout  = 10 * time.Second
+	interval = 100 * time.Millisecond
+)
+
+func newNotifier(name, email string, filters ...string) *emailv1.Notifier {
+	return &emailv1.Notifier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: emailv1.NotifierSpec{
+			Email:   email,
+			Filters: filters,
+		},
+	}
+}
+
+func newWarningEvent(name, reason, kind, object string) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      kind,
+			Name:      object,
+			Namespace: "default",
+		},
+		Type:    corev1.EventTypeWarning,
+		Reason:  reason,
+		Message: "Back-off restarting failed container",
+	}
+}
+
+func getNotifierStatus(notifier *emailv1.Notifier) emailv1.NotifierStatus {
+	fetched := &emailv1.Notifier{}
+	err := k8sClient.Get(context.TODO(), types.NamespacedName{
+		Namespace: notifier.GetNamespace(),
+		Name:      notifier.GetName(),
+	}, fetched)
+	Expect(err).NotTo(HaveOccurred())
+	return fetched.Status
+}
+
+func conditionStatus(notifier *emailv1.Notifier, conditionType emailv1.ConditionType) func() corev1.ConditionStatus {
+	return func() corev1.ConditionStatus {
+		condition := emailv1.FindCondition(getNotifierStatus(notifier).Conditions, conditionType)
+		if condition == nil {
+			return ""
+		}
+		return condition.Status
+	}
+}
+
+var _ = Describe("NotifierReconciler", func() {
+	var notifier *emailv1.Notifier
+
+	BeforeEach(func() {
+		smtpServer.Reset()
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(context.TODO(), notifier)).To(Succeed())
+	})
+
+	It("should email matching Pod warnings", func() {
+		notifier = newNotifier("mail-backoff", "backoff@example.com", "BackOff")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("test-pod.backoff", "BackOff", "Pod", "test-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("backoff@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+
+		mail := smtpServer.MessagesTo("backoff@example.com")[0]
+		Expect(mailHeader(mail.Data, "Subject")).To(ContainSubstring("BackOff"))
+		Expect(mail.Data).To(ContainSubstring("Back-off restarting failed container"))
+		Expect(mail.Data).To(ContainSubstring("Pod: default/test-pod"))
+	})
+
+	It("should not email warnings which do not match", func() {
+		notifier = newNotifier("mail-oom", "oom@example.com", "OOMKilling")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("test-pod.failed", "FailedMount", "Pod", "test-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("oom@example.com")
+		}, 2*time.Second, interval).Should(BeEmpty())
+	})
+
+	It("should notify every matching Notifier without touching the Event", func() {
+		notifier = newNotifier("mail-first", "first@example.com", "FailedScheduling")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+		second := newNotifier("mail-second", "second@example.com", "Failed")
+		Expect(k8sClient.Create(context.TODO(), second)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), second)
+
+		event := newWarningEvent("shared-pod.failedscheduling", "FailedScheduling", "Pod", "shared-pod")
+		event.SetLabels(map[string]string{"app": "shared"})
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("first@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("second@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+
+		Eventually(func() []emailv1.RecordState {
+			records := &emailv1.NotificationRecordList{}
+			Expect(k8sClient.List(context.TODO(), records, client.InNamespace("default"))).To(Succeed())
+			states := []emailv1.RecordState{}
+			for _, record := range records.Items {
+				if record.Spec.EventRef.Name == event.GetName() {
+					states = append(states, record.Status.State)
+				}
+			}
+			return states
+		}, timeout, interval).Should(ConsistOf(emailv1.RecordSent, emailv1.RecordSent))
+
+		fetched := &corev1.Event{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{
+			Namespace: event.GetNamespace(),
+			Name:      event.GetName(),
+		}, fetched)).To(Succeed())
+		Expect(fetched.GetLabels()).To(Equal(map[string]string{"app": "shared"}))
+		Expect(fetched.GetOwnerReferences()).To(BeEmpty())
+		Expect(fetched.GetResourceVersion()).To(Equal(event.GetResourceVersion()))
+	})
+
+	It("should match Events by the involved object labels", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "labeled-pod",
+				Namespace: "default",
+				Labels:    map[string]string{"team": "payments"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "busybox"}}},
+		}
+		Expect(k8sClient.Create(context.TODO(), pod)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), pod)
+
+		notifier = newNotifier("match-payments", "payments@example.com")
+		notifier.Spec.Match = &emailv1.EventFilter{
+			All: []emailv1.EventMatcher{{
+				Kind:   &emailv1.StringMatch{Exact: "Pod"},
+				Labels: map[string]emailv1.StringMatch{"team": {Exact: "payments"}},
+			}},
+			None: []emailv1.EventMatcher{{Reason: &emailv1.StringMatch{Glob: "*Probe*"}}},
+		}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		for _, event := range []*corev1.Event{
+			newWarningEvent("labeled-pod.probe", "FailedProbe", "Pod", "labeled-pod"),
+			newWarningEvent("unlabeled-pod.failed", "Failed", "Pod", "unlabeled-pod"),
+			newWarningEvent("labeled-pod.failed", "Failed", "Pod", "labeled-pod"),
+		} {
+			event.InvolvedObject.APIVersion = "v1"
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		}
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("payments@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("payments@example.com")
+		}, time.Second, interval).Should(HaveLen(1))
+		Expect(mailHeader(smtpServer.MessagesTo("payments@example.com")[0].Data, "Subject")).
+			To(Equal("[match-payments] Failed: default/labeled-pod"))
+	})
+
+	It("should tell the owner workload and containers of the Pod", func() {
+		labels := map[string]string{"app": "owned"}
+		template := corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "busybox"}}},
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "owned", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+		}
+		Expect(k8sClient.Create(context.TODO(), deployment)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), deployment)
+
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "owned-5d8", Namespace: "default"},
+			Spec:       appsv1.ReplicaSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+		}
+		Expect(controllerutil.SetControllerReference(deployment, replicaSet, scheme.Scheme)).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), replicaSet)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), replicaSet)
+
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owned-5d8-x2k", Namespace: "default", Labels: labels}, Spec: template.Spec}
+		pod.Spec.NodeName = "node-1"
+		Expect(controllerutil.SetControllerReference(replicaSet, pod, scheme.Scheme)).To(Succeed())
+		Expect(k8sClient.Create(context.TODO(), pod)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), pod)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:                 "app",
+			Image:                "busybox",
+			RestartCount:         4,
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 2}},
+		}}
+		Expect(k8sClient.Status().Update(context.TODO(), pod)).To(Succeed())
+
+		notifier = newNotifier("owned-workload", "owned@example.com", "BackOff")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("owned-5d8-x2k.backoff", "BackOff", "Pod", "owned-5d8-x2k")
+		event.InvolvedObject.APIVersion = "v1"
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("owned@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		mail := smtpServer.MessagesTo("owned@example.com")[0]
+		Expect(mail.Data).To(ContainSubstring("Workload: Deployment/owned"))
+		Expect(mail.Data).To(ContainSubstring("Node: node-1"))
+		Expect(mail.Data).To(ContainSubstring("Container app: Waiting CrashLoopBackOff, 4 restarts, exit code 2 (Error)"))
+
+		records := &emailv1.NotificationRecordList{}
+		Expect(k8sClient.List(context.TODO(), records, client.InNamespace("default"))).To(Succeed())
+		var resolved *emailv1.ObjectContext
+		for _, record := range records.Items {
+			if record.Spec.Notifier == notifier.GetName() {
+				resolved = record.Spec.Context
+			}
+		}
+		Expect(resolved).NotTo(BeNil())
+		Expect(resolved.Owners).To(Equal([]emailv1.WorkloadReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "owned-5d8"},
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "owned"},
+		}))
+	})
+
+	It("should attach the previous logs of the crashing container", func() {
+		podLogs.Set("default", "crashing-pod", "app", "connecting to db:5432\npanic: connection refused\n")
+
+		notifier = newNotifier("crash-logs", "logs@example.com", "BackOff")
+		notifier.Spec.Logs = &emailv1.LogsPolicy{TailLines: 1}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("crashing-pod.backoff", "BackOff", "Pod", "crashing-pod")
+		event.InvolvedObject.FieldPath = "spec.containers{app}"
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("logs@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		mail := smtpServer.MessagesTo("logs@example.com")[0]
+		Expect(mail.Data).To(ContainSubstring("Previous logs of container app:\r\npanic: connection refused"))
+		Expect(mail.Data).NotTo(ContainSubstring("connecting to db"))
+	})
+
+	It("should notify about the watched kinds only", func() {
+		notifier = newNotifier("kinds-workloads", "workloads@example.com", "Failed")
+		notifier.Spec.Kinds = []string{"Node", "PersistentVolumeClaim", "Deployment", "Job", "CronJob"}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+		pods := newNotifier("kinds-pods", "pods@example.com", "Failed")
+		Expect(k8sClient.Create(context.TODO(), pods)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), pods)
+
+		for _, kind := range notifier.Spec.Kinds {
+			object := strings.ToLower(kind) + "-failing"
+			event := newWarningEvent(object+".failed", "FailedSync", kind, object)
+			if kind == "Node" {
+				event.InvolvedObject.Namespace = ""
+			}
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		}
+		event := newWarningEvent("kinds-pod.failed", "FailedSync", "Pod", "kinds-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []string {
+			subjects := []string{}
+			for _, mail := range smtpServer.MessagesTo("workloads@example.com") {
+				subjects = append(subjects, mailHeader(mail.Data, "Subject"))
+			}
+			return subjects
+		}, timeout, interval).Should(ConsistOf(
+			"[kinds-workloads] FailedSync: node-failing",
+			"[kinds-workloads] FailedSync: default/persistentvolumeclaim-failing",
+			"[kinds-workloads] FailedSync: default/deployment-failing",
+			"[kinds-workloads] FailedSync: default/job-failing",
+			"[kinds-workloads] FailedSync: default/cronjob-failing",
+		))
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("pods@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		Expect(mailHeader(smtpServer.MessagesTo("pods@example.com")[0].Data, "Subject")).
+			To(Equal("[kinds-pods] FailedSync: default/kinds-pod"))
+	})
+
+	It("should ignore kinds no Notifier watches", func() {
+		notifier = newNotifier("kinds-default", "default@example.com", "Failed")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("ignored-job.failed", "BackoffLimitExceeded", "Job", "ignored-job")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("default@example.com")
+		}, 2*time.Second, interval).Should(BeEmpty())
+	})
+
+	It("should notify again after more occurrences", func() {
+		notifier = newNotifier("renotify-crashloop", "crashloop@example.com", "BackOff")
+		notifier.Spec.Renotify = &emailv1.RenotifyPolicy{AfterCount: 2}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("crashloop-pod.backoff", "BackOff", "Pod", "crashloop-pod")
+		event.Count = 1
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("crashloop@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+
+		occur := func() {
+			fetched := &corev1.Event{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{
+				Namespace: event.GetNamespace(),
+				Name:      event.GetName(),
+			}, fetched)).To(Succeed())
+			fetched.Count++
+			Expect(k8sClient.Update(context.TODO(), fetched)).To(Succeed())
+		}
+
+		occur()
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("crashloop@example.com")
+		}, time.Second, interval).Should(HaveLen(1))
+
+		occur()
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("crashloop@example.com")
+		}, timeout, interval).Should(HaveLen(2))
+		Expect(smtpServer.MessagesTo("crashloop@example.com")[1].Data).To(ContainSubstring("Occurrences: 3"))
+	})
+
+	It("should notify an Event once without a renotify policy", func() {
+		notifier = newNotifier("renotify-none", "once@example.com", "BackOff")
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("once-pod.backoff", "BackOff", "Pod", "once-pod")
+		event.Count = 1
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("once@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+
+		event.Count = 10
+		Expect(k8sClient.Update(context.TODO(), event)).To(Succeed())
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("once@example.com")
+		}, 2*time.Second, interval).Should(HaveLen(1))
+	})
+
+	It("should batch Events into a digest", func() {
+		notifier = newNotifier("digest-node", "digest@example.com", "NodeNotReady|BackOff")
+		notifier.Spec.Digest = &emailv1.DigestPolicy{Window: metav1.Duration{Duration: 2 * time.Second}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		for _, pod := range []string{"digest-1", "digest-2", "digest-3"} {
+			event := newWarningEvent(pod+".notready", "NodeNotReady", "Pod", pod)
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		}
+		event := newWarningEvent("digest-1.backoff", "BackOff", "Pod", "digest-1")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("digest@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		Consistently(func() []receivedMail {
+			return smtpServer.MessagesTo("digest@example.com")
+		}, 3*time.Second, interval).Should(HaveLen(1))
+
+		mail := smtpServer.MessagesTo("digest@example.com")[0]
+		Expect(mailHeader(mail.Data, "Subject")).To(Equal("[digest-node] Digest: 4 events in 4 groups"))
+		Expect(mail.Data).To(ContainSubstring("NodeNotReady - Pod default/digest-3"))
+	})
+
+	It("should send a burst of identical Events once", func() {
+		notifier = newNotifier("dedup-mount", "dedup@example.com", "FailedMount")
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: time.Hour}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		for i := 0; i < 10; i++ {
+			event := newWarningEvent("dedup-pod.mount-"+strconv.Itoa(i), "FailedMount", "Pod", "dedup-pod")
+			event.Message = "Unable to attach volumes after " + strconv.Itoa(i) + " attempts"
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		}
+
+		Eventually(func() int64 {
+			return getNotifierStatus(notifier).SuppressedCount
+		}, timeout, interval).Should(BeEquivalentTo(9))
+		Expect(smtpServer.MessagesTo("dedup@example.com")).To(HaveLen(1))
+		Expect(getNotifierStatus(notifier).Dedup).To(HaveLen(1))
+	})
+
+	It("should drop notifications over the rate limit", func() {
+		notifier = newNotifier("ratelimit-mount", "ratelimit@example.com", "FailedMount")
+		notifier.Spec.RateLimit = &emailv1.RateLimitPolicy{Burst: 2, Every: metav1.Duration{Duration: time.Hour}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		for i := 0; i < 5; i++ {
+			pod := "ratelimit-pod-" + strconv.Itoa(i)
+			Expect(k8sClient.Create(context.TODO(), newWarningEvent(pod+".mount", "FailedMount", "Pod", pod))).To(Succeed())
+		}
+
+		Eventually(func() int64 {
+			return getNotifierStatus(notifier).SuppressedCount
+		}, timeout, interval).Should(BeEquivalentTo(3))
+		Expect(smtpServer.MessagesTo("ratelimit@example.com")).To(HaveLen(2))
+		Expect(getNotifierStatus(notifier).RateLimit.Tokens).To(BeZero())
+	})
+
+	It("should render the message template from a ConfigMap", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "mail-template", Namespace: "default"},
+			Data: map[string]string{
+				emailv1.TemplateSubjectKey: "{{ .Object.Name }} is failing",
+				emailv1.TemplateBodyKey:    "<p>{{ .Event.Reason }} in {{ .Notifier.Name }}</p>",
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), configMap)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), configMap)
+
+		notifier = newNotifier("mail-template", "template@example.com", "Unhealthy")
+		notifier.Spec.Template = &emailv1.MessageTemplate{
+			HTML:         true,
+			ConfigMapRef: &corev1.LocalObjectReference{Name: "mail-template"},
+		}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("template-pod.unhealthy", "Unhealthy", "Pod", "template-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("template@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+
+		mail := smtpServer.MessagesTo("template@example.com")[0]
+		Expect(mailHeader(mail.Data, "Subject")).To(Equal("template-pod is failing"))
+		Expect(mail.Data).To(ContainSubstring("<p>Unhealthy in mail-template</p>"))
+		Expect(conditionStatus(notifier, emailv1.ConditionInvalidTemplate)()).To(Equal(corev1.ConditionFalse))
+	})
+
+	It("should fall back to the default message when the template fails", func() {
+		notifier = newNotifier("mail-broken-template", "broken@example.com", "ProbeWarning")
+		notifier.Spec.Template = &emailv1.MessageTemplate{Subject: "{{ .Event.Reason.Missing }}"}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("broken-pod.probe", "ProbeWarning", "Pod", "broken-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(func() []receivedMail {
+			return smtpServer.MessagesTo("broken@example.com")
+		}, timeout, interval).Should(HaveLen(1))
+		mail := smtpServer.MessagesTo("broken@example.com")[0]
+		Expect(mailHeader(mail.Data, "Subject")).To(Equal("[mail-broken-template] ProbeWarning: default/broken-pod"))
+
+		Eventually(conditionStatus(notifier, emailv1.ConditionInvalidTemplate), timeout, interval).
+			Should(Equal(corev1.ConditionTrue))
+		condition := emailv1.FindCondition(getNotifierStatus(notifier).Conditions, emailv1.ConditionInvalidTemplate)
+		Expect(condition.Reason).To(Equal("RenderFailed"))
+	})
+
+	It("should post to channels with the URL from a Secret", func() {
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "slack-webhook",
+				Namespace: "default",
+			},
+			StringData: map[string]string{"url": recorder.URL},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), secret)
+
+		notifier = newNotifier("slack-unhealthy", "", "Unhealthy")
+		notifier.Spec.Channels = []emailv1.Channel{{
+			Type: emailv1.SlackChannel,
+			URLSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.GetName()},
+				Key:                  "url",
+			},
+		}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("slack-pod.unhealthy", "Unhealthy", "Pod", "slack-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(recorder.Payloads, timeout, interval).Should(HaveLen(1))
+		Expect(recorder.Payloads()[0]["text"]).To(Equal("[slack-unhealthy] Unhealthy: default/slack-pod"))
+	})
+
+	It("should sign webhooks once the signing Secret appears", func() {
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+
+		notifier = newNotifier("signed-hooks", "", "FailedCreatePodContainer")
+		notifier.Spec.Channels = []emailv1.Channel{{
+			Type: emailv1.WebhookChannel,
+			URL:  recorder.URL,
+			Webhook: &emailv1.WebhookOptions{
+				SigningSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "late-signing"},
+					Key:                  "key",
+				},
+			},
+		}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+		Eventually(func() string {
+			return getNotifierStatus(notifier).LastError
+		}, timeout, interval).Should(ContainSubstring("late-signing not found"))
+
+		event := newWarningEvent("hooked-pod.failedcreatepodcontainer", "FailedCreatePodContainer", "Pod", "hooked-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+		Consistently(recorder.Payloads, time.Second, interval).Should(BeEmpty())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "late-signing", Namespace: "default"},
+			StringData: map[string]string{"key": "s3cret"},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), secret)
+
+		Eventually(recorder.Payloads, timeout, interval).Should(HaveLen(1))
+		Expect(recorder.LastRequest().Header.Get(SignatureHeader)).To(Equal(signBody([]byte("s3cret"), recorder.LastBody())))
+	})
+
+	It("should trigger PagerDuty alerts with the routing key from a Secret", func() {
+		standIn := newPagerDutyStandIn()
+		defer standIn.Close()
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pagerduty", Namespace: "default"},
+			StringData: map[string]string{"routingKey": testRoutingKey},
+		}
+		Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+		defer k8sClient.Delete(context.TODO(), secret)
+
+		notifier = newNotifier("pagerduty-killing", "", "FailedKillPod")
+		notifier.Spec.Channels = []emailv1.Channel{{
+			Type: emailv1.PagerDutyChannel,
+			URL:  standIn.URL + "/v2/enqueue",
+			RoutingKeySecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.GetName()},
+				Key:                  "routingKey",
+			},
+		}}
+		Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+		event := newWarningEvent("unkillable-pod.failedkillpod", "FailedKillPod", "Pod", "unkillable-pod")
+		Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+		Eventually(standIn.Events, timeout, interval).Should(HaveLen(1))
+		Expect(standIn.Events()[0]["event_action"]).To(Equal("trigger"))
+		Expect(standIn.Events()[0]["dedup_key"]).To(Equal("default/" + recordName(notifier, event)))
+	})
+
+	Context("status", func() {
+		It("should count deliveries and report Ready", func() {
+			notifier = newNotifier("status-delivered", "delivered@example.com", "Failed")
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+			Eventually(conditionStatus(notifier, emailv1.ConditionReady), timeout, interval).Should(Equal(corev1.ConditionTrue))
+
+			event := newWarningEvent("delivered-pod.failed", "Failed", "Pod", "delivered-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() int64 {
+				return getNotifierStatus(notifier).DeliveredCount
+			}, timeout, interval).Should(BeEquivalentTo(1))
+			status := getNotifierStatus(notifier)
+			Expect(status.LastNotificationTime).NotTo(BeNil())
+			Expect(status.ObservedGeneration).To(Equal(notifier.GetGeneration()))
+			Expect(emailv1.IsConditionTrue(status.Conditions, emailv1.ConditionDeliveryDegraded)).To(BeFalse())
+			Expect(emailv1.IsConditionTrue(status.Conditions, emailv1.ConditionInvalidFilter)).To(BeFalse())
+		})
+
+		It("should export the pipeline metrics", func() {
+			observed := scrapeMetric(`notifier_events_observed_total{type="Warning"}`)
+			latencies := scrapeMetric("notifier_delivery_latency_seconds_count")
+			notifier = newNotifier("status-metrics", "metrics@example.com", "FailedSync")
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("metrics-pod.failedsync", "FailedSync", "Pod", "metrics-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() float64 {
+				return scrapeMetric(`notifier_notifications_sent_total{channel="email",namespace="default",notifier="status-metrics"}`)
+			}, timeout, interval).Should(BeEquivalentTo(1))
+			Expect(scrapeMetric(`notifier_events_matched_total{namespace="default",notifier="status-metrics"}`)).To(BeEquivalentTo(1))
+			Expect(scrapeMetric(`notifier_events_observed_total{type="Warning"}`)).To(BeNumerically(">", observed))
+			Expect(scrapeMetric("notifier_delivery_latency_seconds_count")).To(BeNumerically(">", latencies))
+			Eventually(func() float64 {
+				return scrapeMetric(`notifier_pending_notifications{namespace="default",notifier="status-metrics"}`)
+			}, timeout, interval).Should(BeEquivalentTo(0))
+		})
+
+		It("should report invalid filters", func() {
+			notifier = newNotifier("status-invalid", "invalid@example.com", "Back(Off")
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			Eventually(conditionStatus(notifier, emailv1.ConditionInvalidFilter), timeout, interval).Should(Equal(corev1.ConditionTrue))
+			Expect(conditionStatus(notifier, emailv1.ConditionReady)()).To(Equal(corev1.ConditionFalse))
+			Expect(getNotifierStatus(notifier).LastError).To(ContainSubstring(`invalid filter "Back(Off"`))
+		})
+
+		It("should notify the other Notifiers despite an invalid one", func() {
+			notifier = newNotifier("status-valid", "valid@example.com", "FailedAttach")
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+			invalid := newNotifier("status-invalid-neighbour", "neighbour@example.com", "Failed(Attach")
+			Expect(k8sClient.Create(context.TODO(), invalid)).To(Succeed())
+			defer k8sClient.Delete(context.TODO(), invalid)
+
+			event := newWarningEvent("attach-pod.failedattach", "FailedAttach", "Pod", "attach-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("valid@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("neighbour@example.com")).To(BeEmpty())
+		})
+
+		It("should report failed deliveries as degraded", func() {
+			recorder := newWebhookRecorder()
+			defer recorder.Close()
+			recorder.RespondWith(http.StatusInternalServerError)
+
+			notifier = newNotifier("status-degraded", "", "Evicted")
+			notifier.Spec.Channels = []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL}}
+			notifier.Spec.Retry = &emailv1.RetryPolicy{Backoff: &metav1.Duration{Duration: time.Second}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("degraded-pod.evicted", "Evicted", "Pod", "degraded-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(conditionStatus(notifier, emailv1.ConditionDeliveryDegraded), timeout, interval).Should(Equal(corev1.ConditionTrue))
+			status := getNotifierStatus(notifier)
+			Expect(status.FailedCount).To(BeNumerically(">=", 1))
+			Expect(status.LastError).To(ContainSubstring("500 Internal Server Error"))
+
+			recorder.RespondWith(http.StatusOK)
+			Eventually(conditionStatus(notifier, emailv1.ConditionDeliveryDegraded), timeout, interval).Should(Equal(corev1.ConditionFalse))
+			Expect(getNotifierStatus(notifier).DeliveredCount).To(BeEquivalentTo(1))
+		})
+	})
+
+	Context("retry", func() {
+		var recorder *webhookRecorder
+
+		BeforeEach(func() {
+			recorder = newWebhookRecorder()
+			recorder.RespondWith(http.StatusServiceUnavailable)
+		})
+
+		AfterEach(func() {
+			recorder.Close()
+		})
+
+		getRecord := func(event *corev1.Event) func() emailv1.NotificationRecordStatus {
+			return func() emailv1.NotificationRecordStatus {
+				fetched := &emailv1.NotificationRecord{}
+				key := types.NamespacedName{Namespace: "default", Name: recordName(notifier, event)}
+				if err := k8sClient.Get(context.TODO(), key, fetched); err != nil {
+					return emailv1.NotificationRecordStatus{}
+				}
+				return fetched.Status
+			}
+		}
+
+		It("should dead-letter notifications after the last attempt", func() {
+			notifier = newNotifier("retry-dead-letter", "", "FailedCreate")
+			notifier.Spec.Channels = []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL}}
+			notifier.Spec.Retry = &emailv1.RetryPolicy{MaxAttempts: 3, Backoff: &metav1.Duration{Duration: 200 * time.Millisecond}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("dead-letter-pod.failedcreate", "FailedCreate", "Pod", "dead-letter-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() emailv1.RecordState {
+				return getRecord(event)().State
+			}, timeout, interval).Should(Equal(emailv1.RecordDeadLettered))
+			status := getRecord(event)()
+			Expect(status.Attempts).To(BeEquivalentTo(3))
+			Expect(status.FailedAttempts).To(BeEquivalentTo(3))
+			Expect(status.NextAttemptTime).To(BeNil())
+			Expect(status.LastError).To(ContainSubstring("503 Service Unavailable"))
+			Expect(recorder.Payloads()).To(HaveLen(3))
+
+			Eventually(func() int64 {
+				return getNotifierStatus(notifier).DeadLetteredCount
+			}, timeout, interval).Should(BeEquivalentTo(1))
+
+			Eventually(func() []string {
+				events := &corev1.EventList{}
+				Expect(k8sClient.List(context.TODO(), events, client.InNamespace("default"))).To(Succeed())
+				reasons := []string{}
+				for _, event := range events.Items {
+					if event.InvolvedObject.Kind == "Notifier" && event.InvolvedObject.Name == notifier.GetName() {
+						reasons = append(reasons, event.Reason)
+					}
+				}
+				return reasons
+			}, timeout, interval).Should(ConsistOf("DeadLettered"))
+
+			// Dead-lettered records are not retried anymore
+			recorder.RespondWith(http.StatusOK)
+			Consistently(recorder.Payloads, time.Second, interval).Should(HaveLen(3))
+		})
+
+		It("should not send again to the channels which got the notification", func() {
+			notifier = newNotifier("retry-partial", "partial@example.com", "FailedMount")
+			notifier.Spec.Channels = []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL}}
+			notifier.Spec.Retry = &emailv1.RetryPolicy{Backoff: &metav1.Duration{Duration: time.Second}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("partial-pod.failedmount", "FailedMount", "Pod", "partial-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() emailv1.RecordState {
+				return getRecord(event)().State
+			}, timeout, interval).Should(Equal(emailv1.RecordFailed))
+			status := getRecord(event)()
+			Expect(status.DeliveredTo).To(Equal([]string{"email:partial@example.com"}))
+			Expect(status.NextAttemptTime).NotTo(BeNil())
+
+			recorder.RespondWith(http.StatusOK)
+			Eventually(func() emailv1.RecordState {
+				return getRecord(event)().State
+			}, timeout, interval).Should(Equal(emailv1.RecordSent))
+			Expect(getRecord(event)().DeliveredTo).To(BeEmpty())
+			Expect(smtpServer.MessagesTo("partial@example.com")).To(HaveLen(1))
+		})
+	})
+
+	Context("silences", func() {
+		getSuppressedBy := func(event *corev1.Event) func() string {
+			return func() string {
+				fetched := &emailv1.NotificationRecord{}
+				key := types.NamespacedName{Namespace: "default", Name: recordName(notifier, event)}
+				if err := k8sClient.Get(context.TODO(), key, fetched); err != nil {
+					return ""
+				}
+				return fetched.Status.SuppressedBy
+			}
+		}
+
+		It("should suppress Events matching an active Silence", func() {
+			notifier = newNotifier("silenced", "silenced@example.com", "Unhealthy")
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			silence := &emailv1.Silence{
+				ObjectMeta: metav1.ObjectMeta{Name: "maintenance", Namespace: "default"},
+				Spec: emailv1.SilenceSpec{
+					Match: emailv1.EventFilter{All: []emailv1.EventMatcher{{
+						Name: &emailv1.StringMatch{Glob: "maintained-*"},
+					}}},
+					EndsAt:    metav1.NewTime(time.Now().Add(time.Hour)),
+					CreatedBy: "ops@example.com",
+				},
+			}
+			Expect(k8sClient.Create(context.TODO(), silence)).To(Succeed())
+			defer k8sClient.Delete(context.TODO(), silence)
+
+			silenced := newWarningEvent("maintained-pod.unhealthy", "Unhealthy", "Pod", "maintained-pod")
+			Expect(k8sClient.Create(context.TODO(), silenced)).To(Succeed())
+			Eventually(getSuppressedBy(silenced), timeout, interval).Should(Equal("Silence/maintenance"))
+			Eventually(func() int64 {
+				return getNotifierStatus(notifier).SuppressedCount
+			}, timeout, interval).Should(BeEquivalentTo(1))
+
+			event := newWarningEvent("other-pod.unhealthy", "Unhealthy", "Pod", "other-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("silenced@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("silenced@example.com")[0].Data).To(ContainSubstring("other-pod"))
+		})
+
+		It("should suppress notifications during quiet hours", func() {
+			notifier = newNotifier("quiet", "quiet@example.com", "NodeNotReady")
+			notifier.Spec.QuietHours = &emailv1.QuietHours{Windows: []emailv1.QuietWindow{{Start: "00:00", End: "00:00"}}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("quiet-pod.nodenotready", "NodeNotReady", "Pod", "quiet-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+			Eventually(getSuppressedBy(event), timeout, interval).Should(Equal("QuietHours"))
+			Eventually(func() int64 {
+				return getNotifierStatus(notifier).SuppressedCount
+			}, timeout, interval).Should(BeEquivalentTo(1))
+			Expect(smtpServer.MessagesTo("quiet@example.com")).To(BeEmpty())
+		})
+	})
+
+	Context("resolve", func() {
+		getIncidents := func() []emailv1.OpenIncident {
+			return getNotifierStatus(notifier).OpenIncidents
+		}
+
+		It("should notify once the Pod is Ready again", func() {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "recovering-pod", Namespace: "default"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "busybox"}}},
+			}
+			Expect(k8sClient.Create(context.TODO(), pod)).To(Succeed())
+			defer k8sClient.Delete(context.TODO(), pod)
+
+			notifier = newNotifier("resolve-ready", "recovered@example.com", "FailedPostStartHook")
+			notifier.Spec.Resolve = &emailv1.ResolvePolicy{
+				Window:   &metav1.Duration{Duration: time.Hour},
+				ReadyFor: &metav1.Duration{},
+			}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("recovering-pod.failedpoststarthook", "FailedPostStartHook", "Pod", "recovering-pod")
+			event.InvolvedObject.APIVersion = "v1"
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("recovered@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Eventually(getIncidents, timeout, interval).Should(HaveLen(1))
+			Expect(getIncidents()[0].Record).To(Equal(recordName(notifier, event)))
+			Consistently(func() []receivedMail {
+				return smtpServer.MessagesTo("recovered@example.com")
+			}, time.Second, interval).Should(HaveLen(1))
+
+			pod.Status.Conditions = []corev1.PodCondition{{
+				Type:               corev1.PodReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.Now(),
+			}}
+			Expect(k8sClient.Status().Update(context.TODO(), pod)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("recovered@example.com")
+			}, timeout, interval).Should(HaveLen(2))
+			mail := smtpServer.MessagesTo("recovered@example.com")[1]
+			Expect(mailHeader(mail.Data, "Subject")).To(ContainSubstring("[resolved] FailedPostStartHook: default/recovering-pod"))
+			Expect(mail.Data).To(ContainSubstring("Resolution: the Pod is Ready again"))
+			Expect(mail.Data).To(ContainSubstring("record " + recordName(notifier, event)))
+
+			Eventually(getIncidents, timeout, interval).Should(BeEmpty())
+			record := &emailv1.NotificationRecord{}
+			key := types.NamespacedName{Namespace: "default", Name: recordName(notifier, event)}
+			Expect(k8sClient.Get(context.TODO(), key, record)).To(Succeed())
+			Expect(record.Status.ResolvedTime).NotTo(BeNil())
+		})
+
+		It("should notify once the warnings stop", func() {
+			notifier = newNotifier("resolve-quiet", "quiet-node@example.com", "NodeHasDiskPressure")
+			notifier.Spec.Kinds = []string{"Node"}
+			notifier.Spec.Resolve = &emailv1.ResolvePolicy{Window: &metav1.Duration{Duration: 2 * time.Second}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("quiet-node.nodehasdiskpressure", "NodeHasDiskPressure", "Node", "quiet-node")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("quiet-node@example.com")
+			}, timeout, interval).Should(HaveLen(2))
+			mail := smtpServer.MessagesTo("quiet-node@example.com")[1]
+			Expect(mailHeader(mail.Data, "Subject")).To(ContainSubstring("[resolved]"))
+			Expect(mail.Data).To(ContainSubstring("Resolution: no warning for 2s"))
+			Eventually(getIncidents, timeout, interval).Should(BeEmpty())
+
+			// The resolved record doesn't open the incident again
+			Consistently(func() []receivedMail {
+				return smtpServer.MessagesTo("quiet-node@example.com")
+			}, 3*time.Second, interval).Should(HaveLen(2))
+		})
+	})
+
+	Context("incidents", func() {
+		It("should notify once about the replicas of a Deployment", func() {
+			labels := map[string]string{"app": "grouped"}
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "busybox"}}},
+			}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "grouped", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+			}
+			Expect(k8sClient.Create(context.TODO(), deployment)).To(Succeed())
+			defer k8sClient.Delete(context.TODO(), deployment)
+
+			replicaSet := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "grouped-6f4", Namespace: "default"},
+				Spec:       appsv1.ReplicaSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+			}
+			Expect(controllerutil.SetControllerReference(deployment, replicaSet, scheme.Scheme)).To(Succeed())
+			Expect(k8sClient.Create(context.TODO(), replicaSet)).To(Succeed())
+			defer k8sClient.Delete(context.TODO(), replicaSet)
+
+			notifier = newNotifier("grouped-replicas", "grouped@example.com", "BackOff")
+			notifier.Spec.Grouping = &emailv1.GroupingPolicy{Window: &metav1.Duration{Duration: 2 * time.Second}}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			var events []*corev1.Event
+			for _, name := range []string{"grouped-6f4-a", "grouped-6f4-b", "grouped-6f4-c"} {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}, Spec: template.Spec}
+				Expect(controllerutil.SetControllerReference(replicaSet, pod, scheme.Scheme)).To(Succeed())
+				Expect(k8sClient.Create(context.TODO(), pod)).To(Succeed())
+				defer k8sClient.Delete(context.TODO(), pod)
+
+				event := newWarningEvent(name+".backoff", "BackOff", "Pod", name)
+				event.InvolvedObject.APIVersion = "v1"
+				Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+				events = append(events, event)
+			}
+
+			incident := &emailv1.Incident{}
+			getIncident := func() *emailv1.Incident {
+				incidents := &emailv1.IncidentList{}
+				Expect(k8sClient.List(context.TODO(), incidents, client.InNamespace("default"))).To(Succeed())
+				for _, item := range incidents.Items {
+					if item.Spec.Notifier == notifier.GetName() {
+						*incident = item
+						return incident
+					}
+				}
+				return nil
+			}
+			Eventually(func() int32 {
+				if getIncident() == nil {
+					return 0
+				}
+				return incident.Status.EventCount
+			}, timeout, interval).Should(BeEquivalentTo(3))
+			Expect(incident.Group()).To(Equal("Deployment default/grouped"))
+			Expect(incident.Status.ObjectCount).To(BeEquivalentTo(3))
+			Expect(incident.Status.Record).To(Equal(recordName(notifier, events[0])))
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("grouped@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("grouped@example.com")[0].Data).To(ContainSubstring("Incident: " + incident.GetName()))
+			for _, event := range events[1:] {
+				Eventually(func() string {
+					fetched := &emailv1.NotificationRecord{}
+					key := types.NamespacedName{Namespace: "default", Name: recordName(notifier, event)}
+					if err := k8sClient.Get(context.TODO(), key, fetched); err != nil {
+						return ""
+					}
+					return fetched.Status.SuppressedBy
+				}, timeout, interval).Should(Equal("Incident/" + incident.GetName()))
+			}
+			Consistently(func() []receivedMail {
+				return smtpServer.MessagesTo("grouped@example.com")
+			}, time.Second, interval).Should(HaveLen(1))
+
+			Eventually(func() emailv1.IncidentState {
+				return getIncident().Status.State
+			}, timeout, interval).Should(Equal(emailv1.IncidentClosed))
+		})
+	})
+
+	Context("escalation", func() {
+		var policy *emailv1.EscalationPolicy
+
+		BeforeEach(func() {
+			policy = &emailv1.EscalationPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: "default"},
+				Spec: emailv1.EscalationPolicySpec{Tiers: []emailv1.EscalationTier{
+					{Name: "team", Channels: []emailv1.Channel{{Type: emailv1.EmailChannel, Email: "team-tier@example.com"}}},
+					{Name: "on-call", After: metav1.Duration{Duration: 2 * time.Second}, Channels: []emailv1.Channel{{Type: emailv1.EmailChannel, Email: "oncall-tier@example.com"}}},
+				}},
+			}
+			Expect(k8sClient.Create(context.TODO(), policy)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(context.TODO(), policy)).To(Succeed())
+		})
+
+		getRecord := func(event *corev1.Event) *emailv1.NotificationRecord {
+			fetched := &emailv1.NotificationRecord{}
+			key := types.NamespacedName{Namespace: "default", Name: recordName(notifier, event)}
+			if err := k8sClient.Get(context.TODO(), key, fetched); err != nil {
+				return nil
+			}
+			return fetched
+		}
+
+		It("should notify the tiers until there are no more", func() {
+			notifier = newNotifier("escalated", "escalated@example.com", "Killing")
+			notifier.Spec.EscalationPolicyRef = &corev1.LocalObjectReference{Name: "oncall"}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("escalated-pod.killing", "Killing", "Pod", "escalated-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("team-tier@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("escalated@example.com")).To(HaveLen(1))
+			Expect(smtpServer.MessagesTo("oncall-tier@example.com")).To(BeEmpty())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("oncall-tier@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			mail := smtpServer.MessagesTo("oncall-tier@example.com")[0]
+			Expect(mailHeader(mail.Data, "Subject")).To(ContainSubstring("[escalated to on-call]"))
+
+			Eventually(func() *metav1.Time {
+				return getRecord(event).Status.Escalation.NextTime
+			}, timeout, interval).Should(BeNil())
+			Expect(getRecord(event).Status.Escalation.Level).To(BeEquivalentTo(2))
+		})
+
+		It("should stop escalating once acknowledged", func() {
+			policy.Spec.Tiers[1].After.Duration = 3 * time.Second
+			Expect(k8sClient.Update(context.TODO(), policy)).To(Succeed())
+
+			notifier = newNotifier("acknowledged", "acknowledged@example.com", "FailedPreStopHook")
+			notifier.Spec.EscalationPolicyRef = &corev1.LocalObjectReference{Name: "oncall"}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+
+			event := newWarningEvent("acknowledged-pod.failedprestophook", "FailedPreStopHook", "Pod", "acknowledged-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("team-tier@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+
+			// The controller updates the record status as well, retry on conflicts
+			Eventually(func() error {
+				record := getRecord(event)
+				record.SetAnnotations(map[string]string{emailv1.AcknowledgedByAnnotation: "alice"})
+				return k8sClient.Update(context.TODO(), record)
+			}, timeout, interval).Should(Succeed())
+
+			Eventually(func() string {
+				return getRecord(event).Status.Escalation.AcknowledgedBy
+			}, timeout, interval).Should(Equal("alice"))
+			Consistently(func() []receivedMail {
+				return smtpServer.MessagesTo("oncall-tier@example.com")
+			}, 4*time.Second, interval).Should(BeEmpty())
+		})
+	})
+
+	Context("with smtpSecretRef", func() {
+		var secret *corev1.Secret
+
+		getLastError := func() string {
+			return getNotifierStatus(notifier).LastError
+		}
+
+		BeforeEach(func() {
+			config := smtpServer.Config()
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "team-smtp",
+					Namespace: "default",
+				},
+				StringData: map[string]string{
+					SMTPSecretHostKey: config.Host,
+					SMTPSecretPortKey: strconv.Itoa(config.Port),
+					SMTPSecretFromKey: "team-relay@example.com",
+				},
+			}
+
+			notifier = newNotifier("mail-secret", "secret@example.com", "FailedMount")
+			notifier.Spec.SMTPSecretRef = &corev1.LocalObjectReference{Name: secret.GetName()}
+			Expect(k8sClient.Create(context.TODO(), notifier)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(context.TODO(), secret)).To(Succeed())
+		})
+
+		It("should report a missing Secret and deliver once it appears", func() {
+			Eventually(getLastError, timeout, interval).Should(ContainSubstring("team-smtp not found"))
+
+			event := newWarningEvent("secret-pod.failedmount", "FailedMount", "Pod", "secret-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+			Consistently(func() []receivedMail {
+				return smtpServer.MessagesTo("secret@example.com")
+			}, time.Second, interval).Should(BeEmpty())
+
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("secret@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("secret@example.com")[0].From).To(Equal("team-relay@example.com"))
+			Eventually(getLastError, timeout, interval).Should(BeEmpty())
+		})
+
+		It("should report a malformed Secret and re-read it on rotation", func() {
+			secret.StringData[SMTPSecretPortKey] = "smtp"
+			Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+			Eventually(getLastError, timeout, interval).Should(ContainSubstring(`key "port" is not a number`))
+
+			config := smtpServer.Config()
+			secret.StringData[SMTPSecretPortKey] = strconv.Itoa(config.Port)
+			secret.StringData[SMTPSecretFromKey] = "rotated@example.com"
+			Expect(k8sClient.Update(context.TODO(), secret)).To(Succeed())
+			Eventually(getLastError, timeout, interval).Should(BeEmpty())
+
+			event := newWarningEvent("rotated-pod.failedmount", "FailedMount", "Pod", "rotated-pod")
+			Expect(k8sClient.Create(context.TODO(), event)).To(Succeed())
+			Eventually(func() []receivedMail {
+				return smtpServer.MessagesTo("secret@example.com")
+			}, timeout, interval).Should(HaveLen(1))
+			Expect(smtpServer.MessagesTo("secret@example.com")[0].From).To(Equal("rotated@example.com"))
+		})
+	})
+})
@@ -0,0 +1,221 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	emailv1 "std/api/v1"
+)
+
+// maxOwnerDepth bounds the owner chain, Pod -> ReplicaSet -> Deployment is the usual depth
+const maxOwnerDepth = 5
+
+// resolveContext resolves the controller chain of the involved object, and the node and containers of a Pod.
+// Objects are read as unstructured, so any workload owning Pods is followed, and no informer is started for them.
+// The context is best effort: the chain ends at objects which are gone or can't be read.
+func resolveContext(reader client.Reader, ref corev1.ObjectReference) (*emailv1.ObjectContext, error) {
+	if ref.APIVersion == "" || ref.Kind == "" || ref.Namespace == "" {
+		return nil, nil
+	}
+	object, err := getUnstructured(reader, ref.GroupVersionKind(), ref.Namespace, ref.Name)
+	if err != nil || object == nil {
+		return nil, err
+	}
+
+	context := &emailv1.ObjectContext{}
+	if ref.Kind == "Pod" && ref.GroupVersionKind().Group == "" {
+		pod := &corev1.Pod{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), pod)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read Pod %s/%s", ref.Namespace, ref.Name)
+		}
+		podContext(pod, context)
+	}
+
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		owner := metav1.GetControllerOf(object)
+		if owner == nil {
+			break
+		}
+		context.Owners = append(context.Owners, emailv1.WorkloadReference{
+			APIVersion: owner.APIVersion,
+			Kind:       owner.Kind,
+			Name:       owner.Name,
+		})
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil {
+			break
+		}
+		object, err = getUnstructured(reader, gv.WithKind(owner.Kind), ref.Namespace, owner.Name)
+		if err != nil {
+			return nil, err
+		} else if object == nil {
+			break
+		}
+	}
+
+	if len(context.Owners) == 0 && context.NodeName == "" && len(context.Containers) == 0 {
+		return nil, nil
+	}
+	return context, nil
+}
+
+// getUnstructured reads the object, it is nil when it is gone or the manager may not read it
+func getUnstructured(reader client.Reader, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	err := reader.Get(ctx.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, object)
+	if k8serror.IsNotFound(err) || k8serror.IsForbidden(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get %s %s/%s", gvk.Kind, namespace, name)
+	}
+	return object, nil
+}
+
+// podContext copies the node and container statuses of the Pod
+func podContext(pod *corev1.Pod, context *emailv1.ObjectContext) {
+	context.NodeName = pod.Spec.NodeName
+	context.Phase = pod.Status.Phase
+	for _, status := range pod.Status.InitContainerStatuses {
+		container := containerContext(status)
+		container.Init = true
+		context.Containers = append(context.Containers, container)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		context.Containers = append(context.Containers, containerContext(status))
+	}
+}
+
+func containerContext(status corev1.ContainerStatus) emailv1.ContainerContext {
+	container := emailv1.ContainerContext{
+		Name:         status.Name,
+		Image:        status.Image,
+		Ready:        status.Ready,
+		RestartCount: status.RestartCount,
+	}
+	switch {
+	case status.State.Waiting != nil:
+		container.State = "Waiting"
+		container.Reason = status.State.Waiting.Reason
+	case status.State.Running != nil:
+		container.State = "Running"
+	case status.State.Terminated != nil:
+		container.State = "Terminated"
+		container.Reason = status.State.Terminated.Reason
+		exitCode := status.State.Terminated.ExitCode
+		container.ExitCode = &exitCode
+	}
+	if last := status.LastTerminationState.Terminated; last != nil {
+		container.LastTerminationReason = last.Reason
+		if container.ExitCode == nil {
+			exitCode := last.ExitCode
+			container.ExitCode = &exitCode
+		}
+	}
+	return container
+}
+
+// contextText describes the workload and containers for plain text bodies
+func contextText(context *emailv1.ObjectContext) string {
+	if context == nil {
+		return ""
+	}
+	text := ""
+	if workload := context.Workload(); workload != nil {
+		text += fmt.Sprintf("Workload: %s\n", workload)
+	}
+	if context.NodeName != "" {
+		text += fmt.Sprintf("Node: %s\n", context.NodeName)
+	}
+	for _, container := range context.Containers {
+		text += "Container " + containerSummary(container) + "\n"
+	}
+	return text
+}
+
+// contextFacts are the name and value pairs shown by chat channels
+func contextFacts(context *emailv1.ObjectContext) [][2]string {
+	if context == nil {
+		return nil
+	}
+	facts := [][2]string{}
+	if workload := context.Workload(); workload != nil {
+		facts = append(facts, [2]string{"Workload", workload.String()})
+	}
+	if context.NodeName != "" {
+		facts = append(facts, [2]string{"Node", context.NodeName})
+	}
+	if len(context.Containers) > 0 {
+		summaries := []string{}
+		for _, container := range context.Containers {
+			summaries = append(summaries, containerSummary(container))
+		}
+		facts = append(facts, [2]string{"Containers", strings.Join(summaries, "\n")})
+	}
+	return facts
+}
+
+// containerSummary is a one line status of the container, like "app: Waiting CrashLoopBackOff, 5 restarts, exit code 137 (OOMKilled)"
+func containerSummary(container emailv1.ContainerContext) string {
+	state := strings.TrimSpace(container.State + " " + container.Reason)
+	if state == "" {
+		state = "Unknown"
+	}
+	parts := []string{container.Name + ": " + state}
+	if container.RestartCount > 0 {
+		parts = append(parts, fmt.Sprintf("%d restarts", container.RestartCount))
+	}
+	if container.ExitCode != nil {
+		exit := fmt.Sprintf("exit code %d", *container.ExitCode)
+		if container.LastTerminationReason != "" && container.LastTerminationReason != container.Reason {
+			exit += " (" + container.LastTerminationReason + ")"
+		}
+		parts = append(parts, exit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// contextResolver resolves the context of the involved object once, on first use.
+// An Event matching several Notifiers is resolved once for all of their records.
+type contextResolver struct {
+	reader   client.Reader
+	ref      corev1.ObjectReference
+	resolved bool
+	context  *emailv1.ObjectContext
+	err      error
+}
+
+func (c *contextResolver) get() (*emailv1.ObjectContext, error) {
+	if !c.resolved {
+		c.context, c.err = resolveContext(c.reader, c.ref)
+		c.resolved = true
+	}
+	return c.context, c.err
+}
@@ -0,0 +1,234 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/net/context"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	emailv1 "std/api/v1"
+)
+
+// objectReader serves unstructured objects by kind, namespace and name, counting the reads
+type objectReader struct {
+	objects map[string]*unstructured.Unstructured
+	reads   int
+}
+
+func newObjectReader(objects ...runtime.Object) *objectReader {
+	r := &objectReader{objects: map[string]*unstructured.Unstructured{}}
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		Expect(err).NotTo(HaveOccurred())
+		u := &unstructured.Unstructured{Object: content}
+		r.objects[u.GetKind()+"/"+u.GetNamespace()+"/"+u.GetName()] = u
+	}
+	return r
+}
+
+func (r *objectReader) Get(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+	r.reads++
+	u := obj.(*unstructured.Unstructured)
+	found, ok := r.objects[u.GetKind()+"/"+key.Namespace+"/"+key.Name]
+	if !ok {
+		return k8serror.NewNotFound(schema.GroupResource{Resource: u.GetKind()}, key.Name)
+	}
+	found.DeepCopyInto(u)
+	return nil
+}
+
+func (r *objectReader) List(_ context.Context, _ runtime.Object, _ ...client.ListOptionFunc) error {
+	return k8serror.NewMethodNotSupported(schema.GroupResource{}, "list")
+}
+
+func controlledBy(apiVersion, kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{APIVersion: apiVersion, Kind: kind, Name: name, UID: types.UID("uid-" + name), Controller: &controller}}
+}
+
+func newOwnedPod(name string, owners []metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", OwnerReferences: owners},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "migrate",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}},
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "app",
+				Image:        "example.com/app:1.0",
+				RestartCount: 5,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason: "CrashLoopBackOff",
+				}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason:   "OOMKilled",
+					ExitCode: 137,
+				}},
+			}},
+		},
+	}
+}
+
+func podRef(name string) corev1.ObjectReference {
+	return corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Namespace: "apps", Name: name}
+}
+
+var _ = Describe("resolveContext", func() {
+	It("should resolve the Deployment, node and containers of a Pod", func() {
+		reader := newObjectReader(
+			newOwnedPod("web-7c9-x2k", controlledBy("apps/v1", "ReplicaSet", "web-7c9")),
+			&appsv1.ReplicaSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "web-7c9", Namespace: "apps", OwnerReferences: controlledBy("apps/v1", "Deployment", "web")},
+			},
+			&appsv1.Deployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+			},
+		)
+
+		resolved, err := resolveContext(reader, podRef("web-7c9-x2k"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.Owners).To(Equal([]emailv1.WorkloadReference{
+			{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-7c9"},
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+		}))
+		Expect(resolved.Workload().String()).To(Equal("Deployment/web"))
+		Expect(resolved.NodeName).To(Equal("node-1"))
+		Expect(resolved.Phase).To(Equal(corev1.PodRunning))
+
+		exitCode := int32(137)
+		completed := int32(0)
+		Expect(resolved.Containers).To(Equal([]emailv1.ContainerContext{
+			{Name: "migrate", Init: true, State: "Terminated", Reason: "Completed", ExitCode: &completed},
+			{
+				Name:                  "app",
+				Image:                 "example.com/app:1.0",
+				RestartCount:          5,
+				State:                 "Waiting",
+				Reason:                "CrashLoopBackOff",
+				ExitCode:              &exitCode,
+				LastTerminationReason: "OOMKilled",
+			},
+		}))
+	})
+
+	It("should follow Jobs up to their CronJob", func() {
+		reader := newObjectReader(
+			newOwnedPod("backup-1561-abc", controlledBy("batch/v1", "Job", "backup-1561")),
+			&batchv1.Job{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+				ObjectMeta: metav1.ObjectMeta{Name: "backup-1561", Namespace: "apps", OwnerReferences: controlledBy("batch/v1beta1", "CronJob", "backup")},
+			},
+		)
+
+		resolved, err := resolveContext(reader, podRef("backup-1561-abc"))
+		Expect(err).NotTo(HaveOccurred())
+		// The CronJob itself isn't served, it still ends the chain
+		Expect(resolved.Workload()).To(Equal(&emailv1.WorkloadReference{APIVersion: "batch/v1beta1", Kind: "CronJob", Name: "backup"}))
+		Expect(resolved.Owners).To(HaveLen(2))
+	})
+
+	It("should follow any controller, like an AppScaler", func() {
+		appScaler := &unstructured.Unstructured{}
+		appScaler.SetAPIVersion("sample.example.com/v1beta1")
+		appScaler.SetKind("AppScaler")
+		appScaler.SetNamespace("apps")
+		appScaler.SetName("scaled")
+
+		reader := newObjectReader(
+			newOwnedPod("scaled-rs-abc", controlledBy("extensions/v1beta1", "ReplicaSet", "scaled-rs")),
+			&appsv1.ReplicaSet{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "extensions/v1beta1", Kind: "ReplicaSet"},
+				ObjectMeta: metav1.ObjectMeta{Name: "scaled-rs", Namespace: "apps", OwnerReferences: controlledBy("sample.example.com/v1beta1", "AppScaler", "scaled")},
+			},
+			appScaler,
+		)
+
+		resolved, err := resolveContext(reader, podRef("scaled-rs-abc"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.Workload().String()).To(Equal("AppScaler/scaled"))
+		Expect(reader.reads).To(Equal(3))
+	})
+
+	It("should resolve the owners of objects other than Pods", func() {
+		reader := newObjectReader(&appsv1.ReplicaSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+			ObjectMeta: metav1.ObjectMeta{Name: "web-7c9", Namespace: "apps", OwnerReferences: controlledBy("apps/v1", "Deployment", "web")},
+		})
+
+		resolved, err := resolveContext(reader, corev1.ObjectReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Namespace: "apps", Name: "web-7c9"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved.Workload().String()).To(Equal("Deployment/web"))
+		Expect(resolved.NodeName).To(BeEmpty())
+		Expect(resolved.Containers).To(BeEmpty())
+	})
+
+	It("should leave the context out when there is nothing to tell", func() {
+		reader := newObjectReader(&corev1.Node{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Node"},
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		})
+
+		Expect(resolveContext(reader, podRef("gone"))).To(BeNil())
+		Expect(resolveContext(reader, corev1.ObjectReference{Kind: "Pod", Namespace: "apps", Name: "no-version"})).To(BeNil())
+		Expect(resolveContext(reader, corev1.ObjectReference{APIVersion: "v1", Kind: "Node", Name: "node-1"})).To(BeNil())
+	})
+
+	It("should resolve once for all the records of an Event", func() {
+		reader := newObjectReader(newOwnedPod("single", nil))
+		resolver := &contextResolver{reader: reader, ref: podRef("single")}
+
+		first, err := resolver.get()
+		Expect(err).NotTo(HaveOccurred())
+		second, err := resolver.get()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(reader.reads).To(Equal(1))
+	})
+
+	It("should describe the workload and containers", func() {
+		exitCode := int32(137)
+		text := contextText(&emailv1.ObjectContext{
+			Owners:   []emailv1.WorkloadReference{{Kind: "ReplicaSet", Name: "web-7c9"}, {Kind: "Deployment", Name: "web"}},
+			NodeName: "node-1",
+			Containers: []emailv1.ContainerContext{
+				{Name: "app", State: "Waiting", Reason: "CrashLoopBackOff", RestartCount: 5, ExitCode: &exitCode, LastTerminationReason: "OOMKilled"},
+				{Name: "sidecar", State: "Running", Ready: true},
+			},
+		})
+		Expect(text).To(Equal("Workload: Deployment/web\n" +
+			"Node: node-1\n" +
+			"Container app: Waiting CrashLoopBackOff, 5 restarts, exit code 137 (OOMKilled)\n" +
+			"Container sidecar: Running\n"))
+		Expect(contextText(nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,115 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxOpsgenieMessage is the longest alert message Opsgenie accepts
+	maxOpsgenieMessage = 130
+	// maxOpsgenieDescription is the longest alert description Opsgenie accepts
+	maxOpsgenieDescription = 15000
+)
+
+// opsgenieAlert is the request creating an Opsgenie alert
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// opsgenieAction is the request acknowledging or closing an Opsgenie alert
+type opsgenieAction struct {
+	Source string `json:"source,omitempty"`
+	User   string `json:"user,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// opsgenieChannel creates an Opsgenie alert per record, and closes it once resolved
+type opsgenieChannel struct {
+	*webhookChannel
+	apiKey string
+}
+
+func (c *opsgenieChannel) Send(n *Notification) error {
+	if n.Resolved != nil {
+		return c.post(c.actionURL(n, "close"), &opsgenieAction{
+			Source: opsgenieSource(n),
+			Note:   "Resolved, " + n.Resolved.Description,
+		})
+	}
+	return c.post(c.url, OpsgeniePayload(n))
+}
+
+// Acknowledge acknowledges the alert about the record
+func (c *opsgenieChannel) Acknowledge(n *Notification, by string) error {
+	return c.post(c.actionURL(n, "acknowledge"), &opsgenieAction{
+		Source: opsgenieSource(n),
+		User:   by,
+		Note:   "Acknowledged by " + by,
+	})
+}
+
+// actionURL is the endpoint of the action on the alert, identified by its alias
+func (c *opsgenieChannel) actionURL(n *Notification, action string) string {
+	return strings.TrimSuffix(c.url, "/") + "/" + url.PathEscape(n.AlertKey()) + "/" + action + "?identifierType=alias"
+}
+
+// OpsgeniePayload is the alert about the notification, with the alias of the record
+func OpsgeniePayload(n *Notification) interface{} {
+	alert := &opsgenieAlert{
+		Message:     truncate(n.Subject(), maxOpsgenieMessage),
+		Alias:       n.AlertKey(),
+		Description: truncate(n.Text(), maxOpsgenieDescription),
+		Source:      opsgenieSource(n),
+		Details: map[string]string{
+			"notifier": n.Notifier.GetNamespace() + "/" + n.Notifier.GetName(),
+		},
+	}
+	if n.AcknowledgeURL != "" {
+		alert.Details["acknowledge"] = n.AcknowledgeURL
+	}
+	if n.IsDigest() {
+		return alert
+	}
+
+	alert.Entity = n.Event.InvolvedObject.Kind + " " + objectName(n.Event)
+	alert.Tags = []string{n.Event.Reason, n.Event.InvolvedObject.Kind}
+	alert.Details["reason"] = n.Event.Reason
+	alert.Details["kind"] = n.Event.InvolvedObject.Kind
+	alert.Details["object"] = objectName(n.Event)
+	if workload := n.Context.Workload(); workload != nil {
+		alert.Details["workload"] = workload.Kind + "/" + workload.Name
+	}
+	if n.Context != nil && n.Context.NodeName != "" {
+		alert.Details["node"] = n.Context.NodeName
+	}
+	if n.Incident != "" {
+		alert.Details["incident"] = n.Incident
+	}
+	return alert
+}
+
+func opsgenieSource(n *Notification) string {
+	return "notifier " + n.Notifier.GetNamespace() + "/" + n.Notifier.GetName()
+}
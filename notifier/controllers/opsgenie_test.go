@@ -0,0 +1,230 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+const testAPIKey = "eb243592-faa2-4ba2-a551-1afdf565c889"
+
+// opsgenieRequest is an alert request accepted by the stand-in
+type opsgenieRequest struct {
+	// Action is create, acknowledge or close
+	Action string
+	// Alias identifies the alert of an acknowledge or close request
+	Alias string
+	Body  map[string]interface{}
+}
+
+// opsgenieStandIn validates the requests like the Opsgenie Alert API, and remembers the accepted ones
+type opsgenieStandIn struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []opsgenieRequest
+}
+
+func newOpsgenieStandIn() *opsgenieStandIn {
+	o := &opsgenieStandIn{}
+	o.Server = httptest.NewServer(http.HandlerFunc(o.handle))
+	return o
+}
+
+func (o *opsgenieStandIn) handle(resp http.ResponseWriter, req *http.Request) {
+	reject := func(status int, message string) {
+		resp.WriteHeader(status)
+		json.NewEncoder(resp).Encode(map[string]interface{}{"message": message, "took": 0.001, "requestId": "rejected"})
+	}
+	if req.Header.Get("Authorization") != "GenieKey "+testAPIKey {
+		reject(http.StatusUnauthorized, "Key format is not valid!")
+		return
+	}
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		reject(http.StatusBadRequest, "Expected a JSON POST")
+		return
+	}
+	request := opsgenieRequest{Body: map[string]interface{}{}}
+	if err := json.NewDecoder(req.Body).Decode(&request.Body); err != nil {
+		reject(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	path := strings.Split(strings.TrimPrefix(req.URL.EscapedPath(), "/v2/alerts"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "":
+		request.Action = "create"
+		message, _ := request.Body["message"].(string)
+		alias, _ := request.Body["alias"].(string)
+		description, _ := request.Body["description"].(string)
+		switch {
+		case message == "" || len(message) > 130:
+			reject(http.StatusUnprocessableEntity, "Message can not be empty or longer than 130 characters")
+			return
+		case len(alias) > 512:
+			reject(http.StatusUnprocessableEntity, "Alias can not be longer than 512 characters")
+			return
+		case len(description) > 15000:
+			reject(http.StatusUnprocessableEntity, "Description can not be longer than 15000 characters")
+			return
+		}
+		if details, found := request.Body["details"]; found {
+			for _, value := range details.(map[string]interface{}) {
+				if _, ok := value.(string); !ok {
+					reject(http.StatusUnprocessableEntity, "Details must be strings")
+					return
+				}
+			}
+		}
+	case len(path) == 3 && (path[2] == "acknowledge" || path[2] == "close"):
+		if req.URL.Query().Get("identifierType") != "alias" {
+			reject(http.StatusNotFound, "Alert does not exist")
+			return
+		}
+		alias, err := url.PathUnescape(path[1])
+		if err != nil {
+			reject(http.StatusBadRequest, err.Error())
+			return
+		}
+		request.Action, request.Alias = path[2], alias
+	default:
+		reject(http.StatusNotFound, "No handler found")
+		return
+	}
+
+	o.mu.Lock()
+	o.requests = append(o.requests, request)
+	o.mu.Unlock()
+	resp.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(resp).Encode(map[string]interface{}{"result": "Request will be processed", "took": 0.1, "requestId": "43a29c5c"})
+}
+
+func (o *opsgenieStandIn) Requests() []opsgenieRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]opsgenieRequest{}, o.requests...)
+}
+
+var _ = Describe("Opsgenie channel", func() {
+	var (
+		standIn      *opsgenieStandIn
+		spec         emailv1.Channel
+		notification *Notification
+	)
+
+	BeforeEach(func() {
+		standIn = newOpsgenieStandIn()
+		spec = emailv1.Channel{
+			Type:                emailv1.OpsgenieChannel,
+			URL:                 standIn.URL + "/v2/alerts",
+			RoutingKeySecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "opsgenie"}, Key: "apiKey"},
+		}
+		notification = &Notification{
+			Notifier: &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"}},
+			Event: &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "web-7c9-x2k.15f", Namespace: "apps"},
+				InvolvedObject: podRef("web-7c9-x2k"),
+				Type:           corev1.EventTypeWarning,
+				Reason:         "BackOff",
+				Message:        "Back-off restarting failed container",
+			},
+			Context: &emailv1.ObjectContext{
+				Owners:   []emailv1.WorkloadReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+				NodeName: "node-1",
+			},
+			Record: "team-3f2a9c1b7e",
+		}
+	})
+
+	AfterEach(func() {
+		standIn.Close()
+	})
+
+	build := func() Channel {
+		channel, err := buildTestChannel(notification.Notifier, spec, newKeySecret("opsgenie", "apiKey", testAPIKey))
+		Expect(err).NotTo(HaveOccurred())
+		return channel
+	}
+
+	It("should create an alert aliased by the record", func() {
+		Expect(build().Send(notification)).To(Succeed())
+
+		request := standIn.Requests()[0]
+		Expect(request.Action).To(Equal("create"))
+		Expect(request.Body["message"]).To(Equal("[team] BackOff: apps/web-7c9-x2k"))
+		Expect(request.Body["alias"]).To(Equal("apps/team-3f2a9c1b7e"))
+		Expect(request.Body["description"]).To(ContainSubstring("Back-off restarting failed container"))
+		Expect(request.Body["entity"]).To(Equal("Pod apps/web-7c9-x2k"))
+		Expect(request.Body["source"]).To(Equal("notifier apps/team"))
+		Expect(request.Body["tags"]).To(Equal([]interface{}{"BackOff", "Pod"}))
+		Expect(request.Body["details"]).To(Equal(map[string]interface{}{
+			"notifier": "apps/team",
+			"reason":   "BackOff",
+			"kind":     "Pod",
+			"object":   "apps/web-7c9-x2k",
+			"workload": "Deployment/web",
+			"node":     "node-1",
+		}))
+	})
+
+	It("should acknowledge and close the alert by its alias", func() {
+		channel := build()
+		Expect(channel.(Acknowledger).Acknowledge(notification, "Alice")).To(Succeed())
+		notification.Resolved = &ResolvedNotice{Record: notification.Record, Resolution: emailv1.ResolvedReady, Description: "the Pod is Ready again"}
+		Expect(channel.Send(notification)).To(Succeed())
+
+		requests := standIn.Requests()
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[0].Action).To(Equal("acknowledge"))
+		Expect(requests[0].Alias).To(Equal("apps/team-3f2a9c1b7e"))
+		Expect(requests[0].Body).To(HaveKeyWithValue("user", "Alice"))
+		Expect(requests[1].Action).To(Equal("close"))
+		Expect(requests[1].Alias).To(Equal("apps/team-3f2a9c1b7e"))
+		Expect(requests[1].Body).To(HaveKeyWithValue("note", "Resolved, the Pod is Ready again"))
+	})
+
+	It("should keep messages within the limit", func() {
+		notification.Event.Reason = strings.Repeat("CrashLoop", 20)
+		Expect(build().Send(notification)).To(Succeed())
+		Expect(standIn.Requests()[0].Body["message"]).To(HaveLen(maxOpsgenieMessage))
+	})
+
+	It("should fail when the API key is refused", func() {
+		channel, err := buildTestChannel(notification.Notifier, spec, newKeySecret("opsgenie", "apiKey", "wrong"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channel.Send(notification)).To(MatchError(ContainSubstring("401 Unauthorized")))
+	})
+
+	It("should post to the Opsgenie endpoint by default", func() {
+		spec.URL = ""
+		channel := build()
+		Expect(channel.(*opsgenieChannel).url).To(Equal(emailv1.DefaultOpsgenieURL))
+		Expect(channel.(*opsgenieChannel).actionURL(notification, "close")).
+			To(Equal("https://api.opsgenie.com/v2/alerts/apps%2Fteam-3f2a9c1b7e/close?identifierType=alias"))
+	})
+})
@@ -0,0 +1,135 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// maxPagerDutySummary is the longest summary PagerDuty accepts
+const maxPagerDutySummary = 1024
+
+// pagerDutyEvent is a document of the PagerDuty Events API v2
+type pagerDutyEvent struct {
+	RoutingKey  string          `json:"routing_key"`
+	EventAction string          `json:"event_action"`
+	DedupKey    string          `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyAlert `json:"payload,omitempty"`
+	Client      string          `json:"client,omitempty"`
+	Links       []pagerDutyLink `json:"links,omitempty"`
+}
+
+// pagerDutyAlert describes the alert of a trigger event
+type pagerDutyAlert struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// pagerDutyLink is a link shown on the alert
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+// pagerDutyChannel sends the notifications as PagerDuty events, deduplicated by the record
+type pagerDutyChannel struct {
+	*webhookChannel
+	routingKey string
+}
+
+func (c *pagerDutyChannel) Send(n *Notification) error {
+	return c.post(c.url, PagerDutyPayload(n, c.routingKey))
+}
+
+// Acknowledge acknowledges the alert about the record
+func (c *pagerDutyChannel) Acknowledge(n *Notification, by string) error {
+	return c.post(c.url, &pagerDutyEvent{RoutingKey: c.routingKey, EventAction: "acknowledge", DedupKey: n.AlertKey()})
+}
+
+// PagerDutyPayload is the event triggering an alert about the notification, or resolving it
+func PagerDutyPayload(n *Notification, routingKey string) interface{} {
+	event := &pagerDutyEvent{RoutingKey: routingKey, DedupKey: n.AlertKey()}
+	if n.Resolved != nil {
+		event.EventAction = "resolve"
+		return event
+	}
+
+	event.EventAction = "trigger"
+	event.Client = "notifier " + n.Notifier.GetNamespace() + "/" + n.Notifier.GetName()
+	event.Payload = &pagerDutyAlert{
+		Summary:       truncate(n.Subject(), maxPagerDutySummary),
+		Source:        n.Notifier.GetNamespace() + "/" + n.Notifier.GetName(),
+		Severity:      "warning",
+		CustomDetails: map[string]interface{}{"text": n.Text()},
+	}
+	if n.AcknowledgeURL != "" {
+		event.Links = []pagerDutyLink{{Href: n.AcknowledgeURL, Text: "Acknowledge"}}
+	}
+	if n.IsDigest() {
+		return event
+	}
+
+	payload := event.Payload
+	payload.Source = objectName(n.Event)
+	if n.Event.Type != corev1.EventTypeWarning {
+		payload.Severity = "info"
+	}
+	if occurred := eventTime(n.Event); !occurred.IsZero() {
+		payload.Timestamp = occurred.UTC().Format(time.RFC3339)
+	}
+	payload.Component = n.Event.InvolvedObject.Kind
+	payload.Group = n.Event.InvolvedObject.Namespace
+	if workload := n.Context.Workload(); workload != nil {
+		payload.Group = workload.Kind + "/" + workload.Name
+	}
+	payload.Class = n.Event.Reason
+	if n.Context != nil {
+		payload.CustomDetails["context"] = n.Context
+	}
+	return event
+}
+
+// eventTime is the last occurrence of the Event
+func eventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.FirstTimestamp.IsZero() {
+		return event.FirstTimestamp.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// truncate shortens the text to at most max bytes, without splitting a rune
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max - len("...")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
@@ -0,0 +1,300 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+// testRoutingKey has the length of a PagerDuty integration key
+const testRoutingKey = "0123456789abcdef0123456789abcdef"
+
+// pagerDutyStandIn validates the events like the PagerDuty Events API v2, and remembers the accepted ones
+type pagerDutyStandIn struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []map[string]interface{}
+}
+
+func newPagerDutyStandIn() *pagerDutyStandIn {
+	p := &pagerDutyStandIn{}
+	p.Server = httptest.NewServer(http.HandlerFunc(p.handle))
+	return p
+}
+
+func (p *pagerDutyStandIn) handle(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost || req.URL.Path != "/v2/enqueue" {
+		http.NotFound(resp, req)
+		return
+	}
+	event := map[string]interface{}{}
+	if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(resp).Encode(map[string]interface{}{"status": "invalid event", "message": err.Error()})
+		return
+	}
+	if errs := validatePagerDutyEvent(event); len(errs) > 0 {
+		resp.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(resp).Encode(map[string]interface{}{"status": "invalid event", "message": "Event object is invalid", "errors": errs})
+		return
+	}
+
+	p.mu.Lock()
+	p.events = append(p.events, event)
+	p.mu.Unlock()
+	resp.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(resp).Encode(map[string]interface{}{"status": "success", "message": "Event processed", "dedup_key": event["dedup_key"]})
+}
+
+func (p *pagerDutyStandIn) Events() []map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]map[string]interface{}{}, p.events...)
+}
+
+// validatePagerDutyEvent checks the fields of the Events API v2 schema
+func validatePagerDutyEvent(event map[string]interface{}) []string {
+	var errs []string
+	str := func(object map[string]interface{}, field string) string {
+		value, _ := object[field].(string)
+		return value
+	}
+	if len(str(event, "routing_key")) != 32 {
+		errs = append(errs, "'routing_key' must be 32 characters")
+	}
+	if len(str(event, "dedup_key")) > 255 {
+		errs = append(errs, "'dedup_key' is longer than 255 characters")
+	}
+	switch str(event, "event_action") {
+	case "trigger":
+		payload, ok := event["payload"].(map[string]interface{})
+		if !ok {
+			return append(errs, "'payload' is missing")
+		}
+		if summary := str(payload, "summary"); summary == "" || len(summary) > 1024 {
+			errs = append(errs, "'payload.summary' must be 1 to 1024 characters")
+		}
+		if str(payload, "source") == "" {
+			errs = append(errs, "'payload.source' is missing")
+		}
+		switch str(payload, "severity") {
+		case "critical", "error", "warning", "info":
+		default:
+			errs = append(errs, "'payload.severity' is invalid")
+		}
+		if timestamp, found := payload["timestamp"]; found {
+			if _, err := time.Parse(time.RFC3339, timestamp.(string)); err != nil {
+				errs = append(errs, "'payload.timestamp' is not ISO 8601")
+			}
+		}
+	case "acknowledge", "resolve":
+		if str(event, "dedup_key") == "" {
+			errs = append(errs, "'dedup_key' is missing")
+		}
+		if _, found := event["payload"]; found {
+			errs = append(errs, "'payload' is only sent on trigger")
+		}
+	default:
+		errs = append(errs, "'event_action' is invalid")
+	}
+	return errs
+}
+
+// buildTestChannel builds the channel of the Notifier against the Secrets
+func buildTestChannel(notifier *emailv1.Notifier, spec emailv1.Channel, secrets ...runtime.Object) (Channel, error) {
+	r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme, secrets...)}
+	channels, err := r.buildChannels(notifier, []emailv1.Channel{spec})
+	if err != nil {
+		return nil, err
+	}
+	Expect(channels).To(HaveLen(1))
+	return channels[0], nil
+}
+
+// newKeySecret holds the key in the Secret of the apps namespace
+func newKeySecret(name, key, value string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Data:       map[string][]byte{key: []byte(value)},
+	}
+}
+
+var _ = Describe("PagerDuty channel", func() {
+	var (
+		standIn      *pagerDutyStandIn
+		spec         emailv1.Channel
+		notification *Notification
+	)
+
+	BeforeEach(func() {
+		standIn = newPagerDutyStandIn()
+		spec = emailv1.Channel{
+			Type:                emailv1.PagerDutyChannel,
+			URL:                 standIn.URL + "/v2/enqueue",
+			RoutingKeySecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "pagerduty"}, Key: "routingKey"},
+		}
+		notification = &Notification{
+			Notifier: &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"}},
+			Event: &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "web-7c9-x2k.15f", Namespace: "apps"},
+				InvolvedObject: podRef("web-7c9-x2k"),
+				Type:           corev1.EventTypeWarning,
+				Reason:         "BackOff",
+				Message:        "Back-off restarting failed container",
+				LastTimestamp:  metav1.NewTime(time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)),
+			},
+			Context: &emailv1.ObjectContext{
+				Owners:   []emailv1.WorkloadReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+				NodeName: "node-1",
+			},
+			Record:         "team-3f2a9c1b7e",
+			AcknowledgeURL: "https://notifier.example.com/acknowledge/apps/team-3f2a9c1b7e",
+		}
+	})
+
+	AfterEach(func() {
+		standIn.Close()
+	})
+
+	build := func() Channel {
+		channel, err := buildTestChannel(notification.Notifier, spec, newKeySecret("pagerduty", "routingKey", testRoutingKey+"\n"))
+		Expect(err).NotTo(HaveOccurred())
+		return channel
+	}
+
+	It("should trigger an alert deduplicated by the record", func() {
+		Expect(build().Send(notification)).To(Succeed())
+
+		event := standIn.Events()[0]
+		Expect(event["routing_key"]).To(Equal(testRoutingKey))
+		Expect(event["event_action"]).To(Equal("trigger"))
+		Expect(event["dedup_key"]).To(Equal("apps/team-3f2a9c1b7e"))
+		Expect(event["links"]).To(Equal([]interface{}{map[string]interface{}{
+			"href": "https://notifier.example.com/acknowledge/apps/team-3f2a9c1b7e", "text": "Acknowledge",
+		}}))
+
+		payload := event["payload"].(map[string]interface{})
+		Expect(payload["summary"]).To(Equal("[team] BackOff: apps/web-7c9-x2k"))
+		Expect(payload["source"]).To(Equal("apps/web-7c9-x2k"))
+		Expect(payload["severity"]).To(Equal("warning"))
+		Expect(payload["timestamp"]).To(Equal("2019-07-01T12:00:00Z"))
+		Expect(payload["component"]).To(Equal("Pod"))
+		Expect(payload["group"]).To(Equal("Deployment/web"))
+		Expect(payload["class"]).To(Equal("BackOff"))
+		Expect(payload["custom_details"]).To(HaveKeyWithValue("text", ContainSubstring("Back-off restarting failed container")))
+	})
+
+	It("should acknowledge and resolve the alert of the record", func() {
+		channel := build()
+		Expect(channel.(Acknowledger).Acknowledge(notification, "Alice")).To(Succeed())
+		notification.Resolved = &ResolvedNotice{Record: notification.Record, Resolution: emailv1.ResolvedReady, Description: "the Pod is Ready again"}
+		Expect(channel.Send(notification)).To(Succeed())
+
+		events := standIn.Events()
+		Expect(events).To(HaveLen(2))
+		Expect(events[0]).To(Equal(map[string]interface{}{"routing_key": testRoutingKey, "event_action": "acknowledge", "dedup_key": "apps/team-3f2a9c1b7e"}))
+		Expect(events[1]).To(Equal(map[string]interface{}{"routing_key": testRoutingKey, "event_action": "resolve", "dedup_key": "apps/team-3f2a9c1b7e"}))
+	})
+
+	It("should trigger digests deduplicated by their records", func() {
+		notification.Event = nil
+		notification.Record = ""
+		notification.Records = []string{"team-3f2a9c1b7e", "team-8d1e4a0c2f"}
+		notification.Digest = []DigestGroup{{Reason: "BackOff", InvolvedObject: podRef("web-7c9-x2k"), Count: 2, Messages: []string{"restarting"}}}
+		channel := build()
+		Expect(channel.Send(notification)).To(Succeed())
+		Expect(channel.Send(notification)).To(Succeed())
+
+		events := standIn.Events()
+		event := events[0]
+		Expect(event["dedup_key"]).To(HavePrefix("apps/digest-"))
+		Expect(events[1]["dedup_key"]).To(Equal(event["dedup_key"]))
+		Expect(event["payload"]).To(HaveKeyWithValue("source", "apps/team"))
+		Expect(event["payload"]).To(HaveKeyWithValue("summary", "[team] Digest: 2 events in 1 groups"))
+	})
+
+	It("should key digests of other records apart", func() {
+		notification.Event = nil
+		notification.Record = ""
+		notification.Records = []string{"team-3f2a9c1b7e"}
+		key := notification.AlertKey()
+		notification.Records = append(notification.Records, "team-8d1e4a0c2f")
+		Expect(notification.AlertKey()).NotTo(Equal(key))
+		notification.Records = nil
+		Expect(notification.AlertKey()).To(BeEmpty())
+	})
+
+	It("should keep summaries within the limit", func() {
+		notification.Event.Reason = strings.Repeat("é", 600)
+		Expect(build().Send(notification)).To(Succeed())
+		summary := standIn.Events()[0]["payload"].(map[string]interface{})["summary"].(string)
+		Expect(len(summary)).To(BeNumerically("<=", maxPagerDutySummary))
+		Expect(summary).To(HaveSuffix("é..."))
+	})
+
+	It("should fail on rejected events", func() {
+		channel, err := buildTestChannel(notification.Notifier, spec, newKeySecret("pagerduty", "routingKey", "short"))
+		Expect(err).NotTo(HaveOccurred())
+		err = channel.Send(notification)
+		Expect(err).To(MatchError(ContainSubstring("400 Bad Request")))
+		Expect(err).To(MatchError(ContainSubstring("'routing_key' must be 32 characters")))
+	})
+
+	It("should post to the PagerDuty endpoint by default", func() {
+		spec.URL = ""
+		channel := build()
+		Expect(channel.(*pagerDutyChannel).url).To(Equal(emailv1.DefaultPagerDutyURL))
+		Expect(channelKey(channel)).To(HavePrefix("pagerduty:"))
+		Expect(channelType(channel)).To(Equal("pagerduty"))
+	})
+
+	It("should read the routing key from a Secret", func() {
+		_, err := buildTestChannel(notification.Notifier, spec)
+		Expect(errors.Cause(err)).To(Equal(errInvalidConfig))
+
+		_, err = buildTestChannel(notification.Notifier, spec, newKeySecret("pagerduty", "routingKey", " "))
+		Expect(errors.Cause(err)).To(Equal(errInvalidConfig))
+	})
+
+	It("should watch the routing key Secret", func() {
+		notifier := notification.Notifier.DeepCopy()
+		notifier.Spec.Channels = []emailv1.Channel{spec}
+		Expect((&NotifierReconciler{}).secretsIndex(notifier)).To(Equal([]string{"apps/pagerduty"}))
+
+		policy := &emailv1.EscalationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: "apps"},
+			Spec:       emailv1.EscalationPolicySpec{Tiers: []emailv1.EscalationTier{{Name: "on-call", Channels: []emailv1.Channel{spec}}}},
+		}
+		Expect(escalationPolicySecretsIndex(policy)).To(Equal([]string{"apps/pagerduty"}))
+	})
+})
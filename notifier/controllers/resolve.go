@@ -0,0 +1,375 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	ctx "context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	emailv1 "std/api/v1"
+)
+
+// DefaultPodCheckInterval is how often the Pods of open incidents are checked for readiness
+const DefaultPodCheckInterval = 30 * time.Second
+
+// maxOpenIncidents bounds the incidents tracked in the Notifier status
+const maxOpenIncidents = 100
+
+// ResolvedNotice tells which notification is resolved, and why
+type ResolvedNotice struct {
+	// Record is the NotificationRecord of the original notification
+	Record string `json:"record"`
+	// Resolution is Ready, Deleted or Quiet
+	Resolution string `json:"resolution"`
+	// Description explains the resolution, like "the Pod is Ready again"
+	Description string `json:"description"`
+	// OpenedTime is when the original notification was sent
+	OpenedTime metav1.Time `json:"openedTime"`
+	// Duration is how long the incident was open
+	Duration metav1.Duration `json:"duration"`
+}
+
+// sameObject matches the involved objects by kind, namespace and name, a recreated Pod continues the incident
+func sameObject(a, b corev1.ObjectReference) bool {
+	return a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// lastSeen is the last occurrence of the recorded Event
+func lastSeen(record *emailv1.NotificationRecord) time.Time {
+	switch {
+	case !record.Spec.Event.LastTimestamp.IsZero():
+		return record.Spec.Event.LastTimestamp.Time
+	case !record.Spec.Event.FirstTimestamp.IsZero():
+		return record.Spec.Event.FirstTimestamp.Time
+	}
+	return record.CreationTimestamp.Time
+}
+
+// trackIncidents opens an incident for every object notified about, and moves its last seen time with the recurring warnings.
+// Warnings quiet for the whole window by the time they are sent open none, and
+// a warning recurring after the incident was resolved reopens it until the resolved notification is delivered.
+// Beyond maxOpenIncidents, the incidents seen longest ago are dropped without a resolved notification.
+func trackIncidents(notifier *emailv1.Notifier, records []emailv1.NotificationRecord, now time.Time) {
+	status := &notifier.Status
+	policy := notifier.Spec.Resolve
+	if policy == nil {
+		status.OpenIncidents = nil
+		return
+	}
+
+	for i := range records {
+		record := &records[i]
+		ref := record.Spec.Event.InvolvedObject
+		seen := lastSeen(record)
+		if resolved := record.Status.ResolvedTime; resolved != nil && !seen.After(resolved.Time) {
+			continue
+		}
+
+		var incident *emailv1.OpenIncident
+		for j := range status.OpenIncidents {
+			if sameObject(status.OpenIncidents[j].InvolvedObject, ref) {
+				incident = &status.OpenIncidents[j]
+			}
+		}
+		if incident == nil {
+			if record.Status.State != emailv1.RecordSent || record.Status.SentTime == nil || now.Sub(seen) >= policy.GetWindow() {
+				continue
+			}
+			status.OpenIncidents = append(status.OpenIncidents, emailv1.OpenIncident{
+				InvolvedObject: corev1.ObjectReference{
+					APIVersion: ref.APIVersion,
+					Kind:       ref.Kind,
+					Namespace:  ref.Namespace,
+					Name:       ref.Name,
+				},
+				Record:       record.GetName(),
+				Reason:       record.Spec.Event.Reason,
+				OpenedTime:   *record.Status.SentTime,
+				LastSeenTime: metav1.NewTime(seen),
+			})
+			continue
+		}
+
+		if !seen.After(incident.LastSeenTime.Time) {
+			continue
+		}
+		incident.LastSeenTime = metav1.NewTime(seen)
+		if incident.ResolvedTime != nil && seen.After(incident.ResolvedTime.Time) {
+			// Not over after all
+			incident.Resolution = ""
+			incident.ResolvedTime = nil
+			incident.NextAttemptTime = nil
+			incident.FailedAttempts = 0
+			incident.DeliveredTo = nil
+			for j := range incident.Alerts {
+				incident.Alerts[j].DeliveredTo = nil
+			}
+		}
+	}
+
+	for len(status.OpenIncidents) > maxOpenIncidents {
+		oldest := 0
+		for j := range status.OpenIncidents {
+			if status.OpenIncidents[j].LastSeenTime.Before(&status.OpenIncidents[oldest].LastSeenTime) {
+				oldest = j
+			}
+		}
+		status.OpenIncidents = append(status.OpenIncidents[:oldest], status.OpenIncidents[oldest+1:]...)
+	}
+}
+
+// incidentResolution decides whether the incident is over, the resolution is empty while it is open.
+// An incident about a Pod is over once the Pod stayed Ready since the last warning, or is gone.
+// Any incident is over when no warning recurred within the window.
+// It returns how long to wait before deciding again.
+func incidentResolution(reader client.Reader, policy *emailv1.ResolvePolicy, incident *emailv1.OpenIncident, now time.Time, checkInterval time.Duration) (string, time.Duration, error) {
+	quiet := policy.GetWindow() - now.Sub(incident.LastSeenTime.Time)
+	if quiet <= 0 {
+		return emailv1.ResolvedQuiet, 0, nil
+	}
+	ref := incident.InvolvedObject
+	if ref.Kind != "Pod" || (ref.APIVersion != "" && ref.APIVersion != "v1") {
+		return "", quiet, nil
+	}
+
+	pod, err := getPod(reader, ref.Namespace, ref.Name)
+	if err != nil {
+		return "", minDuration(quiet, checkInterval), err
+	} else if pod == nil {
+		return emailv1.ResolvedDeleted, 0, nil
+	}
+
+	ready := podCondition(pod, corev1.PodReady)
+	if ready == nil || ready.Status != corev1.ConditionTrue || ready.LastTransitionTime.Time.Before(incident.LastSeenTime.Time) {
+		// Not Ready, or it was Ready when the last warning came
+		return "", minDuration(quiet, checkInterval), nil
+	}
+	if wait := policy.GetReadyFor() - now.Sub(ready.LastTransitionTime.Time); wait > 0 {
+		return "", minDuration(quiet, wait), nil
+	}
+	return emailv1.ResolvedReady, 0, nil
+}
+
+// getPod reads the Pod directly from the API server, so no informer is started for Pods. A missing Pod is nil.
+func getPod(reader client.Reader, namespace, name string) (*corev1.Pod, error) {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+	err := reader.Get(ctx.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, object)
+	if k8serror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get Pod %s/%s", namespace, name)
+	}
+	pod := &corev1.Pod{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), pod)
+	return pod, errors.Wrapf(err, "Failed to convert Pod %s/%s", namespace, name)
+}
+
+func podCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) *corev1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == conditionType {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// resolutionText explains the resolution in notifications
+func resolutionText(resolution string, policy *emailv1.ResolvePolicy) string {
+	switch resolution {
+	case emailv1.ResolvedReady:
+		return "the Pod is Ready again"
+	case emailv1.ResolvedDeleted:
+		return "the Pod was deleted"
+	}
+	return fmt.Sprintf("no warning for %s", policy.GetWindow())
+}
+
+// resolve tracks the incidents of the Notifier, and sends the resolved notifications of the incidents which are over.
+// The records of a resolved incident are marked first, so they don't reopen it, and claim the resolved notification:
+// an incident found resolved again, while its record is marked already, was notified by a reconcile whose status update was lost.
+// It returns when to check the open incidents again.
+func (r *NotifierReconciler) resolve(notifier *emailv1.Notifier, channels []Channel, template *messageTemplate, records []emailv1.NotificationRecord, now time.Time) (time.Duration, error) {
+	trackIncidents(notifier, records, now)
+	policy := notifier.Spec.Resolve
+	checkInterval := r.PodCheckInterval
+	if checkInterval == 0 {
+		checkInterval = DefaultPodCheckInterval
+	}
+
+	var next time.Duration
+	incidents := notifier.Status.OpenIncidents
+	open := []emailv1.OpenIncident{}
+	for i := range incidents {
+		incident := &incidents[i]
+		if incident.Resolution == "" {
+			resolution, wait, err := incidentResolution(r, policy, incident, now, checkInterval)
+			if err != nil {
+				// The window still resolves it
+				r.Log.Info("Failed to check the incident", "notifier", notifier.GetName(), "error", err.Error())
+			}
+			if resolution == "" {
+				next = minDuration(next, wait)
+				open = append(open, *incident)
+				continue
+			}
+			if resolvedBefore(records, incident) {
+				continue
+			}
+			resolved := metav1.NewTime(now)
+			incident.Resolution = resolution
+			incident.ResolvedTime = &resolved
+		}
+		if incident.NextAttemptTime != nil && incident.NextAttemptTime.After(now) {
+			next = minDuration(next, incident.NextAttemptTime.Sub(now))
+			open = append(open, *incident)
+			continue
+		}
+
+		if err := r.markResolved(records, incident); err != nil {
+			notifier.Status.OpenIncidents = append(open, incidents[i:]...)
+			return 0, err
+		}
+		if err := r.sendResolved(notifier, channels, template, incident, now); err != nil {
+			incident.FailedAttempts++
+			notifier.Status.LastError = fmt.Sprintf("resolved notification about %s %s failed: %v",
+				incident.InvolvedObject.Kind, referenceName(incident.InvolvedObject), err)
+			if incident.FailedAttempts >= notifier.Spec.Retry.GetMaxAttempts() {
+				r.dropResolved(notifier, incident)
+				continue
+			}
+			backoff := retryBackoff(notifier.Spec.Retry, incident.FailedAttempts)
+			retry := metav1.NewTime(now.Add(backoff))
+			incident.NextAttemptTime = &retry
+			next = minDuration(next, backoff)
+			open = append(open, *incident)
+		}
+	}
+	notifier.Status.OpenIncidents = open
+	return next, nil
+}
+
+// markResolved stamps the records of the incident which occurred before it was resolved, records of an earlier resolution included.
+// The alerts of the notified records are resolved along with the incident.
+func (r *NotifierReconciler) markResolved(records []emailv1.NotificationRecord, incident *emailv1.OpenIncident) error {
+	for i := range records {
+		record := &records[i]
+		if !sameObject(record.Spec.Event.InvolvedObject, incident.InvolvedObject) ||
+			lastSeen(record).After(incident.ResolvedTime.Time) ||
+			(record.Status.ResolvedTime != nil && !record.Status.ResolvedTime.Before(incident.ResolvedTime)) {
+			continue
+		}
+		record.Status.ResolvedTime = incident.ResolvedTime
+		if err := r.Status().Update(ctx.TODO(), record); err != nil {
+			return err
+		}
+		if record.Status.SentTime != nil && record.GetName() != incident.Record && !hasAlert(incident, record.GetName()) {
+			incident.Alerts = append(incident.Alerts, emailv1.ResolvedAlert{Record: record.GetName()})
+		}
+	}
+	return nil
+}
+
+// resolvedBefore reports whether the record of the incident was marked resolved since the last warning
+func resolvedBefore(records []emailv1.NotificationRecord, incident *emailv1.OpenIncident) bool {
+	for i := range records {
+		if records[i].GetName() == incident.Record {
+			resolved := records[i].Status.ResolvedTime
+			return resolved != nil && !resolved.Before(&incident.LastSeenTime)
+		}
+	}
+	return false
+}
+
+func hasAlert(incident *emailv1.OpenIncident, record string) bool {
+	for _, alert := range incident.Alerts {
+		if alert.Record == record {
+			return true
+		}
+	}
+	return false
+}
+
+// sendResolved notifies every channel of the Notifier that the incident is over
+func (r *NotifierReconciler) sendResolved(notifier *emailv1.Notifier, channels []Channel, template *messageTemplate, incident *emailv1.OpenIncident, now time.Time) error {
+	notification := &Notification{
+		Notifier: notifier,
+		Event: &corev1.Event{
+			Type:           corev1.EventTypeNormal,
+			Reason:         incident.Reason,
+			InvolvedObject: incident.InvolvedObject,
+		},
+		Record: incident.Record,
+		Resolved: &ResolvedNotice{
+			Record:      incident.Record,
+			Resolution:  incident.Resolution,
+			Description: resolutionText(incident.Resolution, notifier.Spec.Resolve),
+			OpenedTime:  incident.OpenedTime,
+			Duration:    metav1.Duration{Duration: incident.ResolvedTime.Sub(incident.OpenedTime.Time).Round(time.Second)},
+		},
+	}
+	r.render(notifier, template, notification)
+	r.Log.Info("Incident resolved", "notifier", notifier.GetName(), "kind", incident.InvolvedObject.Kind,
+		"object", referenceName(incident.InvolvedObject), "resolution", incident.Resolution)
+	if err := r.sendAll(notifier, channels, notification, &incident.DeliveredTo); err != nil {
+		return err
+	}
+
+	// Every notified record triggered its own alert, the channels tracking alerts resolve each of them
+	alerting := []Channel{}
+	for _, channel := range channels {
+		if _, ok := channel.(Acknowledger); ok {
+			alerting = append(alerting, channel)
+		}
+	}
+	for len(incident.Alerts) > 0 {
+		alert := &incident.Alerts[0]
+		resolved := *notification.Resolved
+		resolved.Record = alert.Record
+		alertNotification := *notification
+		alertNotification.Record = alert.Record
+		alertNotification.Resolved = &resolved
+		if err := r.sendAll(notifier, alerting, &alertNotification, &alert.DeliveredTo); err != nil {
+			return err
+		}
+		incident.Alerts = incident.Alerts[1:]
+	}
+	return nil
+}
+
+// dropResolved reports an incident given up after the last retry of its resolved notification, like a dead-lettered record
+func (r *NotifierReconciler) dropResolved(notifier *emailv1.Notifier, incident *emailv1.OpenIncident) {
+	r.Log.Info("Giving up on resolved notification", "notifier", notifier.GetName(), "kind", incident.InvolvedObject.Kind,
+		"object", referenceName(incident.InvolvedObject), "attempts", incident.FailedAttempts, "error", notifier.Status.LastError)
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(notifierObject(notifier), corev1.EventTypeWarning, "ResolveFailed",
+		"Gave up on the resolved notification about %s %s after %d attempts: %s",
+		incident.InvolvedObject.Kind,
+		referenceName(incident.InvolvedObject),
+		incident.FailedAttempts,
+		notifier.Status.LastError)
+}
@@ -0,0 +1,376 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("incidents", func() {
+	var (
+		now      time.Time
+		notifier *emailv1.Notifier
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+		notifier = &emailv1.Notifier{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"},
+			Spec: emailv1.NotifierSpec{Resolve: &emailv1.ResolvePolicy{
+				Window:   &metav1.Duration{Duration: 10 * time.Minute},
+				ReadyFor: &metav1.Duration{Duration: time.Minute},
+			}},
+		}
+	})
+
+	// newRecord is a record about the Pod, which last occurred at the time
+	newRecord := func(name, pod string, seen time.Time, state emailv1.RecordState) emailv1.NotificationRecord {
+		record := emailv1.NotificationRecord{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", CreationTimestamp: metav1.NewTime(seen)},
+			Spec: emailv1.NotificationRecordSpec{
+				Notifier: "team",
+				Event: emailv1.EventSnapshot{
+					Type:           corev1.EventTypeWarning,
+					Reason:         "BackOff",
+					InvolvedObject: podRef(pod),
+					LastTimestamp:  metav1.NewTime(seen),
+				},
+			},
+		}
+		record.Status.State = state
+		if state == emailv1.RecordSent {
+			sent := metav1.NewTime(seen)
+			record.Status.SentTime = &sent
+		}
+		return record
+	}
+
+	incidents := func() []emailv1.OpenIncident {
+		return notifier.Status.OpenIncidents
+	}
+
+	Context("tracking", func() {
+		It("should open an incident per notified object", func() {
+			records := []emailv1.NotificationRecord{
+				newRecord("team-1", "web-1", now.Add(-time.Minute), emailv1.RecordSent),
+				newRecord("team-2", "web-1", now, emailv1.RecordSent),
+				newRecord("team-3", "web-2", now, emailv1.RecordPending),
+				newRecord("team-4", "web-3", now.Add(-time.Hour), emailv1.RecordSent),
+			}
+			records[1].Spec.Event.Reason = "Unhealthy"
+			trackIncidents(notifier, records, now)
+
+			Expect(incidents()).To(HaveLen(1))
+			incident := incidents()[0]
+			Expect(incident.InvolvedObject).To(Equal(podRef("web-1")))
+			Expect(incident.Record).To(Equal("team-1"))
+			Expect(incident.Reason).To(Equal("BackOff"))
+			Expect(incident.OpenedTime.Time).To(Equal(now.Add(-time.Minute)))
+			Expect(incident.LastSeenTime.Time).To(Equal(now))
+
+			records[2].Status.State = emailv1.RecordSent
+			records[2].Status.SentTime = &metav1.Time{Time: now}
+			trackIncidents(notifier, records, now)
+			Expect(incidents()).To(HaveLen(2))
+		})
+
+		It("should follow the recurring warnings", func() {
+			record := newRecord("team-1", "web-1", now, emailv1.RecordSent)
+			trackIncidents(notifier, []emailv1.NotificationRecord{record}, now)
+
+			record.Spec.Event.Count = 5
+			record.Spec.Event.LastTimestamp = metav1.NewTime(now.Add(5 * time.Minute))
+			suppressed := newRecord("team-2", "web-1", now.Add(3*time.Minute), emailv1.RecordSuppressed)
+			trackIncidents(notifier, []emailv1.NotificationRecord{record, suppressed}, now.Add(5*time.Minute))
+			Expect(incidents()).To(HaveLen(1))
+			Expect(incidents()[0].LastSeenTime.Time).To(Equal(now.Add(5 * time.Minute)))
+		})
+
+		It("should reopen an incident the warning recurs for before it is notified", func() {
+			record := newRecord("team-1", "web-1", now, emailv1.RecordSent)
+			trackIncidents(notifier, []emailv1.NotificationRecord{record}, now)
+			resolved := metav1.NewTime(now.Add(time.Minute))
+			retry := metav1.NewTime(now.Add(2 * time.Minute))
+			notifier.Status.OpenIncidents[0].Resolution = emailv1.ResolvedReady
+			notifier.Status.OpenIncidents[0].ResolvedTime = &resolved
+			notifier.Status.OpenIncidents[0].NextAttemptTime = &retry
+
+			record.Spec.Event.LastTimestamp = metav1.NewTime(now.Add(90 * time.Second))
+			trackIncidents(notifier, []emailv1.NotificationRecord{record}, now.Add(90*time.Second))
+			Expect(incidents()[0].Resolution).To(BeEmpty())
+			Expect(incidents()[0].ResolvedTime).To(BeNil())
+			Expect(incidents()[0].NextAttemptTime).To(BeNil())
+		})
+
+		It("should not reopen resolved incidents until the warning recurs", func() {
+			record := newRecord("team-1", "web-1", now, emailv1.RecordSent)
+			resolved := metav1.NewTime(now.Add(time.Minute))
+			record.Status.ResolvedTime = &resolved
+			trackIncidents(notifier, []emailv1.NotificationRecord{record}, now.Add(2*time.Minute))
+			Expect(incidents()).To(BeEmpty())
+
+			record.Spec.Event.LastTimestamp = metav1.NewTime(now.Add(3 * time.Minute))
+			trackIncidents(notifier, []emailv1.NotificationRecord{record}, now.Add(3*time.Minute))
+			Expect(incidents()).To(HaveLen(1))
+			Expect(incidents()[0].LastSeenTime.Time).To(Equal(now.Add(3 * time.Minute)))
+		})
+
+		It("should track a bounded number of incidents", func() {
+			records := []emailv1.NotificationRecord{}
+			for i := 0; i <= maxOpenIncidents; i++ {
+				seen := now.Add(time.Duration(i-maxOpenIncidents) * time.Second)
+				records = append(records, newRecord(fmt.Sprintf("team-%d", i), fmt.Sprintf("web-%d", i), seen, emailv1.RecordSent))
+			}
+			records[0], records[1] = records[1], records[0]
+			trackIncidents(notifier, records, now)
+			Expect(incidents()).To(HaveLen(maxOpenIncidents))
+			for _, incident := range incidents() {
+				Expect(incident.Record).NotTo(Equal("team-0"))
+			}
+		})
+
+		It("should forget the incidents when the Notifier stops resolving them", func() {
+			trackIncidents(notifier, []emailv1.NotificationRecord{newRecord("team-1", "web-1", now, emailv1.RecordSent)}, now)
+			Expect(incidents()).To(HaveLen(1))
+
+			notifier.Spec.Resolve = nil
+			trackIncidents(notifier, nil, now)
+			Expect(incidents()).To(BeNil())
+		})
+	})
+
+	Context("resolution", func() {
+		var incident *emailv1.OpenIncident
+
+		BeforeEach(func() {
+			incident = &emailv1.OpenIncident{
+				InvolvedObject: podRef("web-1"),
+				Record:         "team-1",
+				Reason:         "BackOff",
+				OpenedTime:     metav1.NewTime(now.Add(-5 * time.Minute)),
+				LastSeenTime:   metav1.NewTime(now.Add(-2 * time.Minute)),
+			}
+		})
+
+		readyPod := func(since time.Time) *corev1.Pod {
+			pod := newOwnedPod("web-1", nil)
+			pod.Status.Conditions = []corev1.PodCondition{{
+				Type:               corev1.PodReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(since),
+			}}
+			return pod
+		}
+
+		resolution := func(reader *objectReader) (string, time.Duration) {
+			resolution, wait, err := incidentResolution(reader, notifier.Spec.Resolve, incident, now, 30*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			return resolution, wait
+		}
+
+		It("should resolve once the Pod stayed Ready", func() {
+			resolved, wait := resolution(newObjectReader(readyPod(now.Add(-90 * time.Second))))
+			Expect(resolved).To(Equal(emailv1.ResolvedReady))
+			Expect(wait).To(BeZero())
+		})
+
+		It("should wait for the Pod to stay Ready", func() {
+			resolved, wait := resolution(newObjectReader(readyPod(now.Add(-20 * time.Second))))
+			Expect(resolved).To(BeEmpty())
+			Expect(wait).To(Equal(40 * time.Second))
+		})
+
+		It("should not take a Pod Ready before the last warning as recovered", func() {
+			resolved, wait := resolution(newObjectReader(readyPod(now.Add(-3 * time.Minute))))
+			Expect(resolved).To(BeEmpty())
+			Expect(wait).To(Equal(30 * time.Second))
+
+			resolved, _ = resolution(newObjectReader(newOwnedPod("web-1", nil)))
+			Expect(resolved).To(BeEmpty())
+		})
+
+		It("should resolve incidents about deleted Pods", func() {
+			resolved, _ := resolution(newObjectReader())
+			Expect(resolved).To(Equal(emailv1.ResolvedDeleted))
+		})
+
+		It("should resolve once the warnings stopped for the window", func() {
+			incident.InvolvedObject = corev1.ObjectReference{Kind: "Node", Name: "node-1"}
+			reader := newObjectReader()
+			resolved, wait := resolution(reader)
+			Expect(resolved).To(BeEmpty())
+			Expect(wait).To(Equal(8 * time.Minute))
+			Expect(reader.reads).To(BeZero())
+
+			incident.LastSeenTime = metav1.NewTime(now.Add(-10 * time.Minute))
+			resolved, _ = resolution(reader)
+			Expect(resolved).To(Equal(emailv1.ResolvedQuiet))
+			Expect(resolutionText(resolved, notifier.Spec.Resolve)).To(Equal("no warning for 10m0s"))
+		})
+	})
+
+	It("should retry the resolved notification, and give up after the last attempt", func() {
+		jitter := retryJitter
+		defer func() { retryJitter = jitter }()
+		retryJitter = func(max time.Duration) time.Duration { return max }
+
+		failing := newWebhookRecorder()
+		defer failing.Close()
+		failing.RespondWith(http.StatusBadGateway)
+		working := newWebhookRecorder()
+		defer working.Close()
+
+		notifier.Spec.Retry = &emailv1.RetryPolicy{MaxAttempts: 2, Backoff: &metav1.Duration{Duration: time.Minute}}
+		resolved := metav1.NewTime(now)
+		notifier.Status.OpenIncidents = []emailv1.OpenIncident{{
+			InvolvedObject: podRef("web-1"),
+			Record:         "team-1",
+			Reason:         "BackOff",
+			OpenedTime:     metav1.NewTime(now.Add(-time.Hour)),
+			LastSeenTime:   metav1.NewTime(now.Add(-5 * time.Minute)),
+			Resolution:     emailv1.ResolvedDeleted,
+			ResolvedTime:   &resolved,
+		}}
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(s), Log: ctrl.Log.WithName("resolve")}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{
+			{Type: emailv1.WebhookChannel, URL: working.URL},
+			{Type: emailv1.WebhookChannel, URL: failing.URL},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		next, err := r.resolve(notifier, channels, nil, nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(Equal(time.Minute))
+		Expect(incidents()).To(HaveLen(1))
+		Expect(incidents()[0].FailedAttempts).To(BeEquivalentTo(1))
+		Expect(incidents()[0].DeliveredTo).To(HaveLen(1))
+
+		// The working channel isn't notified again
+		next, err = r.resolve(notifier, channels, nil, nil, now.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(working.Payloads()).To(HaveLen(1))
+		Expect(failing.Payloads()).To(HaveLen(2))
+		Expect(incidents()).To(BeEmpty())
+		Expect(next).To(BeZero())
+		Expect(notifier.Status.LastError).To(ContainSubstring("resolved notification about Pod apps/web-1 failed"))
+	})
+
+	It("should resolve the alerts of every notified record", func() {
+		standIn := newPagerDutyStandIn()
+		defer standIn.Close()
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+
+		first := newRecord("team-1", "web-1", now.Add(-20*time.Minute), emailv1.RecordSent)
+		second := newRecord("team-2", "web-1", now.Add(-15*time.Minute), emailv1.RecordSent)
+		second.Spec.Event.Reason = "Unhealthy"
+		duplicate := newRecord("team-3", "web-1", now.Add(-14*time.Minute), emailv1.RecordSuppressed)
+		records := []emailv1.NotificationRecord{first, second, duplicate}
+
+		s := runtime.NewScheme()
+		Expect(corev1.AddToScheme(s)).To(Succeed())
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{
+			Client: fake.NewFakeClientWithScheme(s, &first, &second, &duplicate, newKeySecret("pagerduty", "routingKey", testRoutingKey)),
+			Log:    ctrl.Log.WithName("resolve"),
+		}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{
+			{Type: emailv1.WebhookChannel, URL: recorder.URL},
+			{
+				Type:                emailv1.PagerDutyChannel,
+				URL:                 standIn.URL + "/v2/enqueue",
+				RoutingKeySecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "pagerduty"}, Key: "routingKey"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		trackIncidents(notifier, records, now.Add(-15*time.Minute))
+		Expect(incidents()).To(HaveLen(1))
+		Expect(incidents()[0].Record).To(Equal("team-1"))
+		_, err = r.resolve(notifier, channels, nil, records, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incidents()).To(BeEmpty())
+
+		Expect(recorder.Payloads()).To(HaveLen(1))
+		Expect(standIn.Events()).To(Equal([]map[string]interface{}{
+			{"routing_key": testRoutingKey, "event_action": "resolve", "dedup_key": "apps/team-1"},
+			{"routing_key": testRoutingKey, "event_action": "resolve", "dedup_key": "apps/team-2"},
+		}))
+	})
+
+	It("should not notify an incident again when the status update was lost", func() {
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+
+		record := newRecord("team-1", "web-1", now.Add(-20*time.Minute), emailv1.RecordSent)
+		records := []emailv1.NotificationRecord{record}
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(s, &record), Log: ctrl.Log.WithName("resolve")}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL}})
+		Expect(err).NotTo(HaveOccurred())
+
+		trackIncidents(notifier, records, now.Add(-15*time.Minute))
+		lost := notifier.Status.DeepCopy()
+		_, err = r.resolve(notifier, channels, nil, records, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Payloads()).To(HaveLen(1))
+		Expect(records[0].Status.ResolvedTime).NotTo(BeNil())
+
+		// The Notifier status update conflicted, the marked record still tells the incident was notified
+		notifier.Status = *lost
+		_, err = r.resolve(notifier, channels, nil, records, now.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Payloads()).To(HaveLen(1))
+		Expect(incidents()).To(BeEmpty())
+	})
+
+	It("should tell which notification is resolved", func() {
+		notification := &Notification{
+			Notifier: notifier,
+			Event:    &corev1.Event{Type: corev1.EventTypeNormal, Reason: "BackOff", InvolvedObject: podRef("web-1")},
+			Resolved: &ResolvedNotice{
+				Record:      "team-3f2a9c1b7e",
+				Resolution:  emailv1.ResolvedReady,
+				Description: "the Pod is Ready again",
+				OpenedTime:  metav1.NewTime(now),
+				Duration:    metav1.Duration{Duration: 15 * time.Minute},
+			},
+		}
+		Expect(notification.Subject()).To(Equal("[team][resolved] BackOff: apps/web-1"))
+		Expect(notification.Text()).To(Equal("Resolved!\n\nReason: BackOff\nPod: apps/web-1\nResolution: the Pod is Ready again\n" +
+			"Open for: 15m0s\nFirst notified: 2019-07-01T12:00:00Z, record team-3f2a9c1b7e\n"))
+
+		attachment := SlackPayload(notification).(map[string]interface{})["attachments"].([]map[string]interface{})[0]
+		Expect(attachment["color"]).To(Equal("good"))
+		Expect(attachment["text"]).To(Equal("Resolved, the Pod is Ready again"))
+		Expect(WebhookPayload(notification)).To(HaveKeyWithValue("resolved", notification.Resolved))
+	})
+})
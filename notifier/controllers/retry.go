@@ -0,0 +1,97 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"math/rand"
+	"time"
+
+	emailv1 "std/api/v1"
+)
+
+// retryJitter picks a random delay up to max, so records failed together aren't retried together
+var retryJitter = func(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// retryBackoff is the delay before the next attempt, after the given number of failed attempts.
+// The backoff doubles with every failure up to the max backoff, and its second half is random.
+func retryBackoff(policy *emailv1.RetryPolicy, failures int32) time.Duration {
+	backoff, max := policy.GetBackoff(), policy.GetMaxBackoff()
+	for i := int32(1); i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff/2 + retryJitter(backoff-backoff/2)
+}
+
+// retryDue decides whether a failed record may be attempted again, or how long to wait for it
+func retryDue(record *emailv1.NotificationRecord, now time.Time) (bool, time.Duration) {
+	if record.Status.State != emailv1.RecordFailed || record.Status.NextAttemptTime == nil {
+		return true, 0
+	}
+	wait := record.Status.NextAttemptTime.Sub(now)
+	if wait <= 0 {
+		return true, 0
+	}
+	return false, wait
+}
+
+// lastFailure finds the record failed last, and how long until the first retry is due
+func lastFailure(records []emailv1.NotificationRecord, now time.Time) (*emailv1.NotificationRecord, time.Duration) {
+	var failed *emailv1.NotificationRecord
+	var next time.Duration
+	for i := range records {
+		record := &records[i]
+		if record.Status.State != emailv1.RecordFailed {
+			continue
+		}
+		if failed == nil || (record.Status.LastAttemptTime != nil && !record.Status.LastAttemptTime.Before(failed.Status.LastAttemptTime)) {
+			failed = record
+		}
+		if _, wait := retryDue(record, now); wait > 0 {
+			next = minDuration(next, wait)
+		} else {
+			next = minDuration(next, time.Second)
+		}
+	}
+	return failed, next
+}
+
+// delivered reports whether every record went through the channel already
+func delivered(records []emailv1.NotificationRecord, key string) bool {
+	for _, record := range records {
+		if !containsString(record.Status.DeliveredTo, key) {
+			return false
+		}
+	}
+	return len(records) > 0
+}
+
+// deliveredToAll reports whether each of the lists has the channel
+func deliveredToAll(lists []*[]string, key string) bool {
+	for _, list := range lists {
+		if !containsString(*list, key) {
+			return false
+		}
+	}
+	return len(lists) > 0
+}
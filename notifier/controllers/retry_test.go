@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("retry", func() {
+	var (
+		jitter func(time.Duration) time.Duration
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		jitter = retryJitter
+		now = time.Date(2019, 7, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		retryJitter = jitter
+	})
+
+	failedRecord := func(name string, lastAttempt, nextAttempt time.Duration) emailv1.NotificationRecord {
+		last := metav1.NewTime(now.Add(lastAttempt))
+		next := metav1.NewTime(now.Add(nextAttempt))
+		record := emailv1.NotificationRecord{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		record.Status.State = emailv1.RecordFailed
+		record.Status.LastAttemptTime = &last
+		record.Status.NextAttemptTime = &next
+		record.Status.LastError = name + " failed"
+		return record
+	}
+
+	It("should back off exponentially up to the max backoff", func() {
+		retryJitter = func(max time.Duration) time.Duration { return max }
+		Expect(retryBackoff(nil, 1)).To(Equal(emailv1.DefaultRetryBackoff))
+		Expect(retryBackoff(nil, 3)).To(Equal(4 * emailv1.DefaultRetryBackoff))
+		Expect(retryBackoff(nil, 30)).To(Equal(emailv1.DefaultMaxRetryBackoff))
+
+		policy := &emailv1.RetryPolicy{
+			Backoff:    &metav1.Duration{Duration: time.Second},
+			MaxBackoff: &metav1.Duration{Duration: 5 * time.Second},
+		}
+		Expect(retryBackoff(policy, 2)).To(Equal(2 * time.Second))
+		Expect(retryBackoff(policy, 4)).To(Equal(5 * time.Second))
+
+		retryJitter = func(max time.Duration) time.Duration { return 0 }
+		Expect(retryBackoff(policy, 2)).To(Equal(time.Second), "half of the backoff is jitter")
+	})
+
+	It("should spread the retries", func() {
+		policy := &emailv1.RetryPolicy{Backoff: &metav1.Duration{Duration: time.Minute}}
+		delays := map[time.Duration]bool{}
+		for i := 0; i < 20; i++ {
+			delay := retryBackoff(policy, 1)
+			Expect(delay).To(BeNumerically(">=", 30*time.Second))
+			Expect(delay).To(BeNumerically("<=", time.Minute))
+			delays[delay] = true
+		}
+		Expect(len(delays)).To(BeNumerically(">", 1))
+	})
+
+	It("should wait for the next attempt of failed records only", func() {
+		record := failedRecord("waiting", -time.Minute, 30*time.Second)
+		due, wait := retryDue(&record, now)
+		Expect(due).To(BeFalse())
+		Expect(wait).To(Equal(30 * time.Second))
+
+		Expect(retryDue(&record, now.Add(time.Minute))).To(BeTrue())
+
+		record.Status.State = emailv1.RecordPending
+		Expect(retryDue(&record, now)).To(BeTrue())
+	})
+
+	It("should report the last failure and the first retry", func() {
+		sent := emailv1.NotificationRecord{}
+		sent.Status.State = emailv1.RecordSent
+		records := []emailv1.NotificationRecord{
+			failedRecord("older", -time.Hour, time.Minute),
+			sent,
+			failedRecord("newer", -time.Minute, 5*time.Minute),
+		}
+
+		failed, next := lastFailure(records, now)
+		Expect(failed.GetName()).To(Equal("newer"))
+		Expect(next).To(Equal(time.Minute))
+
+		failed, next = lastFailure(records[1:2], now)
+		Expect(failed).To(BeNil())
+		Expect(next).To(BeZero())
+	})
+
+	It("should tell which channels received all records", func() {
+		records := []emailv1.NotificationRecord{{}, {}}
+		records[0].Status.DeliveredTo = []string{"email:team@example.com", "slack:0123456789ab"}
+		records[1].Status.DeliveredTo = []string{"email:team@example.com"}
+		Expect(delivered(records, "email:team@example.com")).To(BeTrue())
+		Expect(delivered(records, "slack:0123456789ab")).To(BeFalse())
+		Expect(delivered(nil, "email:team@example.com")).To(BeFalse())
+
+		webhook, err := newWebhookChannel(nil, "https://hooks.example.com/T000/secret", emailv1.SlackChannel)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channelKey(webhook)).To(HavePrefix("slack:"))
+		Expect(channelKey(webhook)).NotTo(ContainSubstring("secret"))
+		Expect(channelKey(&emailChannel{to: "team@example.com"})).To(Equal("email:team@example.com"))
+	})
+})
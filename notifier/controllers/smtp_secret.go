@@ -0,0 +1,86 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Keys of a Secret holding SMTP server settings
+const (
+	SMTPSecretHostKey     = "host"
+	SMTPSecretPortKey     = "port"
+	SMTPSecretUsernameKey = "username"
+	SMTPSecretPasswordKey = "password"
+	SMTPSecretFromKey     = "from"
+	SMTPSecretTLSKey      = "tls"
+	SMTPSecretAuthKey     = "auth"
+)
+
+// SMTPConfigFromSecret overrides the defaults with the settings stored in the Secret.
+// The Secret has to provide at least the host, everything else falls back to the defaults.
+func SMTPConfigFromSecret(secret *corev1.Secret, defaults SMTPConfig) (SMTPConfig, error) {
+	config := defaults
+	name := secret.GetNamespace() + "/" + secret.GetName()
+
+	value := func(key string) (string, bool) {
+		data, found := secret.Data[key]
+		return strings.TrimSpace(string(data)), found
+	}
+
+	host, found := value(SMTPSecretHostKey)
+	if !found || host == "" {
+		return config, errors.Errorf("Secret %s: missing required key %q", name, SMTPSecretHostKey)
+	}
+	config.Host = host
+
+	if port, found := value(SMTPSecretPortKey); found {
+		number, err := strconv.Atoi(port)
+		if err != nil {
+			return config, errors.Errorf("Secret %s: key %q is not a number: %q", name, SMTPSecretPortKey, port)
+		}
+		config.Port = number
+	}
+
+	// Credentials of the default relay must never leak to a different server
+	config.Username, _ = value(SMTPSecretUsernameKey)
+	if password, found := secret.Data[SMTPSecretPasswordKey]; found {
+		config.Password = string(password)
+	} else {
+		config.Password = ""
+	}
+
+	if from, found := value(SMTPSecretFromKey); found {
+		config.From = from
+	}
+	if tls, found := value(SMTPSecretTLSKey); found {
+		config.TLS = TLSMode(strings.ToLower(tls))
+	}
+	if auth, found := value(SMTPSecretAuthKey); found {
+		config.Auth = AuthMethod(strings.ToLower(auth))
+	} else if config.Username == "" {
+		config.Auth = AuthNone
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, errors.Wrapf(err, "Secret %s", name)
+	}
+	return config, nil
+}
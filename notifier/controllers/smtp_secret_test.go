@@ -0,0 +1,102 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("SMTPConfigFromSecret", func() {
+	var defaults SMTPConfig
+
+	newSecret := func(data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "smtp", Namespace: "team"},
+			Data:       map[string][]byte{},
+		}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+
+	BeforeEach(func() {
+		defaults = SMTPConfig{
+			Host:     "relay.example.com",
+			Port:     587,
+			Username: "default-user",
+			Password: "default-password",
+			From:     "informer@example.com",
+			TLS:      TLSStartTLS,
+			Auth:     AuthPlain,
+		}
+	})
+
+	It("should override the defaults", func() {
+		config, err := SMTPConfigFromSecret(newSecret(map[string]string{
+			"host":     "mail.team.example.com",
+			"port":     "465",
+			"username": "team",
+			"password": "s3cret\n",
+			"from":     "team@example.com",
+			"tls":      "TLS",
+			"auth":     "login",
+		}), defaults)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(SMTPConfig{
+			Host:     "mail.team.example.com",
+			Port:     465,
+			Username: "team",
+			Password: "s3cret\n",
+			From:     "team@example.com",
+			TLS:      TLSImplicit,
+			Auth:     AuthLogin,
+		}))
+	})
+
+	It("should not reuse the default credentials for another host", func() {
+		config, err := SMTPConfigFromSecret(newSecret(map[string]string{
+			"host": "mail.team.example.com",
+		}), defaults)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Port).To(Equal(587))
+		Expect(config.From).To(Equal("informer@example.com"))
+		Expect(config.Username).To(BeEmpty())
+		Expect(config.Password).To(BeEmpty())
+		Expect(config.Auth).To(Equal(AuthNone))
+	})
+
+	It("should reject malformed Secrets", func() {
+		_, err := SMTPConfigFromSecret(newSecret(map[string]string{}), defaults)
+		Expect(err).To(MatchError(`Secret team/smtp: missing required key "host"`))
+
+		_, err = SMTPConfigFromSecret(newSecret(map[string]string{
+			"host": "mail.team.example.com",
+			"port": "smtp",
+		}), defaults)
+		Expect(err).To(MatchError(`Secret team/smtp: key "port" is not a number: "smtp"`))
+
+		_, err = SMTPConfigFromSecret(newSecret(map[string]string{
+			"host": "mail.team.example.com",
+			"auth": "cram-md5",
+		}), defaults)
+		Expect(err).To(MatchError(ContainSubstring(`unknown SMTP auth method "cram-md5"`)))
+	})
+})
@@ -0,0 +1,336 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// receivedMail is a message accepted by fakeSMTPServer
+type receivedMail struct {
+	From string
+	To   []string
+	Data string
+	TLS  bool
+	User string
+}
+
+// fakeSMTPServer is a minimal in-process SMTP server, good enough for net/smtp clients.
+// It supports STARTTLS, implicit TLS and the PLAIN and LOGIN auth mechanisms.
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	implicit  bool
+
+	// When set, clients must authenticate before sending mail
+	Username string
+	Password string
+
+	mu       sync.Mutex
+	messages []receivedMail
+}
+
+// newFakeSMTPServer starts a plain text server accepting STARTTLS
+func newFakeSMTPServer() *fakeSMTPServer {
+	return startFakeSMTPServer(false)
+}
+
+// newFakeSMTPSServer starts a server which speaks TLS from the first byte
+func newFakeSMTPSServer() *fakeSMTPServer {
+	return startFakeSMTPServer(true)
+}
+
+func startFakeSMTPServer(implicit bool) *fakeSMTPServer {
+	s := &fakeSMTPServer{
+		tlsConfig: selfSignedTLSConfig(),
+		implicit:  implicit,
+	}
+
+	var err error
+	if implicit {
+		s.listener, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	go s.serve()
+	return s
+}
+
+// Config returns a client configuration pointing to this server
+func (s *fakeSMTPServer) Config() SMTPConfig {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	config := SMTPConfig{
+		Host:               "127.0.0.1",
+		Port:               portNumber,
+		From:               "notifier@example.com",
+		TLS:                TLSNone,
+		Auth:               AuthNone,
+		InsecureSkipVerify: true,
+		Timeout:            5 * time.Second,
+	}
+	if s.implicit {
+		config.TLS = TLSImplicit
+	}
+	return config
+}
+
+// Messages returns a copy of all messages received so far
+func (s *fakeSMTPServer) Messages() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail{}, s.messages...)
+}
+
+// MessagesTo returns all received messages addressed to the recipient
+func (s *fakeSMTPServer) MessagesTo(rcpt string) []receivedMail {
+	matched := []receivedMail{}
+	for _, m := range s.Messages() {
+		for _, to := range m.To {
+			if to == rcpt {
+				matched = append(matched, m)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Reset forgets all received messages
+func (s *fakeSMTPServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+func (s *fakeSMTPServer) Close() {
+	s.listener.Close()
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_, isTLS := conn.(*tls.Conn)
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost fake ESMTP")
+
+	var user string
+	var mail *receivedMail
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250-localhost")
+			if !isTLS {
+				text.PrintfLine("250-STARTTLS")
+			}
+			text.PrintfLine("250-AUTH PLAIN LOGIN")
+			text.PrintfLine("250 8BITMIME")
+		case "STARTTLS":
+			if isTLS {
+				text.PrintfLine("503 already running TLS")
+				continue
+			}
+			text.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, isTLS = tlsConn, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			user = s.authenticate(text, arg)
+		case "MAIL":
+			if s.Username != "" && user == "" {
+				text.PrintfLine("530 authentication required")
+				continue
+			}
+			mail = &receivedMail{From: trimAddress(arg, "FROM:"), TLS: isTLS, User: user}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			if mail == nil {
+				text.PrintfLine("503 need MAIL first")
+				continue
+			}
+			mail.To = append(mail.To, trimAddress(arg, "TO:"))
+			text.PrintfLine("250 OK")
+		case "DATA":
+			if mail == nil || len(mail.To) == 0 {
+				text.PrintfLine("503 need RCPT first")
+				continue
+			}
+			text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := ioutil.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, *mail)
+			s.mu.Unlock()
+			mail = nil
+			text.PrintfLine("250 OK queued")
+		case "RSET":
+			mail = nil
+			text.PrintfLine("250 OK")
+		case "NOOP":
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// authenticate runs the AUTH exchange, returning the user name on success
+func (s *fakeSMTPServer) authenticate(text *textproto.Conn, arg string) string {
+	parts := strings.Fields(arg)
+	if len(parts) == 0 {
+		text.PrintfLine("501 missing mechanism")
+		return ""
+	}
+
+	readResponse := func(challenge string) string {
+		text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, _ := text.ReadLine()
+		decoded, _ := base64.StdEncoding.DecodeString(line)
+		return string(decoded)
+	}
+
+	var username, password string
+	switch strings.ToUpper(parts[0]) {
+	case "PLAIN":
+		var response string
+		if len(parts) > 1 {
+			decoded, _ := base64.StdEncoding.DecodeString(parts[1])
+			response = string(decoded)
+		} else {
+			response = readResponse("")
+		}
+		fields := strings.Split(response, "\x00")
+		if len(fields) == 3 {
+			username, password = fields[1], fields[2]
+		}
+	case "LOGIN":
+		username = readResponse("Username:")
+		password = readResponse("Password:")
+	default:
+		text.PrintfLine("504 unrecognized mechanism")
+		return ""
+	}
+
+	if username == s.Username && password == s.Password {
+		text.PrintfLine("235 authentication succeeded")
+		return username
+	}
+	text.PrintfLine("535 authentication failed")
+	return ""
+}
+
+func trimAddress(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(strings.ToUpper(arg), prefix) {
+		arg = arg[len(prefix):]
+	}
+	if i := strings.Index(arg, " "); i >= 0 {
+		arg = arg[:i]
+	}
+	return strings.Trim(arg, "<>")
+}
+
+// selfSignedTLSConfig serves a throwaway certificate
+func selfSignedTLSConfig() *tls.Config {
+	return &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate()}}
+}
+
+// selfSignedCertificate generates a throwaway certificate for 127.0.0.1 and localhost
+func selfSignedCertificate() tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"notifier tests"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// mailHeader extracts a header value from raw message data
+func mailHeader(data, header string) string {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, header+": ") {
+			return strings.TrimPrefix(line, header+": ")
+		}
+	}
+	return ""
+}
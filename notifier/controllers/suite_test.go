@@ -16,8 +16,15 @@ limitations under the License.
 package controllers
 
 import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -27,6 +34,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -40,6 +48,10 @@ import (
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var smtpServer *fakeSMTPServer
+var podLogs *fakeLogFetcher
+var stopMgr chan struct{}
+var certDir string
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -57,7 +69,8 @@ var _ = BeforeSuite(func(done Done) {
 		CRDDirectoryPaths: []string{filepath.Join("..", "config", "crd", "bases")},
 	}
 
-	cfg, err := testEnv.Start()
+	var err error
+	cfg, err = testEnv.Start()
 	Expect(err).ToNot(HaveOccurred())
 	Expect(cfg).ToNot(BeNil())
 
@@ -73,11 +86,104 @@ var _ = BeforeSuite(func(done Done) {
 	Expect(err).ToNot(HaveOccurred())
 	Expect(k8sClient).ToNot(BeNil())
 
+	By("starting the controllers")
+	smtpServer = newFakeSMTPServer()
+	podLogs = newFakeLogFetcher()
+
+	// The manager serves the Notifier admission webhook
+	certDir, err = ioutil.TempDir("", "notifier-webhook")
+	Expect(err).ToNot(HaveOccurred())
+	writeServingCerts(certDir)
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme.Scheme,
+		MetricsBindAddress: "0",
+		Port:               freePort(),
+	})
+	Expect(err).ToNot(HaveOccurred())
+	mgr.GetWebhookServer().CertDir = certDir
+
+	filters := NewFilterIndex()
+	notifierReconciler := NotifierReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Notifier"),
+		Scheme:   mgr.GetScheme(),
+		Mailer:   SMTPMailer{},
+		SMTP:     smtpServer.Config(),
+		Filters:  filters,
+		Recorder: mgr.GetEventRecorderFor("notifier-controller"),
+		Logs:     podLogs,
+
+		PodCheckInterval: 200 * time.Millisecond,
+	}
+	err = notifierReconciler.SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&ClusterNotifierReconciler{
+		NotifierReconciler: notifierReconciler,
+		Namespace:          "default",
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&EventReconciler{
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Event"),
+		Scheme:           mgr.GetScheme(),
+		ClusterNamespace: "default",
+		Filters:          filters,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&IncidentReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Incident"),
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	stopMgr = make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(stopMgr)).To(Succeed())
+	}()
+
 	close(done)
 }, 60)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
-	err := testEnv.Stop()
-	Expect(err).ToNot(HaveOccurred())
+	// BeforeSuite may have failed halfway, only undo what it got to
+	if stopMgr != nil {
+		close(stopMgr)
+	}
+	if smtpServer != nil {
+		smtpServer.Close()
+	}
+	if certDir != "" {
+		os.RemoveAll(certDir)
+	}
+	if cfg != nil {
+		// Only a started environment can be stopped
+		err := testEnv.Stop()
+		Expect(err).ToNot(HaveOccurred())
+	}
 })
+
+// writeServingCerts stores a throwaway certificate for the webhook server in dir
+func writeServingCerts(dir string) {
+	cert := selfSignedCertificate()
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	Expect(err).NotTo(HaveOccurred())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+	Expect(ioutil.WriteFile(filepath.Join(dir, "tls.crt"), certPEM, 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(filepath.Join(dir, "tls.key"), keyPEM, 0600)).To(Succeed())
+}
+
+// freePort finds a port to serve the webhooks on
+func freePort() int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,163 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	emailv1 "std/api/v1"
+)
+
+// maxRenderedSize bounds the output of a single template
+const maxRenderedSize = 64 * 1024
+
+// TemplateData is what message templates are executed with
+type TemplateData struct {
+	// Event is the notified Event, nil for digests
+	Event *corev1.Event
+	// Object is the object the Event is about, empty for digests
+	Object corev1.ObjectReference
+	// Context is the owner workload, node and containers of the involved object, nil for digests or when unknown
+	Context *emailv1.ObjectContext
+	// Logs are the previous logs of the failing container, nil unless the Notifier attaches them
+	Logs *ContainerLogs
+	// Escalation is set when a tier of the escalation policy is notified
+	Escalation *EscalationNotice
+	// AcknowledgeURL is the signed link stopping the escalation, empty when there is none
+	AcknowledgeURL string
+	// Resolved is set when the notification tells that an incident is over
+	Resolved *ResolvedNotice
+	// Incident is the name of the Incident grouping further Events with this one, empty when not grouped
+	Incident string
+	// Digest groups the Events of a digest by reason and involved object
+	Digest []DigestGroup
+	// Notifier is the name and namespace of the Notifier sending the notification
+	Notifier NotifierData
+	// ClusterName is set with the --cluster-name flag of the manager
+	ClusterName string
+	// Subject and Text are the default subject and body, for templates decorating them
+	Subject string
+	Text    string
+}
+
+// NotifierData identifies the Notifier in templates
+type NotifierData struct {
+	Name      string
+	Namespace string
+}
+
+// RenderedMessage replaces the default subject and body of a notification
+type RenderedMessage struct {
+	// Subject is empty when the default is kept
+	Subject string
+	// Body is empty when the default is kept
+	Body string
+	// HTML is set when Body is an HTML document
+	HTML bool
+}
+
+// messageTemplate holds the parsed templates of a Notifier, a nil template keeps the default
+type messageTemplate struct {
+	subject emailv1.Template
+	body    emailv1.Template
+	html    bool
+}
+
+// parseMessageTemplate parses the subject and body, an empty one keeps the default
+func parseMessageTemplate(subject, body string, html bool) (*messageTemplate, error) {
+	tmpl := &messageTemplate{html: html}
+	var err error
+	if strings.TrimSpace(subject) != "" {
+		tmpl.subject, err = emailv1.ParseTemplate(emailv1.TemplateSubjectKey, subject, false)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid subject template")
+		}
+	}
+	if strings.TrimSpace(body) != "" {
+		tmpl.body, err = emailv1.ParseTemplate(emailv1.TemplateBodyKey, body, html)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid body template")
+		}
+	}
+	return tmpl, nil
+}
+
+// render executes the templates for the notification
+func (t *messageTemplate) render(n *Notification, clusterName string) (*RenderedMessage, error) {
+	data := &TemplateData{
+		Event:          n.Event,
+		Context:        n.Context,
+		Logs:           n.Logs,
+		Escalation:     n.Escalation,
+		AcknowledgeURL: n.AcknowledgeURL,
+		Resolved:       n.Resolved,
+		Incident:       n.Incident,
+		Digest:         n.Digest,
+		Notifier: NotifierData{
+			Name:      n.Notifier.GetName(),
+			Namespace: n.Notifier.GetNamespace(),
+		},
+		ClusterName: clusterName,
+		Subject:     n.Subject(),
+		Text:        n.Text(),
+	}
+	if n.Event != nil {
+		data.Object = n.Event.InvolvedObject
+	}
+
+	message := &RenderedMessage{}
+	if t.subject != nil {
+		subject, err := execute(t.subject, data)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render subject")
+		}
+		// The subject ends up in a mail header, keep it on a single line
+		message.Subject = strings.Join(strings.Fields(subject), " ")
+	}
+	if t.body != nil {
+		body, err := execute(t.body, data)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render body")
+		}
+		message.Body = body
+		message.HTML = t.html
+	}
+	return message, nil
+}
+
+func execute(tmpl emailv1.Template, data *TemplateData) (string, error) {
+	out := &limitedBuffer{limit: maxRenderedSize}
+	if err := tmpl.Execute(out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// limitedBuffer fails writes beyond the limit, stopping runaway templates
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errors.Errorf("rendered message exceeds %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
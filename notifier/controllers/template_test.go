@@ -0,0 +1,100 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("Message template", func() {
+	var notification *Notification
+
+	BeforeEach(func() {
+		event := newWarningEvent("web-1.15f", "BackOff", "Pod", "web-1")
+		event.Count = 3
+		notification = &Notification{
+			Notifier: &emailv1.Notifier{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "apps"},
+			},
+			Event: event,
+		}
+	})
+
+	render := func(subject, body string, html bool) (*RenderedMessage, error) {
+		tmpl, err := parseMessageTemplate(subject, body, html)
+		Expect(err).NotTo(HaveOccurred())
+		return tmpl.render(notification, "prod-eu")
+	}
+
+	It("should render the data model", func() {
+		message, err := render(
+			"[{{ .ClusterName }}] {{ .Object.Kind }} {{ .Object.Name }}\n failed",
+			"{{ .Notifier.Namespace }}/{{ .Notifier.Name }}: {{ .Event.Reason }} x{{ .Event.Count }}\n{{ .Text }}",
+			false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message.Subject).To(Equal("[prod-eu] Pod web-1 failed"))
+		Expect(message.Body).To(HavePrefix("apps/team: BackOff x3\nEvent occured!"))
+
+		notification.Message = message
+		Expect(notification.Subject()).To(Equal("[prod-eu] Pod web-1 failed"))
+		Expect(notification.Text()).To(Equal(message.Body))
+		Expect(notification.HTML()).To(BeEmpty())
+	})
+
+	It("should keep the default subject and text when they are not templated", func() {
+		message, err := render("", "<p>{{ .Event.Message }}</p>", true)
+		Expect(err).NotTo(HaveOccurred())
+
+		notification.Message = message
+		Expect(notification.Subject()).To(Equal("[team] BackOff: default/web-1"))
+		Expect(notification.Text()).To(HavePrefix("Event occured!"))
+		Expect(notification.HTML()).To(Equal("<p>Back-off restarting failed container</p>"))
+	})
+
+	It("should render digests", func() {
+		notification.Digest = groupDigest([]*corev1.Event{notification.Event})
+		notification.Event = nil
+
+		message, err := render("", "{{ range .Digest }}{{ .Title }};{{ end }}", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(message.Body).To(Equal("BackOff - Pod default/web-1 (x3);"))
+	})
+
+	It("should report templates which fail to render", func() {
+		_, err := render("", "{{ .Event.Reason.Missing }}", false)
+		Expect(err).To(MatchError(ContainSubstring("failed to render body")))
+	})
+
+	It("should stop runaway templates", func() {
+		body := `{{ define "loop" }}` + strings.Repeat("x", 1024) + `{{ template "loop" . }}{{ end }}{{ template "loop" . }}`
+		_, err := render("", body, false)
+		Expect(err).To(MatchError(ContainSubstring("rendered message exceeds")))
+	})
+
+	It("should reject templates which don't parse", func() {
+		_, err := parseMessageTemplate("{{ .Event", "", false)
+		Expect(err).To(MatchError(ContainSubstring("invalid subject template")))
+		_, err = parseMessageTemplate("", "{{ end }}", true)
+		Expect(err).To(MatchError(ContainSubstring("invalid body template")))
+	})
+})
@@ -0,0 +1,166 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	emailv1 "std/api/v1"
+)
+
+// Reasons for suppressing a NotificationRecord
+const (
+	SuppressedDuplicate   = "Duplicate"
+	SuppressedRateLimited = "RateLimited"
+)
+
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// dedupKey identifies the failure behind the Event: the reason, involved object and message fingerprint
+func dedupKey(event *corev1.Event) string {
+	message := digits.ReplaceAllString(event.Message, "0")
+	hash := sha256.Sum256([]byte(message))
+	ref := event.InvolvedObject
+	return strings.Join([]string{event.Reason, ref.Kind, ref.Namespace, ref.Name, hex.EncodeToString(hash[:8])}, "/")
+}
+
+// takeToken refills the bucket for the time passed and takes a token, if there is one left
+func takeToken(bucket *emailv1.TokenBucket, policy *emailv1.RateLimitPolicy, now time.Time) bool {
+	if bucket.LastRefill.IsZero() {
+		bucket.Tokens = policy.Burst
+		bucket.LastRefill = metav1.NewTime(now)
+	}
+	period := policy.GetEvery()
+	refills := now.Sub(bucket.LastRefill.Time) / period
+	if refills > 0 {
+		bucket.LastRefill = metav1.NewTime(bucket.LastRefill.Add(refills * period))
+		if int64(bucket.Tokens)+int64(refills) >= int64(policy.Burst) {
+			bucket.Tokens = policy.Burst
+		} else {
+			bucket.Tokens += int32(refills)
+		}
+	}
+	if bucket.Tokens > policy.Burst {
+		// The burst was lowered
+		bucket.Tokens = policy.Burst
+	}
+
+	if bucket.Tokens < 1 {
+		return false
+	}
+	bucket.Tokens--
+	return true
+}
+
+// throttle applies the dedup and rate limit policies of the Notifier, keeping their state in its status
+type throttle struct {
+	notifier *emailv1.Notifier
+	now      time.Time
+}
+
+// newThrottle forgets the notifications which no longer suppress their duplicates
+func newThrottle(notifier *emailv1.Notifier, now time.Time) *throttle {
+	status := &notifier.Status
+	if notifier.Spec.Dedup == nil {
+		status.Dedup = nil
+	} else {
+		status.Dedup = pruneDedup(status.Dedup, notifier.Spec.Dedup.Window.Duration, now)
+	}
+	if notifier.Spec.RateLimit == nil {
+		status.RateLimit = nil
+	}
+
+	return &throttle{notifier: notifier, now: now}
+}
+
+// pruneDedup drops the entries sent longer than window ago
+func pruneDedup(entries []emailv1.DedupEntry, window time.Duration, now time.Time) []emailv1.DedupEntry {
+	kept := []emailv1.DedupEntry{}
+	for _, entry := range entries {
+		if now.Sub(entry.LastSent.Time) < window {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// expiresIn is the time until the oldest dedup entry is pruned, zero without any
+func (t *throttle) expiresIn() time.Duration {
+	var wait time.Duration
+	for _, entry := range t.notifier.Status.Dedup {
+		wait = minDuration(wait, t.notifier.Spec.Dedup.Window.Duration-t.now.Sub(entry.LastSent.Time))
+	}
+	return wait
+}
+
+// duplicate reports whether a notification about the same failure was sent within the window.
+// Failures which were not delivered yet don't suppress anything, their duplicates get their chance.
+func (t *throttle) duplicate(event *corev1.Event) bool {
+	if t.notifier.Spec.Dedup == nil {
+		return false
+	}
+	key := dedupKey(event)
+	for _, entry := range t.notifier.Status.Dedup {
+		if entry.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// allow takes a token for a notification
+func (t *throttle) allow() bool {
+	policy := t.notifier.Spec.RateLimit
+	if policy == nil {
+		return true
+	}
+	if t.notifier.Status.RateLimit == nil {
+		t.notifier.Status.RateLimit = &emailv1.TokenBucket{}
+	}
+	return takeToken(t.notifier.Status.RateLimit, policy, t.now)
+}
+
+// sent remembers the delivered Events, so their duplicates are suppressed
+func (t *throttle) sent(events []*corev1.Event) {
+	if t.notifier.Spec.Dedup == nil {
+		return
+	}
+	for _, event := range events {
+		key := dedupKey(event)
+		found := false
+		for i := range t.notifier.Status.Dedup {
+			if t.notifier.Status.Dedup[i].Key == key {
+				t.notifier.Status.Dedup[i].LastSent = metav1.NewTime(t.now)
+				found = true
+			}
+		}
+		if !found {
+			t.notifier.Status.Dedup = append(t.notifier.Status.Dedup, emailv1.DedupEntry{
+				Key:      key,
+				LastSent: metav1.NewTime(t.now),
+			})
+		}
+	}
+}
@@ -0,0 +1,211 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+var _ = Describe("throttle", func() {
+	var (
+		notifier *emailv1.Notifier
+		start    time.Time
+	)
+
+	burst := func(count int, message string) []*corev1.Event {
+		events := []*corev1.Event{}
+		for i := 0; i < count; i++ {
+			event := newWarningEvent(fmt.Sprintf("burst-pod.%d", i), "FailedMount", "Pod", "burst-pod")
+			event.Message = fmt.Sprintf(message, i)
+			events = append(events, event)
+		}
+		return events
+	}
+
+	// replay passes the Events through the throttle like the reconciler, returning the sent ones
+	replay := func(throttle *throttle, events []*corev1.Event) []*corev1.Event {
+		sent := []*corev1.Event{}
+		for _, event := range events {
+			if throttle.duplicate(event) || !throttle.allow() {
+				continue
+			}
+			sent = append(sent, event)
+			throttle.sent([]*corev1.Event{event})
+		}
+		return sent
+	}
+
+	// restart round trips the status, like a restarted controller reading it from the API server
+	restart := func() {
+		data, err := json.Marshal(notifier.Status)
+		Expect(err).NotTo(HaveOccurred())
+		notifier.Status = emailv1.NotifierStatus{}
+		Expect(json.Unmarshal(data, &notifier.Status)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		notifier = newNotifier("throttled", "throttled@example.com")
+		start = time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("should key failures by reason, object and message without numbers", func() {
+		events := burst(2, "Unable to attach volumes after %d attempts")
+		Expect(dedupKey(events[0])).To(Equal(dedupKey(events[1])))
+		Expect(dedupKey(events[0])).To(HavePrefix("FailedMount/Pod/default/burst-pod/"))
+
+		other := events[1].DeepCopy()
+		other.InvolvedObject.Name = "other-pod"
+		Expect(dedupKey(other)).NotTo(Equal(dedupKey(events[0])))
+
+		other = events[1].DeepCopy()
+		other.Message = "Unable to mount volumes"
+		Expect(dedupKey(other)).NotTo(Equal(dedupKey(events[0])))
+	})
+
+	It("should send a burst of identical Events once per window", func() {
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: 10 * time.Minute}}
+		events := burst(20, "Unable to attach volumes after %d attempts")
+
+		Expect(replay(newThrottle(notifier, start), events)).To(Equal(events[:1]))
+		Expect(notifier.Status.Dedup).To(HaveLen(1))
+
+		restart()
+		Expect(replay(newThrottle(notifier, start.Add(5*time.Minute)), events)).To(BeEmpty())
+
+		restart()
+		Expect(replay(newThrottle(notifier, start.Add(11*time.Minute)), events)).To(Equal(events[:1]))
+	})
+
+	It("should not suppress duplicates of a failure which was not delivered", func() {
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: 10 * time.Minute}}
+		events := burst(2, "Unable to attach volumes after %d attempts")
+		throttle := newThrottle(notifier, start)
+
+		// The first delivery failed, so its key is not remembered
+		Expect(throttle.duplicate(events[0])).To(BeFalse())
+		Expect(throttle.duplicate(events[1])).To(BeFalse())
+		throttle.sent(events[1:])
+		Expect(throttle.duplicate(events[0])).To(BeTrue())
+	})
+
+	It("should notify again records sent within the dedup window", func() {
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: time.Hour}}
+		notifier.Spec.Renotify = &emailv1.RenotifyPolicy{After: &metav1.Duration{Duration: 10 * time.Minute}}
+		recorder := newWebhookRecorder()
+		defer recorder.Close()
+
+		event := burst(1, "Unable to attach volumes after %d attempts")[0]
+		event.Count = 3
+		record := newNotificationRecord(notifier, event)
+		sent := metav1.NewTime(start.Add(-15 * time.Minute))
+		record.Status.State = emailv1.RecordSent
+		record.Status.SentTime = &sent
+		record.Status.NotifiedCount = 1
+		notifier.Status.Dedup = []emailv1.DedupEntry{{Key: dedupKey(event), LastSent: sent}}
+		Expect(renotifyDue(record, notifier.Spec.Renotify, start)).To(BeTrue())
+
+		s := runtime.NewScheme()
+		Expect(emailv1.AddToScheme(s)).To(Succeed())
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(s, record), Log: ctrl.Log.WithName("throttle")}
+		channel, err := newWebhookChannel(http.DefaultClient, recorder.URL, emailv1.WebhookChannel)
+		Expect(err).NotTo(HaveOccurred())
+
+		records := []emailv1.NotificationRecord{*record}
+		Expect(r.notify(notifier, []Channel{channel}, nil, records, newThrottle(notifier, start))).To(Succeed())
+		Expect(recorder.Payloads()).To(HaveLen(1))
+		Expect(records[0].Status.State).To(Equal(emailv1.RecordSent))
+		Expect(records[0].Status.NotifiedCount).To(BeEquivalentTo(3))
+		Expect(notifier.Status.SuppressedCount).To(BeZero())
+	})
+
+	It("should forget the dedup state when the policy is removed", func() {
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: time.Hour}}
+		replay(newThrottle(notifier, start), burst(1, "%d"))
+		Expect(notifier.Status.Dedup).To(HaveLen(1))
+
+		notifier.Spec.Dedup = nil
+		Expect(replay(newThrottle(notifier, start), burst(3, "%d"))).To(HaveLen(3))
+		Expect(notifier.Status.Dedup).To(BeNil())
+	})
+
+	It("should prune dedup entries older than the window", func() {
+		notifier.Spec.Dedup = &emailv1.DedupPolicy{Window: metav1.Duration{Duration: 10 * time.Minute}}
+		replay(newThrottle(notifier, start), burst(1, "Unable to attach volume %d"))
+		replay(newThrottle(notifier, start.Add(5*time.Minute)), burst(1, "Unable to mount volume %d"))
+		Expect(notifier.Status.Dedup).To(HaveLen(2))
+
+		throttle := newThrottle(notifier, start.Add(11*time.Minute))
+		Expect(notifier.Status.Dedup).To(HaveLen(1))
+		Expect(throttle.expiresIn()).To(Equal(4 * time.Minute))
+
+		throttle = newThrottle(notifier, start.Add(15*time.Minute))
+		Expect(notifier.Status.Dedup).To(BeEmpty())
+		Expect(throttle.expiresIn()).To(BeZero())
+	})
+
+	It("should rate limit a burst of distinct Events", func() {
+		notifier.Spec.RateLimit = &emailv1.RateLimitPolicy{Burst: 3, Every: metav1.Duration{Duration: time.Minute}}
+		events := burst(10, "Unable to attach volume %d")
+
+		Expect(replay(newThrottle(notifier, start), events)).To(Equal(events[:3]))
+		Expect(notifier.Status.RateLimit.Tokens).To(BeZero())
+
+		restart()
+		Expect(replay(newThrottle(notifier, start.Add(90*time.Second)), events)).To(HaveLen(1))
+
+		restart()
+		Expect(replay(newThrottle(notifier, start.Add(2*time.Minute)), events)).To(HaveLen(1))
+
+		restart()
+		Expect(replay(newThrottle(notifier, start.Add(time.Hour)), events)).To(HaveLen(3))
+	})
+
+	It("should refill the bucket up to the burst", func() {
+		policy := &emailv1.RateLimitPolicy{Burst: 2, Every: metav1.Duration{Duration: time.Minute}}
+		bucket := &emailv1.TokenBucket{}
+		Expect(takeToken(bucket, policy, start)).To(BeTrue())
+		Expect(bucket.Tokens).To(BeEquivalentTo(1))
+
+		Expect(takeToken(bucket, policy, start.Add(30*time.Minute))).To(BeTrue())
+		Expect(bucket.Tokens).To(BeEquivalentTo(1))
+		Expect(bucket.LastRefill.Time).To(Equal(start.Add(30 * time.Minute)))
+
+		policy.Burst = 1
+		Expect(takeToken(bucket, policy, start.Add(30*time.Minute))).To(BeTrue())
+		Expect(takeToken(bucket, policy, start.Add(30*time.Minute))).To(BeFalse())
+	})
+
+	It("should refill a token a minute without a period", func() {
+		policy := &emailv1.RateLimitPolicy{Burst: 1}
+		bucket := &emailv1.TokenBucket{}
+		Expect(takeToken(bucket, policy, start)).To(BeTrue())
+		Expect(takeToken(bucket, policy, start.Add(30*time.Second))).To(BeFalse())
+		Expect(takeToken(bucket, policy, start.Add(time.Minute))).To(BeTrue())
+	})
+})
@@ -0,0 +1,178 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	emailv1 "std/api/v1"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of the webhook body, as sha256=<hex digest>
+const SignatureHeader = "X-Notifier-Signature"
+
+// WebhookPayloadVersion is the version of the generic webhook document, bumped on incompatible changes
+const WebhookPayloadVersion = "v1"
+
+// signBody is the value of the SignatureHeader for the body
+func signBody(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// configureWebhook resolves the Secrets of the channel options into its headers, signing key and HTTP client
+func (r *NotifierReconciler) configureWebhook(notifier *emailv1.Notifier, channel *webhookChannel, options *emailv1.WebhookOptions) error {
+	if options == nil {
+		return nil
+	}
+	namespace := notifier.GetNamespace()
+	if options.HeadersSecretRef != nil {
+		secret, err := r.getSecret(namespace, options.HeadersSecretRef.Name)
+		if err != nil {
+			return err
+		}
+		channel.headers = http.Header{}
+		for name, value := range secret.Data {
+			channel.headers.Set(name, strings.TrimSpace(string(value)))
+		}
+	}
+	if options.SigningSecretRef != nil {
+		secret, err := r.getSecret(namespace, options.SigningSecretRef.Name)
+		if err != nil {
+			return err
+		}
+		key, found := secret.Data[options.SigningSecretRef.Key]
+		if !found || len(key) == 0 {
+			return errors.Wrapf(errInvalidConfig, "Secret %s/%s: missing key %q",
+				namespace, options.SigningSecretRef.Name, options.SigningSecretRef.Key)
+		}
+		channel.signingKey = key
+	}
+	if options.TLSSecretRef == nil && options.Timeout == nil {
+		return nil
+	}
+
+	client := *channel.client
+	if options.Timeout != nil {
+		client.Timeout = options.Timeout.Duration
+	}
+	if options.TLSSecretRef != nil {
+		secret, err := r.getSecret(namespace, options.TLSSecretRef.Name)
+		if err != nil {
+			return err
+		}
+		transport, err := webhookTransports.get(secret)
+		if err != nil {
+			return errors.Wrapf(errInvalidConfig, "Secret %s/%s: %v", namespace, secret.GetName(), err)
+		}
+		client.Transport = transport
+	}
+	channel.client = &client
+	return nil
+}
+
+// transportCache keeps a transport per TLS Secret, so connections are reused across reconciles
+type transportCache struct {
+	mu         sync.Mutex
+	transports map[string]cachedTransport
+}
+
+type cachedTransport struct {
+	resourceVersion string
+	transport       *http.Transport
+}
+
+// webhookTransports are shared by the Notifier and ClusterNotifier reconcilers
+var webhookTransports = &transportCache{transports: map[string]cachedTransport{}}
+
+// get returns the transport for the current version of the Secret, closing the one of the previous version
+func (c *transportCache) get(secret *corev1.Secret) (*http.Transport, error) {
+	key := secret.GetNamespace() + "/" + secret.GetName()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, found := c.transports[key]
+	if found && cached.resourceVersion == secret.GetResourceVersion() {
+		return cached.transport, nil
+	}
+
+	config, err := tlsConfigFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		cached.transport.CloseIdleConnections()
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSClientConfig:       config,
+		TLSHandshakeTimeout:   10 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	c.transports[key] = cachedTransport{resourceVersion: secret.GetResourceVersion(), transport: transport}
+	return transport, nil
+}
+
+// forget drops the transport of the Secret, closing its idle connections.
+// The transport is built again from the Secret when a channel still uses it.
+func (c *transportCache) forget(namespace, name string) {
+	key := namespace + "/" + name
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, found := c.transports[key]; found {
+		cached.transport.CloseIdleConnections()
+		delete(c.transports, key)
+	}
+}
+
+// tlsConfigFromSecret trusts the CA bundle in ca.crt instead of the system roots,
+// and presents the client certificate in tls.crt and tls.key
+func tlsConfigFromSecret(secret *corev1.Secret) (*tls.Config, error) {
+	config := &tls.Config{}
+	if bundle, found := secret.Data["ca.crt"]; found {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("ca.crt has no PEM certificate")
+		}
+	}
+	cert, hasCert := secret.Data[corev1.TLSCertKey]
+	key, hasKey := secret.Data[corev1.TLSPrivateKeyKey]
+	if hasCert != hasKey {
+		return nil, errors.New("the client certificate needs both tls.crt and tls.key")
+	}
+	if hasCert {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate")
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	if config.RootCAs == nil && config.Certificates == nil {
+		return nil, errors.New("expected ca.crt, or tls.crt and tls.key")
+	}
+	return config, nil
+}
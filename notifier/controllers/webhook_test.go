@@ -0,0 +1,231 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	emailv1 "std/api/v1"
+)
+
+// newClientCertificate is a self signed certificate for TLS client authentication, in PEM
+func newClientCertificate(commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+var _ = Describe("webhook options", func() {
+	var (
+		recorder     *webhookRecorder
+		notifier     *emailv1.Notifier
+		notification *Notification
+		secrets      []runtime.Object
+	)
+
+	newSecret := func(name string, data map[string]string) {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"}, Data: map[string][]byte{}}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	BeforeEach(func() {
+		recorder = newWebhookRecorder()
+		notifier = &emailv1.Notifier{ObjectMeta: metav1.ObjectMeta{Name: "tickets", Namespace: "apps"}}
+		notification = &Notification{
+			Notifier: notifier,
+			Event: &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: "web-1.15f", Namespace: "apps", UID: "uid-web-1"},
+				InvolvedObject: podRef("web-1"),
+				Type:           corev1.EventTypeWarning,
+				Reason:         "BackOff",
+			},
+		}
+		secrets = nil
+	})
+
+	AfterEach(func() {
+		recorder.Close()
+	})
+
+	// build resolves the channel against the Secrets created so far
+	build := func(endpoint string, options *emailv1.WebhookOptions) (Channel, error) {
+		r := &NotifierReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme, secrets...)}
+		channels, err := r.buildChannels(notifier, []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: endpoint, Webhook: options}})
+		if err != nil {
+			return nil, err
+		}
+		Expect(channels).To(HaveLen(1))
+		return channels[0], nil
+	}
+
+	It("should sign the envelope and send the headers from the Secret", func() {
+		newSecret("ticketing-headers", map[string]string{"Authorization": "Bearer abc\n", "X-Ticket-Queue": "ops"})
+		newSecret("ticketing-signing", map[string]string{"key": "s3cret"})
+		channel, err := build(recorder.URL, &emailv1.WebhookOptions{
+			HeadersSecretRef: &corev1.LocalObjectReference{Name: "ticketing-headers"},
+			SigningSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ticketing-signing"}, Key: "key"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channel.Send(notification)).To(Succeed())
+
+		req := recorder.LastRequest()
+		Expect(req.Header.Get("Authorization")).To(Equal("Bearer abc"))
+		Expect(req.Header.Get("X-Ticket-Queue")).To(Equal("ops"))
+		Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(req.Header.Get(SignatureHeader)).To(Equal(signBody([]byte("s3cret"), recorder.LastBody())))
+		Expect(req.Header.Get(SignatureHeader)).To(HavePrefix("sha256="))
+
+		payload := recorder.Payloads()[0]
+		Expect(payload["version"]).To(Equal("v1"))
+		Expect(payload["event"]).To(HaveKeyWithValue("uid", "uid-web-1"))
+	})
+
+	It("should trust the CA bundle and present the client certificate", func() {
+		clientCert, clientKey := newClientCertificate("notifier")
+		clientCAs := x509.NewCertPool()
+		Expect(clientCAs.AppendCertsFromPEM(clientCert)).To(BeTrue())
+
+		var peer string
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			peer = req.TLS.PeerCertificates[0].Subject.CommonName
+		}))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+		server.StartTLS()
+		defer server.Close()
+		serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+		newSecret("ticketing-tls", map[string]string{"ca.crt": serverCA, "tls.crt": string(clientCert), "tls.key": string(clientKey)})
+		channel, err := build(server.URL, &emailv1.WebhookOptions{TLSSecretRef: &corev1.LocalObjectReference{Name: "ticketing-tls"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channel.Send(notification)).To(Succeed())
+		Expect(peer).To(Equal("notifier"))
+
+		// Without the CA bundle the server isn't trusted, without the client certificate it refuses the request
+		channel, err = build(server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channel.Send(notification)).To(MatchError(ContainSubstring("certificate")))
+		newSecret("ticketing-ca", map[string]string{"ca.crt": serverCA})
+		channel, err = build(server.URL, &emailv1.WebhookOptions{TLSSecretRef: &corev1.LocalObjectReference{Name: "ticketing-ca"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channel.Send(notification)).NotTo(Succeed())
+	})
+
+	It("should drop the transports of changed or deleted Secrets", func() {
+		clientCert, clientKey := newClientCertificate("notifier")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ticketing-tls", Namespace: "apps", ResourceVersion: "1"},
+			Data:       map[string][]byte{"tls.crt": clientCert, "tls.key": clientKey},
+		}
+		cache := &transportCache{transports: map[string]cachedTransport{}}
+		transport, err := cache.get(secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cache.get(secret)).To(BeIdenticalTo(transport))
+
+		cache.forget("apps", "other")
+		Expect(cache.transports).To(HaveLen(1))
+		cache.forget("apps", "ticketing-tls")
+		Expect(cache.transports).To(BeEmpty())
+		Expect(cache.get(secret)).NotTo(BeIdenticalTo(transport))
+	})
+
+	It("should give up on slow endpoints after the timeout", func() {
+		slow := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			time.Sleep(500 * time.Millisecond)
+		}))
+		defer slow.Close()
+
+		channel, err := build(slow.URL+"/token-abc", &emailv1.WebhookOptions{Timeout: &metav1.Duration{Duration: 50 * time.Millisecond}})
+		Expect(err).NotTo(HaveOccurred())
+		start := time.Now()
+		err = channel.Send(notification)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).NotTo(ContainSubstring("token-abc"))
+		Expect(time.Since(start)).To(BeNumerically("<", 400*time.Millisecond))
+	})
+
+	It("should index the Secrets of the options and escalation tiers", func() {
+		options := &emailv1.WebhookOptions{
+			HeadersSecretRef: &corev1.LocalObjectReference{Name: "ticketing-headers"},
+			SigningSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ticketing-signing"}, Key: "key"},
+			TLSSecretRef:     &corev1.LocalObjectReference{Name: "ticketing-tls"},
+		}
+		notifier.Spec.Channels = []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL, Webhook: options}}
+		r := &NotifierReconciler{}
+		Expect(r.secretsIndex(notifier)).To(Equal([]string{"apps/ticketing-headers", "apps/ticketing-signing", "apps/ticketing-tls"}))
+
+		policy := &emailv1.EscalationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: "apps"},
+			Spec: emailv1.EscalationPolicySpec{Tiers: []emailv1.EscalationTier{
+				{Name: "team", Channels: []emailv1.Channel{{Type: emailv1.EmailChannel, Email: "team@example.com"}}},
+				{Name: "tickets", Channels: []emailv1.Channel{{Type: emailv1.WebhookChannel, URL: recorder.URL, Webhook: options}}},
+			}},
+		}
+		Expect(escalationPolicySecretsIndex(policy)).To(Equal([]string{"apps/ticketing-headers", "apps/ticketing-signing", "apps/ticketing-tls"}))
+	})
+
+	It("should report unusable Secrets as invalid configuration", func() {
+		_, err := build(recorder.URL, &emailv1.WebhookOptions{HeadersSecretRef: &corev1.LocalObjectReference{Name: "missing"}})
+		Expect(errors.Cause(err)).To(Equal(errInvalidConfig))
+
+		newSecret("ticketing-signing", map[string]string{"other": "s3cret"})
+		_, err = build(recorder.URL, &emailv1.WebhookOptions{
+			SigningSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ticketing-signing"}, Key: "key"},
+		})
+		Expect(errors.Cause(err)).To(Equal(errInvalidConfig))
+
+		newSecret("ticketing-half-tls", map[string]string{"tls.crt": "cert"})
+		_, err = build(recorder.URL, &emailv1.WebhookOptions{TLSSecretRef: &corev1.LocalObjectReference{Name: "ticketing-half-tls"}})
+		Expect(errors.Cause(err)).To(Equal(errInvalidConfig))
+		Expect(err.Error()).To(ContainSubstring("needs both tls.crt and tls.key"))
+	})
+})
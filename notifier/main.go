@@ -18,6 +18,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	emailv1 "std/api/v1"
 
@@ -26,7 +27,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
@@ -47,10 +50,53 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var smtpConfig controllers.SMTPConfig
+	var smtpTLS, smtpAuth, smtpSecret string
+	var recordTTL, podCheckInterval time.Duration
+	var clusterName, clusterNamespace, webhookCertDir string
+	var webhookPort int
+	var acknowledgeAddr, acknowledgeURL, acknowledgeKey string
+	var acknowledgeLinkTTL time.Duration
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&smtpSecret, "smtp-secret", "",
+		"The namespace/name of a Secret with SMTP settings, used by Notifiers without their own smtpSecretRef.")
+	flag.StringVar(&smtpConfig.Host, "smtp-host", "",
+		"The SMTP server used for sending notifications. Notifications are only logged when empty.")
+	flag.IntVar(&smtpConfig.Port, "smtp-port", 587, "The SMTP server port.")
+	flag.StringVar(&smtpConfig.Username, "smtp-username", "", "The username for SMTP authentication.")
+	flag.StringVar(&smtpConfig.Password, "smtp-password", os.Getenv("SMTP_PASSWORD"),
+		"The password for SMTP authentication. Defaults to the SMTP_PASSWORD environment variable.")
+	flag.StringVar(&smtpConfig.From, "smtp-from", "", "The sender address of notification emails.")
+	flag.StringVar(&smtpTLS, "smtp-tls", string(controllers.TLSStartTLS),
+		"How to secure the SMTP connection: none, starttls or tls.")
+	flag.StringVar(&smtpAuth, "smtp-auth", string(controllers.AuthPlain),
+		"The SMTP authentication mechanism: none, plain or login.")
+	flag.BoolVar(&smtpConfig.InsecureSkipVerify, "smtp-insecure-skip-verify", false,
+		"Skip verification of the SMTP server certificate.")
+	flag.DurationVar(&smtpConfig.Timeout, "smtp-timeout", controllers.DefaultSMTPTimeout,
+		"The timeout for delivering a single email.")
+	flag.DurationVar(&recordTTL, "record-ttl", controllers.DefaultRecordTTL,
+		"How long sent NotificationRecords are kept. Zero keeps them forever.")
+	flag.DurationVar(&podCheckInterval, "pod-check-interval", controllers.DefaultPodCheckInterval,
+		"How often the Pods of open incidents are checked for readiness, when Notifiers resolve them.")
+	flag.StringVar(&clusterName, "cluster-name", "", "The name of the cluster, available to message templates.")
+	flag.StringVar(&clusterNamespace, "cluster-resource-namespace", "failure-informer-system",
+		"The namespace holding the Secrets, ConfigMaps and NotificationRecords of ClusterNotifiers.")
+	flag.IntVar(&webhookPort, "webhook-port", 443, "The port the admission webhook server binds to.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory with the tls.crt and tls.key of the admission webhook server.")
+	flag.StringVar(&acknowledgeAddr, "acknowledge-addr", ":8082", "The address the acknowledgement link endpoint binds to.")
+	flag.StringVar(&acknowledgeURL, "acknowledge-url", "",
+		"The external URL of the acknowledgement link endpoint. Escalated notifications carry no links when empty.")
+	flag.StringVar(&acknowledgeKey, "acknowledge-key", os.Getenv("ACKNOWLEDGE_KEY"),
+		"The key signing the acknowledgement links. Defaults to the ACKNOWLEDGE_KEY environment variable.")
+	flag.DurationVar(&acknowledgeLinkTTL, "acknowledge-link-ttl", controllers.DefaultAcknowledgeLinkTTL,
+		"How long the acknowledgement links are valid after they were sent.")
 	flag.Parse()
+	smtpConfig.TLS = controllers.TLSMode(smtpTLS)
+	smtpConfig.Auth = controllers.AuthMethod(smtpAuth)
 
 	ctrl.SetLogger(zap.Logger(true))
 
@@ -58,30 +104,117 @@ func main() {
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
 		LeaderElection:     enableLeaderElection,
+		Port:               webhookPort,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	mgr.GetWebhookServer().CertDir = webhookCertDir
 
-	err = (&controllers.NotifierReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Notifier"),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr)
+	if smtpConfig.Host != "" {
+		if err := smtpConfig.Validate(); err != nil {
+			setupLog.Error(err, "invalid SMTP configuration")
+			os.Exit(1)
+		}
+	}
+	var defaultSMTPSecret types.NamespacedName
+	if smtpSecret != "" {
+		namespace, name, err := cache.SplitMetaNamespaceKey(smtpSecret)
+		if err != nil || namespace == "" {
+			setupLog.Error(err, "invalid SMTP Secret, expected namespace/name", "smtp-secret", smtpSecret)
+			os.Exit(1)
+		}
+		defaultSMTPSecret = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	logFetcher, err := controllers.NewLogFetcher(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create the log fetcher")
+		os.Exit(1)
+	}
+
+	var acknowledgeServer *controllers.AcknowledgeServer
+	if acknowledgeURL != "" {
+		if acknowledgeKey == "" {
+			setupLog.Error(nil, "acknowledgement links need a signing key, set --acknowledge-key")
+			os.Exit(1)
+		}
+		acknowledgeServer = &controllers.AcknowledgeServer{
+			Client:      mgr.GetClient(),
+			Log:         ctrl.Log.WithName("acknowledge"),
+			URL:         acknowledgeURL,
+			Key:         []byte(acknowledgeKey),
+			LinkTTL:     acknowledgeLinkTTL,
+			BindAddress: acknowledgeAddr,
+		}
+		if err := mgr.Add(acknowledgeServer); err != nil {
+			setupLog.Error(err, "unable to add the acknowledgement server")
+			os.Exit(1)
+		}
+	}
+
+	filters := controllers.NewFilterIndex()
+	notifierReconciler := controllers.NotifierReconciler{
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("Notifier"),
+		Scheme:            mgr.GetScheme(),
+		Mailer:            controllers.SMTPMailer{},
+		SMTP:              smtpConfig,
+		DefaultSMTPSecret: defaultSMTPSecret,
+		RecordTTL:         recordTTL,
+		ClusterName:       clusterName,
+		Filters:           filters,
+		Recorder:          mgr.GetEventRecorderFor("notifier-controller"),
+		Logs:              logFetcher,
+		Acknowledge:       acknowledgeServer,
+		PodCheckInterval:  podCheckInterval,
+	}
+	err = notifierReconciler.SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Notifier")
 		os.Exit(1)
 	}
+	clusterNotifierReconciler := &controllers.ClusterNotifierReconciler{
+		NotifierReconciler: notifierReconciler,
+		Namespace:          clusterNamespace,
+	}
+	clusterNotifierReconciler.Log = ctrl.Log.WithName("controllers").WithName("ClusterNotifier")
+	err = clusterNotifierReconciler.SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterNotifier")
+		os.Exit(1)
+	}
 	err = (&controllers.EventReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Event"),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Log:              ctrl.Log.WithName("controllers").WithName("Event"),
+		Scheme:           mgr.GetScheme(),
+		ClusterNamespace: clusterNamespace,
+		Filters:          filters,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Event")
 		os.Exit(1)
 	}
+	err = (&controllers.IncidentReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Incident"),
+		TTL:    recordTTL,
+	}).SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Incident")
+		os.Exit(1)
+	}
+	err = (&emailv1.Silence{}).SetupWebhookWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "Silence")
+		os.Exit(1)
+	}
+	err = (&emailv1.EscalationPolicy{}).SetupWebhookWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "EscalationPolicy")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")